@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+	pbv2 "multi-process-docker/proto/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Greeter API versions the client can speak, chosen with -api-version
+const (
+	apiV1 = 1
+	apiV2 = 2
+)
+
+// checkAPIVersion returns an error unless version is one the client speaks
+func checkAPIVersion(version int) error {
+	if version != apiV1 && version != apiV2 {
+		return fmt.Errorf("unknown API version %d (expected %d or %d)", version, apiV1, apiV2)
+	}
+	return nil
+}
+
+// newGreeterClient returns a client for version of the Greeter API on conn.
+// The request loop is written against version 1, so a version 2 client is
+// adapted to it.
+func newGreeterClient(conn grpc.ClientConnInterface, version int) pb.GreeterClient {
+	if version == apiV2 {
+		return greeterV2Client{client: pbv2.NewGreeterClient(conn)}
+	}
+	return pb.NewGreeterClient(conn)
+}
+
+// sayHello calls SayHello through client, also returning when the server
+// answered if the client speaks a version of the API that reports it
+func sayHello(ctx context.Context, client pb.GreeterClient, req *pb.HelloRequest) (*pb.HelloReply, time.Time, error) {
+	v2, ok := client.(greeterV2Client)
+	if !ok {
+		reply, err := client.SayHello(ctx, req)
+		return reply, time.Time{}, err
+	}
+	reply, err := v2.client.SayHello(ctx, &pbv2.HelloRequest{Name: req.Name})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return helloReplyV1(reply), reply.ServedAt.AsTime(), nil
+}
+
+// greeterV2Client makes calls to version 2 of the Greeter API through the
+// version 1 client interface, converting requests and replies
+type greeterV2Client struct {
+	client pbv2.GreeterClient
+}
+
+func (c greeterV2Client) SayHello(ctx context.Context, req *pb.HelloRequest, opts ...grpc.CallOption) (*pb.HelloReply, error) {
+	reply, err := c.client.SayHello(ctx, &pbv2.HelloRequest{Name: req.Name}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return helloReplyV1(reply), nil
+}
+
+func (c greeterV2Client) StreamMessages(ctx context.Context, req *pb.StreamRequest, opts ...grpc.CallOption) (pb.Greeter_StreamMessagesClient, error) {
+	stream, err := c.client.StreamMessages(ctx, &pbv2.StreamRequest{Count: req.Count}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return messageStreamV2{stream}, nil
+}
+
+func (c greeterV2Client) GetStats(ctx context.Context, req *emptypb.Empty, opts ...grpc.CallOption) (*pb.StatsReply, error) {
+	stats, err := c.client.GetStats(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.StatsReply{RequestCount: stats.RequestCount, Uptime: stats.Uptime}, nil
+}
+
+// helloReplyV1 drops the fields version 1 lacks from a version 2 reply
+func helloReplyV1(reply *pbv2.HelloReply) *pb.HelloReply {
+	return &pb.HelloReply{Message: reply.Message, Count: reply.Count, InstanceId: reply.InstanceId}
+}
+
+// messageStreamV2 receives a version 2 message stream as version 1 messages
+type messageStreamV2 struct {
+	pbv2.Greeter_StreamMessagesClient
+}
+
+func (s messageStreamV2) Recv() (*pb.MessageResponse, error) {
+	msg, err := s.Greeter_StreamMessagesClient.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.MessageResponse{Message: msg.Message, Index: msg.Index}, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+	pbv2 "multi-process-docker/proto/v2"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// servedAt is the time every testGreeterV2 reply claims to be sent at
+var servedAt = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+// testGreeterV2 serves version 2 of the Greeter API from a testGreeter
+type testGreeterV2 struct {
+	pbv2.UnimplementedGreeterServer
+	v1 *testGreeter
+}
+
+func (g testGreeterV2) SayHello(ctx context.Context, req *pbv2.HelloRequest) (*pbv2.HelloReply, error) {
+	reply, err := g.v1.SayHello(ctx, &pb.HelloRequest{Name: req.Name})
+	if err != nil {
+		return nil, err
+	}
+	return &pbv2.HelloReply{Message: reply.Message, Count: reply.Count, InstanceId: reply.InstanceId, ServedAt: timestamppb.New(servedAt)}, nil
+}
+
+func (g testGreeterV2) StreamMessages(req *pbv2.StreamRequest, stream pbv2.Greeter_StreamMessagesServer) error {
+	return g.v1.StreamMessages(&pb.StreamRequest{Count: req.Count}, messageSenderV1{stream})
+}
+
+func (g testGreeterV2) GetStats(ctx context.Context, req *emptypb.Empty) (*pbv2.StatsReply, error) {
+	stats, err := g.v1.GetStats(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &pbv2.StatsReply{RequestCount: stats.RequestCount, Uptime: stats.Uptime}, nil
+}
+
+// messageSenderV1 sends version 1 messages on a version 2 stream
+type messageSenderV1 struct {
+	pbv2.Greeter_StreamMessagesServer
+}
+
+func (s messageSenderV1) Send(msg *pb.MessageResponse) error {
+	return s.Greeter_StreamMessagesServer.Send(&pbv2.MessageResponse{Message: msg.Message, Index: msg.Index})
+}
+
+func TestBothAPIVersionsOnOneServer(t *testing.T) {
+	network := newTestNetwork()
+	server := network.serve(t, "server")
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	var stdout bytes.Buffer
+	out, err := newResponseOutput(outputJSON, &stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Request 3 streams as well, through whichever version is asked for
+	for _, version := range []int{apiV1, apiV2} {
+		if err := requestCycle(context.Background(), newGreeterClient(conn, version), 3, "server", defaultRPCMethods, defaultRPCTimeouts, out); err != nil {
+			t.Fatalf("requestCycle with API v%d: %v", version, err)
+		}
+	}
+	if got := server.count.Load(); got != 2 {
+		t.Errorf("server saw %d SayHello calls, want 2", got)
+	}
+
+	decoder := json.NewDecoder(&stdout)
+	var lines []responseLine
+	for decoder.More() {
+		var line responseLine
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("decoding output: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	// A SayHello reply and streamCount messages per version
+	if want := 2 * (1 + int(streamCount)); len(lines) != want {
+		t.Fatalf("got %d responses, want %d: %+v", len(lines), want, lines)
+	}
+	v1Hello, v2Hello := lines[0], lines[1+streamCount]
+	if v1Hello.Method != "SayHello" || v1Hello.Count != 1 || v1Hello.ServedAt != nil {
+		t.Errorf("v1 SayHello response = %+v, want request count 1 without a serving time", v1Hello)
+	}
+	if v2Hello.Method != "SayHello" || v2Hello.Count != 2 || v2Hello.ServedAt == nil || !v2Hello.ServedAt.Equal(servedAt) {
+		t.Errorf("v2 SayHello response = %+v, want request count 2 served at %v", v2Hello, servedAt)
+	}
+	if last := lines[len(lines)-1]; last.Method != "StreamMessages" || *last.Index != streamCount-1 {
+		t.Errorf("last v2 response = %+v, want the final streamed message", last)
+	}
+
+	var stats bytes.Buffer
+	if err := printStats(context.Background(), newEndpointPool("passthrough:///server"), apiV2, requestTimeout, &stats, network.dialer()); err != nil {
+		t.Fatalf("printStats with API v2: %v", err)
+	}
+	if want := "Server passthrough:///server: 2 requests served, up 1m0s\n"; stats.String() != want {
+		t.Errorf("v2 stats = %q, want %q", stats.String(), want)
+	}
+}
+
+func TestCheckAPIVersion(t *testing.T) {
+	for _, version := range []int{apiV1, apiV2} {
+		if err := checkAPIVersion(version); err != nil {
+			t.Errorf("checkAPIVersion(%d) = %v, want nil", version, err)
+		}
+	}
+	if err := checkAPIVersion(3); err == nil {
+		t.Error("checkAPIVersion(3) = nil, want an error")
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// tokenCredentials attaches the shared secret to every RPC. The server is only
+// reachable over a local socket, so it is sent without transport security.
+type tokenCredentials string
+
+var _ credentials.PerRPCCredentials = tokenCredentials("")
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool { return false }
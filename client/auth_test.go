@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTokenCredentialsAttachToken(t *testing.T) {
+	var got []string
+	network := newTestNetwork()
+	network.serve(t, "server", grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		got = md.Get("authorization")
+		return handler(ctx, req)
+	}))
+
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer(), grpc.WithPerRPCCredentials(tokenCredentials("s3cret")))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := pb.NewGreeterClient(conn).SayHello(context.Background(), &pb.HelloRequest{Name: "test"}); err != nil {
+		t.Fatalf("SayHello: %v", err)
+	}
+	if len(got) != 1 || got[0] != "Bearer s3cret" {
+		t.Errorf("authorization metadata = %q, want [\"Bearer s3cret\"]", got)
+	}
+}
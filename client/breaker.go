@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// errCircuitOpen is returned instead of making a call while the circuit is open
+var errCircuitOpen = errors.New("circuit breaker open")
+
+// breakerState is the state of a circuitBreaker
+type breakerState int
+
+const (
+	// Calls go through
+	breakerClosed breakerState = iota
+	// Calls are skipped until the cool-down has passed
+	breakerOpen
+	// A single trial call decides whether to close or reopen the circuit
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// circuitBreaker stops the client from hammering a failing server: after
+// threshold consecutive failed calls it skips calls for coolDown, then lets one
+// trial call through to test recovery. It is safe for concurrent use, as by
+// the -concurrency workers: while the trial call is in flight, other calls are
+// skipped as if the circuit were still open.
+type circuitBreaker struct {
+	threshold int
+	coolDown  time.Duration
+	// Clock, replaceable in tests
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(threshold int, coolDown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, coolDown: coolDown, now: time.Now}
+}
+
+// do runs call unless the circuit is open, and records its outcome
+func (b *circuitBreaker) do(call func() error) error {
+	b.mu.Lock()
+	switch b.state {
+	case breakerOpen:
+		remaining := b.coolDown - b.now().Sub(b.openedAt)
+		if remaining > 0 {
+			b.mu.Unlock()
+			log.Printf("Circuit breaker open, skipping request (trial in %v)", remaining.Round(time.Second))
+			return errCircuitOpen
+		}
+		b.state = breakerHalfOpen
+		log.Println("Circuit breaker half-open, sending a trial request")
+	case breakerHalfOpen:
+		b.mu.Unlock()
+		return errCircuitOpen
+	}
+	b.mu.Unlock()
+
+	err := call()
+	b.record(err)
+	return err
+}
+
+// record updates the circuit with the outcome of a call
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		if b.state != breakerClosed {
+			log.Println("Circuit breaker closed, server recovered")
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		if b.state == breakerHalfOpen {
+			log.Printf("Circuit breaker reopened, trial request failed; pausing requests for %v", b.coolDown)
+		} else {
+			log.Printf("Circuit breaker opened after %d consecutive failures; pausing requests for %v", b.failures, b.coolDown)
+		}
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// reset closes the circuit, e.g. after switching to another endpoint
+func (b *circuitBreaker) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = breakerClosed
+	b.failures = 0
+}
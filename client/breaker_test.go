@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const coolDown = 30 * time.Second
+	now := time.Unix(0, 0)
+	breaker := newCircuitBreaker(3, coolDown)
+	breaker.now = func() time.Time { return now }
+
+	calls := 0
+	failing := errors.New("unavailable")
+	fail := func() error { calls++; return failing }
+	succeed := func() error { calls++; return nil }
+
+	// Failures below the threshold keep the circuit closed
+	for i := 0; i < 3; i++ {
+		if err := breaker.do(fail); !errors.Is(err, failing) {
+			t.Fatalf("call %d = %v, want the call's error", i+1, err)
+		}
+	}
+	if breaker.state != breakerOpen {
+		t.Fatalf("state after 3 failures = %v, want open", breaker.state)
+	}
+
+	// While open, calls are skipped
+	now = now.Add(coolDown / 2)
+	if err := breaker.do(succeed); !errors.Is(err, errCircuitOpen) || calls != 3 {
+		t.Fatalf("call while open = %v after %d calls, want errCircuitOpen without calling", err, calls)
+	}
+
+	// After the cool-down a failed trial reopens the circuit for another cool-down
+	now = now.Add(coolDown / 2)
+	if err := breaker.do(fail); !errors.Is(err, failing) || calls != 4 {
+		t.Fatalf("trial call = %v after %d calls, want the call's error", err, calls)
+	}
+	if breaker.state != breakerOpen {
+		t.Fatalf("state after failed trial = %v, want open", breaker.state)
+	}
+	now = now.Add(coolDown - time.Second)
+	if err := breaker.do(succeed); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("call before the new cool-down ended = %v, want errCircuitOpen", err)
+	}
+
+	// A successful trial closes it again
+	now = now.Add(time.Second)
+	if err := breaker.do(succeed); err != nil {
+		t.Fatalf("trial call = %v, want success", err)
+	}
+	if breaker.state != breakerClosed || breaker.failures != 0 {
+		t.Errorf("state after successful trial = %v with %d failures, want closed with 0", breaker.state, breaker.failures)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	breaker := newCircuitBreaker(2, time.Minute)
+	failing := errors.New("unavailable")
+
+	breaker.do(func() error { return failing })
+	breaker.do(func() error { return nil })
+	breaker.do(func() error { return failing })
+	if breaker.state != breakerClosed {
+		t.Errorf("state = %v, want closed since the failures were not consecutive", breaker.state)
+	}
+}
+
+func TestCircuitBreakerSingleTrialWhenConcurrent(t *testing.T) {
+	now := time.Unix(0, 0)
+	breaker := newCircuitBreaker(1, time.Second)
+	breaker.now = func() time.Time { return now }
+	breaker.do(func() error { return errors.New("unavailable") })
+
+	// While the trial call is in flight, other callers are still skipped
+	now = now.Add(time.Second)
+	inTrial := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- breaker.do(func() error {
+			close(inTrial)
+			<-release
+			return nil
+		})
+	}()
+	<-inTrial
+	if err := breaker.do(func() error { return nil }); !errors.Is(err, errCircuitOpen) {
+		t.Errorf("call during the trial = %v, want errCircuitOpen", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("trial call = %v", err)
+	}
+	if err := breaker.do(func() error { return nil }); err != nil {
+		t.Errorf("call after a successful trial = %v, want it to go through", err)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+
+	"multi-process-docker/internal/config"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// versionDialOptions returns the interceptors that send version as the
+// client's build version with every RPC, for the server to check
+func versionDialOptions(version string) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(metadata.AppendToOutgoingContext(ctx, config.ClientVersionHeader, version), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(metadata.AppendToOutgoingContext(ctx, config.ClientVersionHeader, version), desc, cc, method, opts...)
+		}),
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"multi-process-docker/internal/config"
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestVersionDialOptionsSendVersion(t *testing.T) {
+	// Client versions seen by the server, by method
+	var mu sync.Mutex
+	seen := make(map[string][]string)
+	record := func(ctx context.Context, method string) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		seen[method] = md.Get(config.ClientVersionHeader)
+	}
+
+	network := newTestNetwork()
+	network.serve(t, "server",
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			record(ctx, info.FullMethod)
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			record(ss.Context(), info.FullMethod)
+			return handler(srv, ss)
+		}),
+	)
+	opts := append([]grpc.DialOption{network.dialer()}, versionDialOptions("v1.4.0")...)
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), opts...)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	// The third request cycle also streams
+	if err := requestCycle(context.Background(), pb.NewGreeterClient(conn), 3, "server", defaultRPCMethods, defaultRPCTimeouts, nil); err != nil {
+		t.Fatalf("requestCycle: %v", err)
+	}
+
+	for _, method := range []string{"/hello.Greeter/SayHello", "/hello.Greeter/StreamMessages"} {
+		if got := seen[method]; len(got) != 1 || got[0] != "v1.4.0" {
+			t.Errorf("%s client version = %q, want [v1.4.0]", method, got)
+		}
+	}
+}
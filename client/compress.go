@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// compressionOption returns the dial option compressing every RPC with the
+// named compressor, which must be registered
+func compressionOption(name string) (grpc.DialOption, error) {
+	if encoding.GetCompressor(name) == nil {
+		return nil, fmt.Errorf("unknown compressor %q (expected %s)", name, gzip.Name)
+	}
+	return grpc.WithDefaultCallOptions(grpc.UseCompressor(name)), nil
+}
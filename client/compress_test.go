@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	pb "multi-process-docker/proto/v1"
+)
+
+func TestCompressionOption(t *testing.T) {
+	if _, err := compressionOption("brotli"); err == nil {
+		t.Error("compressionOption accepted an unregistered compressor")
+	}
+
+	opt, err := compressionOption("gzip")
+	if err != nil {
+		t.Fatalf("compressionOption(gzip): %v", err)
+	}
+	network := newTestNetwork()
+	network.serve(t, "server")
+	endpoints := newEndpointPool("passthrough:///server")
+	conn, err := connect(context.Background(), endpoints, network.dialer(), opt)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Every third request also streams, so both RPCs run compressed
+	requestNum := 2
+	if err := makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, endpoints.Current(), defaultRPCMethods, defaultRPCTimeouts, nil); err != nil {
+		t.Fatalf("compressed request: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+)
+
+// serverConn owns the client's connection to the active endpoint. Every dial
+// goes through connect, so reopening after failover, a broken connection or
+// an idle close gets the same retries and backoff as the first connection.
+// It is only used from the request loop's goroutine.
+type serverConn struct {
+	ctx            context.Context
+	endpoints      *endpointPool
+	apiVersion     int
+	dialOpts       []grpc.DialOption
+	reconnectAfter time.Duration
+	idleTimeout    time.Duration
+
+	// Signalled by watchConnection when the connection stays broken
+	reconnect chan struct{}
+	// Fires once the open connection has gone idleTimeout without a request
+	idleTimer *time.Timer
+
+	conn      *grpc.ClientConn // nil while closed
+	stopWatch context.CancelFunc
+}
+
+// newServerConn returns a closed connection to endpoints, whose clients speak
+// apiVersion of the Greeter API. An idleTimeout of 0 keeps the connection open
+// between requests.
+func newServerConn(ctx context.Context, endpoints *endpointPool, apiVersion int, reconnectAfter, idleTimeout time.Duration, opts ...grpc.DialOption) *serverConn {
+	idleTimer := time.NewTimer(idleTimeout)
+	idleTimer.Stop()
+	return &serverConn{
+		ctx:            ctx,
+		endpoints:      endpoints,
+		apiVersion:     apiVersion,
+		dialOpts:       opts,
+		reconnectAfter: reconnectAfter,
+		idleTimeout:    idleTimeout,
+		reconnect:      make(chan struct{}, 1),
+		idleTimer:      idleTimer,
+	}
+}
+
+// open dials the active endpoint unless the connection is already open
+func (c *serverConn) open() error {
+	if c.conn != nil {
+		return nil
+	}
+	conn, err := connect(c.ctx, c.endpoints, c.dialOpts...)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+
+	// Watch the connection and ask for a redial when it stays broken
+	watchCtx, stopWatch := context.WithCancel(c.ctx)
+	c.stopWatch = stopWatch
+	go watchConnection(watchCtx, conn, c.reconnectAfter, c.reconnect)
+	c.touch()
+	return nil
+}
+
+// close closes the connection until the next open
+func (c *serverConn) close() {
+	c.idleTimer.Stop()
+	if c.conn == nil {
+		return
+	}
+	c.stopWatch()
+	c.conn.Close()
+	c.conn = nil
+}
+
+// redial replaces the connection, optionally moving to the next endpoint first
+func (c *serverConn) redial(advance bool) error {
+	c.close()
+	if advance {
+		c.endpoints.Advance()
+	}
+	return c.open()
+}
+
+// client returns a Greeter client on the open connection
+func (c *serverConn) client() pb.GreeterClient {
+	return newGreeterClient(c.conn, c.apiVersion)
+}
+
+// touch marks the connection as used, restarting the idle timeout
+func (c *serverConn) touch() {
+	if c.idleTimeout > 0 && c.conn != nil {
+		c.idleTimer.Reset(c.idleTimeout)
+	}
+}
+
+// idle fires once the open connection has gone idleTimeout without a touch
+func (c *serverConn) idle() <-chan time.Time {
+	return c.idleTimer.C
+}
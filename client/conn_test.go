@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestServerConnReopensAfterIdleTimeout(t *testing.T) {
+	network := newTestNetwork()
+	greeter := network.serve(t, "server")
+	endpoints := newEndpointPool("passthrough:///server")
+
+	server := newServerConn(context.Background(), endpoints, apiV1, 10*time.Second, 100*time.Millisecond, network.dialer())
+	if err := server.open(); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer server.close()
+
+	var requestNum int
+	if err := makeRequests(context.Background(), server.client(), &requestNum, endpoints.Current(), defaultRPCMethods, defaultRPCTimeouts, nil); err != nil {
+		t.Fatalf("request before idle: %v", err)
+	}
+	server.touch()
+
+	select {
+	case <-server.idle():
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle timeout did not fire")
+	}
+	idleConn := server.conn
+	server.close()
+	if state := idleConn.GetState(); state != connectivity.Shutdown {
+		t.Errorf("idle connection state = %v, want %v", state, connectivity.Shutdown)
+	}
+
+	// The next request dials again through connect
+	if err := server.open(); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if server.conn == idleConn {
+		t.Fatal("reopen reused the closed connection")
+	}
+	if err := makeRequests(context.Background(), server.client(), &requestNum, endpoints.Current(), defaultRPCMethods, defaultRPCTimeouts, nil); err != nil {
+		t.Fatalf("request after idle: %v", err)
+	}
+	if got := greeter.count.Load(); got != 2 {
+		t.Errorf("server handled %d requests, want 2", got)
+	}
+}
+
+func TestServerConnStaysOpenWithoutIdleTimeout(t *testing.T) {
+	network := newTestNetwork()
+	network.serve(t, "server")
+	endpoints := newEndpointPool("passthrough:///server")
+
+	server := newServerConn(context.Background(), endpoints, apiV1, 10*time.Second, 0, network.dialer())
+	if err := server.open(); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer server.close()
+	server.touch()
+
+	select {
+	case <-server.idle():
+		t.Fatal("idle timeout fired while disabled")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
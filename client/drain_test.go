@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDrainContextLetsStreamFinish(t *testing.T) {
+	tests := []struct {
+		name     string
+		drain    time.Duration
+		wantCode codes.Code
+	}{
+		{"stream finishes within the deadline", 3 * time.Second, codes.OK},
+		{"stream cut off at the deadline", 50 * time.Millisecond, codes.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := newTestNetwork()
+			server := network.serve(t, "server")
+			server.streamDelay = 100 * time.Millisecond
+			endpoints := newEndpointPool("passthrough:///server")
+			conn, err := connect(context.Background(), endpoints, network.dialer())
+			if err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			rpcCtx, cancelRPCs := drainContext(ctx, tt.drain)
+			defer cancelRPCs()
+
+			// Shutdown begins while the 500ms stream is in flight
+			time.AfterFunc(150*time.Millisecond, cancel)
+			// The third request cycle also streams
+			requestNum := 2
+			start := time.Now()
+			err = makeRequests(rpcCtx, pb.NewGreeterClient(conn), &requestNum, endpoints.Current(), defaultRPCMethods, defaultRPCTimeouts, nil)
+
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("makeRequests() = %v, want code %v", err, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK && time.Since(start) < 500*time.Millisecond {
+				t.Error("stream returned before all messages were sent")
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// endpointPool cycles through the configured server endpoints for failover
+type endpointPool struct {
+	mu      sync.Mutex
+	targets []string
+	current int
+}
+
+// newEndpointPool parses a comma-separated list of targets. Bare paths are
+// treated as Unix Domain Sockets.
+func newEndpointPool(list string) *endpointPool {
+	var targets []string
+	for _, target := range strings.Split(list, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		if !strings.Contains(target, "://") && strings.HasPrefix(target, "/") {
+			target = "unix://" + target
+		}
+		targets = append(targets, target)
+	}
+	return &endpointPool{targets: targets}
+}
+
+// Current returns the active endpoint
+func (p *endpointPool) Current() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.targets[p.current]
+}
+
+// Advance switches to the next endpoint, wrapping around, and returns it
+func (p *endpointPool) Advance() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = (p.current + 1) % len(p.targets)
+	return p.targets[p.current]
+}
+
+// Len returns the number of endpoints
+func (p *endpointPool) Len() int {
+	return len(p.targets)
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestEndpointPool(t *testing.T) {
+	pool := newEndpointPool(" /tmp/a.sock, ,dns:///grpc.internal:50051,unix:///tmp/b.sock")
+	want := []string{"unix:///tmp/a.sock", "dns:///grpc.internal:50051", "unix:///tmp/b.sock"}
+
+	if pool.Len() != len(want) {
+		t.Fatalf("Len() = %d, want %d", pool.Len(), len(want))
+	}
+	if got := pool.Current(); got != want[0] {
+		t.Errorf("Current() = %q, want %q", got, want[0])
+	}
+	// Advance cycles through every endpoint and wraps around
+	for i := 1; i <= len(want); i++ {
+		if got := pool.Advance(); got != want[i%len(want)] {
+			t.Errorf("Advance() #%d = %q, want %q", i, got, want[i%len(want)])
+		}
+	}
+}
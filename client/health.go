@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// First pause between health checks while waiting for the server to serve
+	healthPollDelay = 100 * time.Millisecond
+	// Longest pause between health checks, reached by doubling
+	healthPollMaxDelay = 2 * time.Second
+)
+
+// waitForServing blocks until the server's health service reports SERVING,
+// checking again after a pause that doubles from initialDelay up to maxDelay.
+// A server without a health service is taken to be serving. Each status seen
+// is logged once rather than on every check, so a slow boot stays quiet. It
+// returns ctx.Err() if ctx is done first.
+func waitForServing(ctx context.Context, conn grpc.ClientConnInterface, initialDelay, maxDelay time.Duration) error {
+	client := healthpb.NewHealthClient(conn)
+	delay := initialDelay
+	var last string
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+		resp, err := client.Check(checkCtx, &healthpb.HealthCheckRequest{})
+		cancel()
+
+		current := resp.GetStatus().String()
+		switch {
+		case err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING:
+			if last != "" {
+				log.Println("Server is SERVING")
+			}
+			return nil
+		case status.Code(err) == codes.Unimplemented:
+			return nil
+		case err != nil:
+			current = status.Code(err).String()
+		}
+		if current != last {
+			log.Printf("Waiting for the server to report SERVING (currently %s)...", current)
+			last = current
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
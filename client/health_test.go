@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestWaitForServingHoldsFirstRequest(t *testing.T) {
+	network := newTestNetwork()
+	lis := bufconn.Listen(1 << 20)
+	network.listeners["server"] = lis
+	greeter := &testGreeter{name: "server", server: grpc.NewServer()}
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	pb.RegisterGreeterServer(greeter.server, greeter)
+	healthpb.RegisterHealthServer(greeter.server, healthServer)
+	go greeter.server.Serve(lis)
+	defer greeter.server.Stop()
+
+	const bootTime = 300 * time.Millisecond
+	time.AfterFunc(bootTime, func() { healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING) })
+
+	report := newLoadReport()
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), append([]grpc.DialOption{network.dialer()}, report.dialOptions()...)...)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	begin := time.Now()
+	if err := waitForServing(context.Background(), conn, 10*time.Millisecond, 50*time.Millisecond); err != nil {
+		t.Fatalf("waitForServing: %v", err)
+	}
+	if waited := time.Since(begin); waited < bootTime-50*time.Millisecond {
+		t.Errorf("waitForServing returned after %v, before the server was SERVING", waited)
+	}
+
+	var requestNum int
+	if err := makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, "server", defaultRPCMethods, defaultRPCTimeouts, nil); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if strings.Contains(logs.String(), "Error") {
+		t.Errorf("logs = %q, want no errors", logs.String())
+	}
+	// The status is logged once, however many checks it took
+	if n := strings.Count(logs.String(), "Waiting for the server"); n != 1 {
+		t.Errorf("logged the wait %d times, want once:\n%s", n, logs.String())
+	}
+	if got := report.summary(); got.Total != 1 || got.Succeeded != 1 {
+		t.Errorf("load report = %v, want only the first SayHello", got)
+	}
+}
+
+func TestWaitForServingWithoutHealthService(t *testing.T) {
+	network := newTestNetwork()
+	network.serve(t, "server")
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := waitForServing(ctx, conn, 10*time.Millisecond, 50*time.Millisecond); err != nil {
+		t.Errorf("waitForServing() = %v, want nil for a server without a health service", err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "time"
+
+// exitIdle is the client's exit code after -shutdown-on-idle ends it, so a
+// supervisor can tell an idle exit from a failure (1) or a signal (0)
+const exitIdle = 3
+
+// idleShutdown fires once the client has gone timeout without a successful
+// response, a sign that the server is gone. A timeout of 0 disables it.
+type idleShutdown struct {
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+// newIdleShutdown returns an idleShutdown whose window starts now
+func newIdleShutdown(timeout time.Duration) *idleShutdown {
+	timer := time.NewTimer(timeout)
+	if timeout <= 0 {
+		timer.Stop()
+	}
+	return &idleShutdown{timeout: timeout, timer: timer}
+}
+
+// succeeded records a successful response, restarting the window
+func (s *idleShutdown) succeeded() {
+	if s.timeout > 0 {
+		s.timer.Reset(s.timeout)
+	}
+}
+
+// expired fires once the window passes without a successful response
+func (s *idleShutdown) expired() <-chan time.Time {
+	return s.timer.C
+}
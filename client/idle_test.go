@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+)
+
+func TestIdleShutdownAfterServerStops(t *testing.T) {
+	network := newTestNetwork()
+	greeter := network.serve(t, "server")
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+	client := pb.NewGreeterClient(conn)
+
+	const window = 300 * time.Millisecond
+	idle := newIdleShutdown(window)
+	timeouts := rpcTimeouts{unary: 50 * time.Millisecond, stream: time.Second}
+	var requestNum int
+	request := func() error {
+		err := makeRequests(context.Background(), client, &requestNum, "server", defaultRPCMethods, timeouts, nil)
+		if err == nil {
+			idle.succeeded()
+		}
+		return err
+	}
+
+	if err := request(); err != nil {
+		t.Fatalf("request before the server stopped: %v", err)
+	}
+	greeter.server.Stop()
+	lastSuccess := time.Now()
+
+	// Keep making requests, like the main loop, until the window runs out
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			if err := request(); err == nil {
+				t.Fatal("request succeeded after the server stopped")
+			}
+			continue
+		case <-idle.expired():
+		case <-deadline:
+			t.Fatal("idle window never ran out after the server stopped")
+		}
+		break
+	}
+	if idleFor := time.Since(lastSuccess); idleFor < window-50*time.Millisecond {
+		t.Errorf("idle shutdown after %v, want at least the %v window", idleFor, window)
+	}
+}
+
+func TestIdleShutdownDisabled(t *testing.T) {
+	idle := newIdleShutdown(0)
+	idle.succeeded()
+	select {
+	case <-idle.expired():
+		t.Error("idle shutdown fired with a timeout of 0")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
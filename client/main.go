@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -9,94 +12,267 @@ import (
 	"syscall"
 	"time"
 
-	pb "multi-process-docker/proto"
+	"multi-process-docker/internal/buildinfo"
+	"multi-process-docker/internal/config"
+	"multi-process-docker/internal/tracing"
+	pb "multi-process-docker/proto/v1"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	socketPath     = "/tmp/grpc.sock"
 	retryDelay     = 2 * time.Second
 	requestDelay   = 5 * time.Second
 	maxRetries     = 10
 	dialTimeout    = 5 * time.Second
 	requestTimeout = 10 * time.Second
+	// Messages requested from StreamMessages on every third request
+	streamCount = 5
+	// Pause the server takes after sending each streamed message
+	streamInterval = 500 * time.Millisecond
+	// Consecutive Unavailable errors before failing over to the next endpoint
+	failoverThreshold = 3
 )
 
 func main() {
-	log.Println("Starting gRPC Client...")
+	socket := flag.String("socket", "", "Unix socket path of the server (default $GRPC_SOCKET_PATH or "+config.DefaultSocketPath+")")
+	targets := flag.String("targets", "", "Comma-separated server endpoints to fail over between (paths are treated as UDS); overrides -socket")
+	reconnectAfter := flag.Duration("reconnect-after", 10*time.Second, "Force a reconnect when the connection has been failing for this long")
+	idleTimeout := flag.Duration("idle-timeout", 0, "Close the connection after this long without requests and redial on the next one (0 keeps it open)")
+	unaryTimeout := flag.Duration("unary-timeout", requestTimeout, "Deadline of each unary RPC (SayHello, GetStats)")
+	streamTimeoutFlag := flag.Duration("stream-timeout", streamTimeout(streamCount), fmt.Sprintf("Deadline of each StreamMessages call of %d messages; allow for the server's %v pause before each message", streamCount, streamInterval))
+	drainTimeout := flag.Duration("drain-timeout", 5*time.Second, "How long in-flight requests may run after a shutdown signal")
+	authToken := flag.String("auth-token", "", "Shared secret sent with every RPC (default $"+config.AuthTokenEnv+")")
+	breakerThreshold := flag.Int("breaker-threshold", 5, "Consecutive failed requests that open the circuit breaker")
+	breakerCoolDown := flag.Duration("breaker-cooldown", 30*time.Second, "How long the open circuit breaker skips requests before a trial request")
+	compress := flag.String("compress", "", "Compress RPCs with this compressor (gzip), or send them uncompressed when empty")
+	outputFormat := flag.String("output", outputText, "Response output format: text logs them, json prints one JSON object per response to stdout")
+	shutdownOnIdle := flag.Duration("shutdown-on-idle", 0, fmt.Sprintf("Exit with code %d after this long without a successful response, for ephemeral workloads whose server has gone (0 disables it)", exitIdle))
+	retryStreams := flag.Bool("retry-broken-streams", false, fmt.Sprintf("Retry a stream broken off with Unavailable, as by a server restart, as soon as the server is back (waiting up to %v) instead of at the next request", streamRetryWindow))
+	apiVersion := flag.Int("api-version", apiV1, "Version of the Greeter API to call: 1, or 2 whose SayHello replies also say when the server answered")
+	rpcList := flag.String("rpcs", defaultRPCs, "Comma-separated RPCs each request cycle runs, from "+rpcNames()+"; stream runs in every 3rd cycle only")
+	concurrency := flag.Int("concurrency", 1, "Request cycles kept in flight at once; above 1 they run back to back from that many workers sharing the connection, for load testing, without failover between -targets, -retry-streams, -idle-timeout, -reconnect-after or -shutdown-on-idle")
+	reportInterval := flag.Duration("report-interval", 0, "Also log the load report of request counts, failures and latencies at this interval, not only on shutdown (0 disables it)")
+	stats := flag.Bool("stats", false, "Print the server's request count and uptime and exit")
+	version := flag.Bool("version", false, "Print the version and exit")
+	flag.Parse()
 
-	// Set up signal handling
+	if *version {
+		fmt.Println("grpc-client", buildinfo.Get())
+		return
+	}
+
+	log.Printf("Starting gRPC Client %s...", buildinfo.Get())
+
+	// Set on the way out to exit with something other than 0, once every
+	// deferred cleanup has run
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	if err := checkAPIVersion(*apiVersion); err != nil {
+		log.Fatalf("Invalid -api-version: %v", err)
+	}
+
+	rpcs, err := parseRPCs(*rpcList)
+	if err != nil {
+		log.Fatalf("Invalid -rpcs: %v", err)
+	}
+
+	if *concurrency < 1 {
+		log.Fatalf("Invalid -concurrency %d: must be at least 1", *concurrency)
+	}
+
+	output, err := newResponseOutput(*outputFormat, os.Stdout)
+	if err != nil {
+		log.Fatalf("Invalid -output: %v", err)
+	}
+
+	if *targets == "" {
+		*targets = config.SocketPath(*socket)
+	}
+	endpoints := newEndpointPool(*targets)
+	if endpoints.Len() == 0 {
+		log.Fatalf("No server endpoints configured")
+	}
+
+	// Every RPC tells the server which build is calling, so a server that is
+	// not compatible with it can say so
+	dialOpts := versionDialOptions(buildinfo.Get().Version)
+	if *compress != "" {
+		opt, err := compressionOption(*compress)
+		if err != nil {
+			log.Fatalf("Invalid -compress: %v", err)
+		}
+		dialOpts = append(dialOpts, opt)
+	}
+	if token := config.AuthToken(*authToken); token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	}
+
+	if *stats {
+		if err := printStats(context.Background(), endpoints, *apiVersion, *unaryTimeout, os.Stdout, dialOpts...); err != nil {
+			log.Fatalf("Failed to get server stats: %v", err)
+		}
+		return
+	}
+
+	// Set up tracing (exports only when an OTLP endpoint is configured)
+	shutdownTracing, err := tracing.Setup(context.Background(), "grpc-client")
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Printf("Failed to flush traces: %v", err)
+		}
+	}()
+
+	// Set up signal handling. ctx stops the request loop, while rpcCtx is only
+	// cancelled once in-flight requests have had drainTimeout to finish.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	rpcCtx, cancelRPCs := drainContext(ctx, *drainTimeout)
+	defer cancelRPCs()
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Every RPC of the request loop counts towards the load report
+	report := newLoadReport()
+	dialOpts = append(dialOpts, report.dialOptions()...)
+	defer func() { log.Printf("Load report: %v", report.summary()) }()
+	if *reportInterval > 0 {
+		go report.logEvery(ctx, *reportInterval)
+	}
+
 	go func() {
 		sig := <-sigChan
-		log.Printf("Received signal: %v. Shutting down...", sig)
+		log.Printf("Received signal: %v. Shutting down (draining in-flight requests for up to %v)...", sig, *drainTimeout)
 		cancel()
 	}()
 
 	// Connect to server with retries
-	var conn *grpc.ClientConn
-	var err error
-
-	for i := 0; i < maxRetries; i++ {
+	server := newServerConn(ctx, endpoints, *apiVersion, *reconnectAfter, *idleTimeout, dialOpts...)
+	if err := server.open(); err != nil {
 		if ctx.Err() != nil {
 			log.Println("Shutdown requested, stopping connection attempts")
 			return
 		}
+		log.Fatalf("Failed to connect after %d attempts: %v", maxRetries, err)
+	}
+	defer server.close()
 
-		log.Printf("Attempting to connect to server (attempt %d/%d)...", i+1, maxRetries)
-
-		dialCtx, dialCancel := context.WithTimeout(ctx, dialTimeout)
-		conn, err = grpc.DialContext(
-			dialCtx,
-			"unix://"+socketPath,
-			grpc.WithTransportCredentials(insecure.NewCredentials()),
-			grpc.WithBlock(),
-		)
-		dialCancel()
-
-		if err == nil {
-			log.Println("Successfully connected to gRPC server via UDS")
-			break
-		}
-
-		log.Printf("Failed to connect: %v. Retrying in %v...", err, retryDelay)
-		select {
-		case <-time.After(retryDelay):
-			continue
-		case <-ctx.Done():
-			log.Println("Shutdown requested, stopping connection attempts")
-			return
+	// redial replaces the connection, optionally moving to the next endpoint first
+	redial := func(advance bool) bool {
+		if err := server.redial(advance); err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			log.Fatalf("Failed to connect after %d attempts: %v", maxRetries, err)
 		}
+		return true
 	}
 
-	if err != nil {
-		log.Fatalf("Failed to connect after %d attempts: %v", maxRetries, err)
+	// Hold the first request until the server is ready for it, so a server
+	// that is still booting does not show up as request errors
+	if err := waitForServing(ctx, server.conn, healthPollDelay, healthPollMaxDelay); err != nil {
+		log.Println("Client shutting down gracefully...")
+		return
 	}
-	defer conn.Close()
-
-	client := pb.NewGreeterClient(conn)
 
 	// Request counter
 	requestNum := 0
+	// Consecutive Unavailable errors on the active endpoint
+	unavailable := 0
+
+	// Skips requests while the server keeps failing
+	breaker := newCircuitBreaker(*breakerThreshold, *breakerCoolDown)
+	timeouts := rpcTimeouts{unary: *unaryTimeout, stream: *streamTimeoutFlag}
+	idle := newIdleShutdown(*shutdownOnIdle)
+	request := func() error {
+		err := breaker.do(func() error {
+			defer server.touch()
+			err := makeRequests(rpcCtx, server.client(), &requestNum, endpoints.Current(), rpcs, timeouts, output)
+			if *retryStreams {
+				err = retryBrokenStream(rpcCtx, server.conn, *apiVersion, err, requestNum, timeouts.stream, output)
+			}
+			return err
+		})
+		if err == nil {
+			idle.succeeded()
+		}
+		return err
+	}
+
+	// Load testing keeps the workers busy until shutdown, instead of a request
+	// every requestDelay. The workers share the circuit breaker and back off
+	// for requestDelay after a failure, but have no failover, stream retries,
+	// idle handling or shutdown on idle.
+	if *concurrency > 1 {
+		log.Printf("Running %d concurrent request workers against %s", *concurrency, endpoints.Current())
+		stats := runWorkers(ctx, rpcCtx, server.client(), *concurrency, breaker, requestDelay, endpoints.Current(), rpcs, timeouts, output)
+		log.Printf("Workers completed %d request cycles, %d failed, %d skipped by the circuit breaker", stats.Completed, stats.Failed, stats.Skipped)
+		log.Println("Client shutting down gracefully...")
+		return
+	}
 
 	// Main loop - make requests periodically
 	ticker := time.NewTicker(requestDelay)
 	defer ticker.Stop()
 
 	// Make first request immediately
-	makeRequests(ctx, client, &requestNum)
+	err = request()
 
 	for {
+		if status.Code(err) == codes.Unavailable {
+			unavailable++
+		} else if !errors.Is(err, errCircuitOpen) {
+			unavailable = 0
+		}
+
+		if unavailable >= failoverThreshold && endpoints.Len() > 1 {
+			log.Printf("Endpoint %s unavailable for %d requests, failing over", endpoints.Current(), unavailable)
+			if !redial(true) {
+				log.Println("Client shutting down gracefully...")
+				return
+			}
+			unavailable = 0
+			breaker.reset()
+		}
+
 		select {
 		case <-ticker.C:
-			makeRequests(ctx, client, &requestNum)
+			if server.conn == nil {
+				log.Printf("Reopening idle connection to %s...", endpoints.Current())
+				if !redial(false) {
+					log.Println("Client shutting down gracefully...")
+					return
+				}
+			}
+			err = request()
+		case <-server.idle():
+			log.Printf("Connection idle for %v, closing it until the next request", *idleTimeout)
+			server.close()
+		case <-server.reconnect:
+			log.Printf("Reconnecting to %s...", endpoints.Current())
+			if !redial(false) {
+				log.Println("Client shutting down gracefully...")
+				return
+			}
+			err = nil
+		case <-idle.expired():
+			log.Printf("No successful response for %v, shutting down (exit code %d)", *shutdownOnIdle, exitIdle)
+			exitCode = exitIdle
+			return
 		case <-ctx.Done():
 			log.Println("Client shutting down gracefully...")
 			return
@@ -104,51 +280,163 @@ func main() {
 	}
 }
 
-func makeRequests(ctx context.Context, client pb.GreeterClient, requestNum *int) {
-	*requestNum++
+// drainContext returns a context for requests that is cancelled timeout after
+// ctx is done, so requests in flight at shutdown get a chance to finish
+func drainContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	rpcCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-rpcCtx.Done():
+			return
+		}
+		select {
+		case <-time.After(timeout):
+			log.Println("Drain timeout elapsed, cancelling in-flight requests")
+			cancel()
+		case <-rpcCtx.Done():
+		}
+	}()
+	return rpcCtx, cancel
+}
 
-	// SayHello request
-	log.Printf("\n--- Request #%d: SayHello ---", *requestNum)
-	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
-	defer cancel()
+// connect dials the active endpoint, moving on to the next endpoint after each failed attempt.
+// opts are added to the default dial options.
+func connect(ctx context.Context, endpoints *endpointPool, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	var err error
 
-	resp, err := client.SayHello(reqCtx, &pb.HelloRequest{
-		Name: "Docker Client",
-	})
+	for i := 0; i < maxRetries; i++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 
-	if err != nil {
-		log.Printf("Error calling SayHello: %v", err)
-		return
+		target := endpoints.Current()
+		log.Printf("Attempting to connect to server at %s (attempt %d/%d)...", target, i+1, maxRetries)
+
+		var conn *grpc.ClientConn
+		conn, err = grpc.NewClient(
+			target,
+			append([]grpc.DialOption{
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+				grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+			}, opts...)...,
+		)
+		if err == nil {
+			dialCtx, dialCancel := context.WithTimeout(ctx, dialTimeout)
+			err = waitForReady(dialCtx, conn)
+			dialCancel()
+			if err != nil {
+				conn.Close()
+			}
+		}
+
+		if err == nil {
+			log.Printf("Successfully connected to gRPC server at %s", target)
+			return conn, nil
+		}
+
+		if endpoints.Len() > 1 {
+			log.Printf("Failed to connect to %s: %v. Trying %s in %v...", target, err, endpoints.Advance(), retryDelay)
+		} else {
+			log.Printf("Failed to connect: %v. Retrying in %v...", err, retryDelay)
+		}
+
+		select {
+		case <-time.After(retryDelay):
+			continue
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 
-	log.Printf("Response: %s (Server request count: %d)", resp.Message, resp.Count)
+	return nil, err
+}
 
-	// Every 3rd request, also test streaming
-	if *requestNum%3 == 0 {
-		log.Printf("\n--- Request #%d: StreamMessages ---", *requestNum)
-		streamCtx, streamCancel := context.WithTimeout(ctx, requestTimeout)
-		defer streamCancel()
+// waitForReady starts connecting conn and blocks until it is READY or ctx is done
+func waitForReady(ctx context.Context, conn *grpc.ClientConn) error {
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection not ready (state: %v): %w", state, ctx.Err())
+		}
+	}
+}
 
-		stream, err := client.StreamMessages(streamCtx, &pb.StreamRequest{
-			Count: 5,
-		})
+// rpcTimeouts are the deadlines of the client's RPCs, by kind of method
+type rpcTimeouts struct {
+	unary time.Duration
+	// Deadline of a whole stream, which must cover the server's pause before
+	// each message
+	stream time.Duration
+}
 
-		if err != nil {
-			log.Printf("Error calling StreamMessages: %v", err)
-			return
+// defaultRPCTimeouts are the deadlines used without -unary-timeout and -stream-timeout
+var defaultRPCTimeouts = rpcTimeouts{unary: requestTimeout, stream: streamTimeout(streamCount)}
+
+// makeRequests counts up requestNum and runs that request cycle
+func makeRequests(ctx context.Context, client pb.GreeterClient, requestNum *int, endpoint string, rpcs []rpcMethod, timeouts rpcTimeouts, out *responseOutput) error {
+	*requestNum++
+	return requestCycle(ctx, client, *requestNum, endpoint, rpcs, timeouts, out)
+}
+
+// requestCycle runs request cycle requestNum against the server, calling
+// each of rpcs that is due in this cycle and reporting responses to out. It
+// stops at and returns the first RPC error.
+func requestCycle(ctx context.Context, client pb.GreeterClient, requestNum int, endpoint string, rpcs []rpcMethod, timeouts rpcTimeouts, out *responseOutput) error {
+	for _, rpc := range rpcs {
+		if requestNum%rpc.every != 0 {
+			continue
+		}
+		if err := rpc.run(ctx, client, requestNum, endpoint, timeouts, out); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		for {
-			msg, err := stream.Recv()
-			if err == io.EOF {
-				log.Println("Stream completed")
-				break
-			}
-			if err != nil {
-				log.Printf("Error receiving stream: %v", err)
-				break
-			}
-			log.Printf("  Received: %s (index: %d)", msg.Message, msg.Index)
+// errIncompleteStream is returned by doStream when the stream ended before
+// every requested message arrived, whether with an error or an early EOF
+var errIncompleteStream = errors.New("incomplete stream")
+
+// streamTimeout is the default deadline for streaming count messages: the
+// time the server takes to send them on top of the usual request timeout
+func streamTimeout(count int32) time.Duration {
+	return requestTimeout + time.Duration(count)*streamInterval
+}
+
+// doStream requests count streamed messages within timeout and reports each
+// one received to out
+func doStream(ctx context.Context, client pb.GreeterClient, count int32, timeout time.Duration, requestNum int, out *responseOutput) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stream, err := client.StreamMessages(ctx, &pb.StreamRequest{
+		Count: count,
+	})
+	if err != nil {
+		return err
+	}
+
+	var received int32
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
 		}
+		if err != nil {
+			// Wrap both so callers can still read the RPC status code
+			return fmt.Errorf("%w: received %d of %d messages: %w", errIncompleteStream, received, count, err)
+		}
+		received++
+		out.streamMessage(requestNum, msg)
+	}
+
+	if received != count {
+		return fmt.Errorf("%w: server closed the stream after %d of %d messages", errIncompleteStream, received, count)
 	}
+	return nil
 }
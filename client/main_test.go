@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+	pbv2 "multi-process-docker/proto/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// testGreeter is a Greeter server that counts the requests it receives
+type testGreeter struct {
+	pb.UnimplementedGreeterServer
+	name   string
+	count  atomic.Int32
+	server *grpc.Server
+	// Pause before each streamed message
+	streamDelay time.Duration
+	// If set, streams end after this many messages whatever the requested count
+	streamLimit int32
+	// Time left until the deadline of the last stream when it started, in nanoseconds
+	streamBudget atomic.Int64
+	// Time left until the deadline of the last SayHello, in nanoseconds
+	helloBudget atomic.Int64
+	// Pause before each SayHello reply
+	helloDelay time.Duration
+}
+
+func (g *testGreeter) SayHello(ctx context.Context, req *pb.HelloRequest) (*pb.HelloReply, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		g.helloBudget.Store(int64(time.Until(deadline)))
+	}
+	if g.helloDelay > 0 {
+		select {
+		case <-time.After(g.helloDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	count := g.count.Add(1)
+	return &pb.HelloReply{Message: fmt.Sprintf("Hello %s from %s", req.Name, g.name), Count: count, InstanceId: g.name}, nil
+}
+
+func (g *testGreeter) StreamMessages(req *pb.StreamRequest, stream pb.Greeter_StreamMessagesServer) error {
+	if deadline, ok := stream.Context().Deadline(); ok {
+		g.streamBudget.Store(int64(time.Until(deadline)))
+	}
+	for i := int32(0); i < req.Count; i++ {
+		if g.streamLimit > 0 && i == g.streamLimit {
+			return nil
+		}
+		select {
+		case <-time.After(g.streamDelay):
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+		if err := stream.Send(&pb.MessageResponse{Message: g.name, Index: i}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *testGreeter) GetStats(ctx context.Context, _ *emptypb.Empty) (*pb.StatsReply, error) {
+	return &pb.StatsReply{RequestCount: g.count.Load(), Uptime: durationpb.New(time.Minute)}, nil
+}
+
+// testNetwork holds in-memory listeners by name, reachable as "passthrough:///<name>"
+type testNetwork struct {
+	mu        sync.Mutex
+	listeners map[string]*bufconn.Listener
+}
+
+func newTestNetwork() *testNetwork {
+	return &testNetwork{listeners: make(map[string]*bufconn.Listener)}
+}
+
+// serve starts a Greeter server of both API versions named name on a new
+// in-memory listener, replacing any earlier listener of that name
+func (n *testNetwork) serve(t *testing.T, name string, opts ...grpc.ServerOption) *testGreeter {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	n.mu.Lock()
+	n.listeners[name] = lis
+	n.mu.Unlock()
+
+	greeter := &testGreeter{name: name, server: grpc.NewServer(opts...)}
+	pb.RegisterGreeterServer(greeter.server, greeter)
+	pbv2.RegisterGreeterServer(greeter.server, testGreeterV2{v1: greeter})
+	go greeter.server.Serve(lis)
+	t.Cleanup(greeter.server.Stop)
+	return greeter
+}
+
+// down registers name as an endpoint that refuses every connection
+func (n *testNetwork) down(name string) {
+	lis := bufconn.Listen(1 << 20)
+	lis.Close()
+	n.mu.Lock()
+	n.listeners[name] = lis
+	n.mu.Unlock()
+}
+
+// dialer returns a dial option connecting to the network's listeners
+func (n *testNetwork) dialer() grpc.DialOption {
+	return grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		n.mu.Lock()
+		lis, ok := n.listeners[addr]
+		n.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("no listener %q", addr)
+		}
+		return lis.DialContext(ctx)
+	})
+}
+
+func TestConnectFailsOverToSecondEndpoint(t *testing.T) {
+	network := newTestNetwork()
+	network.down("first")
+	second := network.serve(t, "second")
+
+	endpoints := newEndpointPool("passthrough:///first,passthrough:///second")
+	conn, err := connect(context.Background(), endpoints, network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	if got := endpoints.Current(); got != "passthrough:///second" {
+		t.Errorf("active endpoint = %q, want the second one", got)
+	}
+	var requestNum int
+	if err := makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, endpoints.Current(), defaultRPCMethods, defaultRPCTimeouts, nil); err != nil {
+		t.Fatalf("makeRequests: %v", err)
+	}
+	if second.count.Load() != 1 {
+		t.Errorf("second server handled %d requests, want 1", second.count.Load())
+	}
+}
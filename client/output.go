@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+)
+
+// Supported -output formats
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// responseLine is one response printed in JSON output mode
+type responseLine struct {
+	Request int    `json:"request"`
+	Method  string `json:"method"`
+	Message string `json:"message"`
+	// Server request count of a SayHello or GetStats reply
+	Count int32 `json:"count,omitempty"`
+	// Server instance that sent a SayHello reply
+	Instance string `json:"instance,omitempty"`
+	// When the server sent a SayHello reply, with -api-version 2 only
+	ServedAt *time.Time `json:"served_at,omitempty"`
+	// Position of a streamed message, set for StreamMessages only
+	Index *int32 `json:"index,omitempty"`
+	// Server uptime of a GetStats reply, in seconds
+	Uptime float64 `json:"uptime,omitempty"`
+}
+
+// responseOutput reports the responses the client receives. In JSON mode each
+// response is a JSON object on its own line of w, keeping them apart from the
+// operational logs on stderr; otherwise, and when nil, responses are logged.
+type responseOutput struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// newResponseOutput returns the output for format, which is outputText or outputJSON
+func newResponseOutput(format string, w io.Writer) (*responseOutput, error) {
+	switch format {
+	case outputText:
+		return nil, nil
+	case outputJSON:
+		return &responseOutput{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (expected %s or %s)", format, outputText, outputJSON)
+	}
+}
+
+// hello reports a SayHello reply, sent at servedAt if the server said when
+func (o *responseOutput) hello(requestNum int, resp *pb.HelloReply, servedAt time.Time) {
+	if o == nil {
+		if servedAt.IsZero() {
+			log.Printf("Response: %s (Server request count: %d, instance: %s)", resp.Message, resp.Count, resp.InstanceId)
+		} else {
+			log.Printf("Response: %s (Server request count: %d, instance: %s, served at %s)", resp.Message, resp.Count, resp.InstanceId, servedAt.Format(time.RFC3339Nano))
+		}
+		return
+	}
+	line := responseLine{Request: requestNum, Method: "SayHello", Message: resp.Message, Count: resp.Count, Instance: resp.InstanceId}
+	if !servedAt.IsZero() {
+		line.ServedAt = &servedAt
+	}
+	o.print(line)
+}
+
+func (o *responseOutput) streamMessage(requestNum int, msg *pb.MessageResponse) {
+	if o == nil {
+		log.Printf("  Received: %s (index: %d)", msg.Message, msg.Index)
+		return
+	}
+	o.print(responseLine{Request: requestNum, Method: "StreamMessages", Message: msg.Message, Index: &msg.Index})
+}
+
+func (o *responseOutput) stats(requestNum int, stats *pb.StatsReply) {
+	uptime := stats.Uptime.AsDuration()
+	if o == nil {
+		log.Printf("Stats: %d requests served, up %v", stats.RequestCount, uptime.Round(time.Second))
+		return
+	}
+	o.print(responseLine{Request: requestNum, Method: "GetStats", Count: stats.RequestCount, Uptime: uptime.Seconds()})
+}
+
+func (o *responseOutput) print(line responseLine) {
+	data, err := json.Marshal(line)
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.w.Write(append(data, '\n'))
+}
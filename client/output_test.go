@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	pb "multi-process-docker/proto/v1"
+)
+
+func TestJSONOutput(t *testing.T) {
+	network := newTestNetwork()
+	network.serve(t, "server")
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	var stdout bytes.Buffer
+	out, err := newResponseOutput(outputJSON, &stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The third request also streams
+	requestNum := 0
+	for i := 0; i < 3; i++ {
+		if err := makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, "server", defaultRPCMethods, defaultRPCTimeouts, out); err != nil {
+			t.Fatalf("makeRequests: %v", err)
+		}
+	}
+
+	var got []responseLine
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		var line responseLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("stdout line %q is not JSON: %v", scanner.Text(), err)
+		}
+		got = append(got, line)
+	}
+
+	want := []responseLine{
+		{Request: 1, Method: "SayHello", Message: "Hello Docker Client from server", Count: 1, Instance: "server"},
+		{Request: 2, Method: "SayHello", Message: "Hello Docker Client from server", Count: 2, Instance: "server"},
+		{Request: 3, Method: "SayHello", Message: "Hello Docker Client from server", Count: 3, Instance: "server"},
+	}
+	for i := int32(0); i < streamCount; i++ {
+		want = append(want, responseLine{Request: 3, Method: "StreamMessages", Message: "server", Index: &i})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stdout lines = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewResponseOutputUnknownFormat(t *testing.T) {
+	if _, err := newResponseOutput("xml", &bytes.Buffer{}); err == nil {
+		t.Error("newResponseOutput accepted an unknown format")
+	}
+}
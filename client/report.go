@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// loadReport accumulates the latency and outcome of every RPC made through
+// its interceptors, for a summary when the client is used as a load tool
+type loadReport struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	// Failed calls by status code
+	failures map[codes.Code]int
+}
+
+func newLoadReport() *loadReport {
+	return &loadReport{failures: make(map[codes.Code]int)}
+}
+
+// loadSummary is a snapshot of a loadReport
+type loadSummary struct {
+	Total     int
+	Succeeded int
+	Failures  map[codes.Code]int
+	// Latency percentiles and maximum, zero without any calls
+	P50, P90, P99, Max time.Duration
+}
+
+// dialOptions returns the interceptors that record calls in the report
+func (r *loadReport) dialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(r.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(r.streamInterceptor),
+	}
+}
+
+func (r *loadReport) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	// Waiting for the server to serve is not part of the load
+	if method == healthpb.Health_Check_FullMethodName {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	r.record(time.Since(start), err)
+	return err
+}
+
+func (r *loadReport) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		r.record(time.Since(start), err)
+		return nil, err
+	}
+	return &reportedStream{ClientStream: stream, report: r, start: start}, nil
+}
+
+// reportedStream records a stream in its report once it ends, so the latency
+// covers the whole stream
+type reportedStream struct {
+	grpc.ClientStream
+	report *loadReport
+	start  time.Time
+	once   sync.Once
+}
+
+func (s *reportedStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() {
+			if err == io.EOF {
+				s.report.record(time.Since(s.start), nil)
+			} else {
+				s.report.record(time.Since(s.start), err)
+			}
+		})
+	}
+	return err
+}
+
+func (r *loadReport) record(latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latencies = append(r.latencies, latency)
+	if err != nil {
+		r.failures[status.Code(err)]++
+	}
+}
+
+// summary returns the counts and latency percentiles of the calls so far
+func (r *loadReport) summary() loadSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := loadSummary{Total: len(r.latencies), Failures: make(map[codes.Code]int, len(r.failures))}
+	s.Succeeded = s.Total
+	for code, n := range r.failures {
+		s.Failures[code] = n
+		s.Succeeded -= n
+	}
+	if s.Total == 0 {
+		return s
+	}
+	sorted := slices.Sorted(slices.Values(r.latencies))
+	s.P50 = percentile(sorted, 50)
+	s.P90 = percentile(sorted, 90)
+	s.P99 = percentile(sorted, 99)
+	s.Max = sorted[len(sorted)-1]
+	return s
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted, which must not be empty
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+func (s loadSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d requests, %d succeeded, %d failed", s.Total, s.Succeeded, s.Total-s.Succeeded)
+	if len(s.Failures) > 0 {
+		var failures []string
+		for _, code := range slices.Sorted(maps.Keys(s.Failures)) {
+			failures = append(failures, fmt.Sprintf("%s=%d", code, s.Failures[code]))
+		}
+		fmt.Fprintf(&b, " (%s)", strings.Join(failures, ", "))
+	}
+	if s.Total > 0 {
+		fmt.Fprintf(&b, "; latency p50 %v, p90 %v, p99 %v, max %v", s.P50.Round(time.Microsecond), s.P90.Round(time.Microsecond), s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond))
+	}
+	return b.String()
+}
+
+// logEvery logs the report every interval until ctx is done
+func (r *loadReport) logEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			log.Printf("Load report: %v", r.summary())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
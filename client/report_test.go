@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoadReportCountsCalls(t *testing.T) {
+	network := newTestNetwork()
+	network.serve(t, "server")
+	network.serve(t, "limited", grpc.UnaryInterceptor(func(context.Context, any, *grpc.UnaryServerInfo, grpc.UnaryHandler) (any, error) {
+		return nil, status.Error(codes.ResourceExhausted, "rate limited")
+	}))
+
+	report := newLoadReport()
+	opts := append([]grpc.DialOption{network.dialer()}, report.dialOptions()...)
+	for _, endpoint := range []string{"server", "limited"} {
+		conn, err := connect(context.Background(), newEndpointPool("passthrough:///"+endpoint), opts...)
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		defer conn.Close()
+		client := pb.NewGreeterClient(conn)
+
+		// The third request cycle also streams
+		var requestNum int
+		for range 3 {
+			makeRequests(context.Background(), client, &requestNum, endpoint, defaultRPCMethods, defaultRPCTimeouts, nil)
+		}
+	}
+
+	// Three SayHello calls and a stream succeed, while the limited server
+	// fails every SayHello, so no request cycle there gets as far as streaming
+	got := report.summary()
+	if got.Total != 7 || got.Succeeded != 4 {
+		t.Errorf("summary = %d total, %d succeeded, want 7 total, 4 succeeded", got.Total, got.Succeeded)
+	}
+	if want := map[codes.Code]int{codes.ResourceExhausted: 3}; !reflect.DeepEqual(got.Failures, want) {
+		t.Errorf("failures = %v, want %v", got.Failures, want)
+	}
+	if got.P50 <= 0 || got.P50 > got.P90 || got.P90 > got.P99 || got.P99 > got.Max {
+		t.Errorf("latencies p50 %v, p90 %v, p99 %v, max %v, want positive and increasing", got.P50, got.P90, got.P99, got.Max)
+	}
+	if want := "7 requests, 4 succeeded, 3 failed (ResourceExhausted=3); latency p50"; !strings.HasPrefix(got.String(), want) {
+		t.Errorf("report = %q, want it to start with %q", got.String(), want)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 10; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	tests := []struct {
+		p    int
+		want time.Duration
+	}{
+		{50, 5 * time.Millisecond},
+		{90, 9 * time.Millisecond},
+		{99, 10 * time.Millisecond},
+		{0, 1 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(1..10ms, %d) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
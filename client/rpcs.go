@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// rpcMethod is an RPC the client can exercise in its request cycles
+type rpcMethod struct {
+	// Name used in -rpcs
+	name string
+	// The RPC runs in request cycles whose number is a multiple of every
+	every int
+	run   func(ctx context.Context, client pb.GreeterClient, requestNum int, endpoint string, timeouts rpcTimeouts, out *responseOutput) error
+}
+
+// rpcMethods are the RPCs a request cycle can run, in the order they run
+var rpcMethods = []rpcMethod{
+	{name: "sayhello", every: 1, run: runSayHello},
+	// Every 3rd request, also test streaming
+	{name: "stream", every: 3, run: runStream},
+	{name: "stats", every: 1, run: runStats},
+}
+
+// defaultRPCs is the -rpcs default: SayHello in every cycle, and a stream in every 3rd
+const defaultRPCs = "sayhello,stream"
+
+// defaultRPCMethods are the RPCs run without -rpcs
+var defaultRPCMethods = mustParseRPCs(defaultRPCs)
+
+// parseRPCs parses a comma-separated list of RPC names, returning the methods
+// in the order they run whatever the order given
+func parseRPCs(value string) ([]rpcMethod, error) {
+	selected := make(map[string]bool)
+	for name := range strings.SplitSeq(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !knownRPC(name) {
+			return nil, fmt.Errorf("unknown RPC %q (expected %s)", name, rpcNames())
+		}
+		selected[name] = true
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no RPCs selected")
+	}
+
+	var methods []rpcMethod
+	for _, method := range rpcMethods {
+		if selected[method.name] {
+			methods = append(methods, method)
+		}
+	}
+	return methods, nil
+}
+
+func knownRPC(name string) bool {
+	for _, method := range rpcMethods {
+		if method.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rpcNames lists the names accepted by -rpcs
+func rpcNames() string {
+	names := make([]string, len(rpcMethods))
+	for i, method := range rpcMethods {
+		names[i] = method.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// mustParseRPCs is parseRPCs for values known to be valid
+func mustParseRPCs(value string) []rpcMethod {
+	methods, err := parseRPCs(value)
+	if err != nil {
+		panic(err)
+	}
+	return methods
+}
+
+func runSayHello(ctx context.Context, client pb.GreeterClient, requestNum int, endpoint string, timeouts rpcTimeouts, out *responseOutput) error {
+	log.Printf("\n--- Request #%d: SayHello (%s) ---", requestNum, endpoint)
+	reqCtx, cancel := context.WithTimeout(ctx, timeouts.unary)
+	defer cancel()
+
+	resp, servedAt, err := sayHello(reqCtx, client, &pb.HelloRequest{
+		Name: "Docker Client",
+	})
+	if err != nil {
+		log.Printf("Error calling SayHello: %v", err)
+		return err
+	}
+
+	out.hello(requestNum, resp, servedAt)
+	return nil
+}
+
+func runStream(ctx context.Context, client pb.GreeterClient, requestNum int, endpoint string, timeouts rpcTimeouts, out *responseOutput) error {
+	log.Printf("\n--- Request #%d: StreamMessages (%s) ---", requestNum, endpoint)
+	if err := doStream(ctx, client, streamCount, timeouts.stream, requestNum, out); err != nil {
+		if status.Code(err) == codes.DeadlineExceeded {
+			log.Printf("Warning: stream of %d messages exceeded its %v deadline: %v", streamCount, timeouts.stream, err)
+		} else if errors.Is(err, errIncompleteStream) {
+			log.Printf("Warning: %v", err)
+		} else {
+			log.Printf("Error calling StreamMessages: %v", err)
+		}
+		return err
+	}
+	log.Println("Stream completed")
+	return nil
+}
+
+func runStats(ctx context.Context, client pb.GreeterClient, requestNum int, endpoint string, timeouts rpcTimeouts, out *responseOutput) error {
+	log.Printf("\n--- Request #%d: GetStats (%s) ---", requestNum, endpoint)
+	reqCtx, cancel := context.WithTimeout(ctx, timeouts.unary)
+	defer cancel()
+
+	stats, err := client.GetStats(reqCtx, &emptypb.Empty{})
+	if err != nil {
+		log.Printf("Error calling GetStats: %v", err)
+		return err
+	}
+
+	out.stats(requestNum, stats)
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+)
+
+func TestParseRPCs(t *testing.T) {
+	methods, err := parseRPCs(" Stats, sayhello ,,")
+	if err != nil {
+		t.Fatalf("parseRPCs: %v", err)
+	}
+	var names []string
+	for _, method := range methods {
+		names = append(names, method.name)
+	}
+	// In the order they run, not the order given
+	if want := []string{"sayhello", "stats"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("parseRPCs names = %v, want %v", names, want)
+	}
+
+	for _, value := range []string{"sayhello,goodbye", "", " , "} {
+		if _, err := parseRPCs(value); err == nil {
+			t.Errorf("parseRPCs(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestRequestCycleRunsSelectedRPCs(t *testing.T) {
+	tests := []struct {
+		rpcs string
+		// Calls the server receives over three request cycles, by method
+		want map[string]int
+	}{
+		{"sayhello,stream", map[string]int{"/hello.Greeter/SayHello": 3, "/hello.Greeter/StreamMessages": 1}},
+		{"stats", map[string]int{"/hello.Greeter/GetStats": 3}},
+		{"stream", map[string]int{"/hello.Greeter/StreamMessages": 1}},
+		{"sayhello,stats", map[string]int{"/hello.Greeter/SayHello": 3, "/hello.Greeter/GetStats": 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rpcs, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := make(map[string]int)
+			network := newTestNetwork()
+			network.serve(t, "server",
+				grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+					mu.Lock()
+					calls[info.FullMethod]++
+					mu.Unlock()
+					return handler(ctx, req)
+				}),
+				grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+					mu.Lock()
+					calls[info.FullMethod]++
+					mu.Unlock()
+					return handler(srv, ss)
+				}),
+			)
+			conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+			if err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			defer conn.Close()
+
+			rpcs, err := parseRPCs(tt.rpcs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var requestNum int
+			for range 3 {
+				if err := makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, "server", rpcs, defaultRPCTimeouts, nil); err != nil {
+					t.Fatalf("makeRequests: %v", err)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("calls = %v, want %v", calls, tt.want)
+			}
+		})
+	}
+}
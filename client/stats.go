@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// printStats connects to the server and writes its request count and uptime to w,
+// calling GetStats of apiVersion and failing if it takes longer than timeout.
+// opts are added to the default dial options.
+func printStats(ctx context.Context, endpoints *endpointPool, apiVersion int, timeout time.Duration, w io.Writer, opts ...grpc.DialOption) error {
+	conn, err := connect(ctx, endpoints, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	stats, err := newGreeterClient(conn, apiVersion).GetStats(reqCtx, &emptypb.Empty{})
+	if err != nil {
+		return fmt.Errorf("GetStats failed: %w", err)
+	}
+
+	fmt.Fprintf(w, "Server %s: %d requests served, up %v\n", endpoints.Current(), stats.RequestCount, stats.Uptime.AsDuration().Round(time.Second))
+	return nil
+}
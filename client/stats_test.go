@@ -0,0 +1,22 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPrintStats(t *testing.T) {
+	network := newTestNetwork()
+	server := network.serve(t, "server")
+	server.count.Store(7)
+
+	var out bytes.Buffer
+	if err := printStats(context.Background(), newEndpointPool("passthrough:///server"), apiV1, requestTimeout, &out, network.dialer()); err != nil {
+		t.Fatalf("printStats: %v", err)
+	}
+	if want := "passthrough:///server: 7 requests served"; !strings.Contains(out.String(), want) {
+		t.Errorf("output = %q, want it to contain %q", out.String(), want)
+	}
+}
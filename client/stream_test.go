@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+)
+
+func TestMakeRequestsWarnsOnIncompleteStream(t *testing.T) {
+	network := newTestNetwork()
+	server := network.serve(t, "server")
+	server.streamLimit = 2
+
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	// The third request also streams
+	requestNum := 2
+	err = makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, "server", defaultRPCMethods, defaultRPCTimeouts, nil)
+	if !errors.Is(err, errIncompleteStream) {
+		t.Fatalf("makeRequests() = %v, want errIncompleteStream", err)
+	}
+	if want := "Warning: incomplete stream: server closed the stream after 2 of 5 messages"; !strings.Contains(logs.String(), want) {
+		t.Errorf("logs = %q, want them to contain %q", logs.String(), want)
+	}
+	if strings.Contains(logs.String(), "Stream completed") {
+		t.Errorf("logs = %q, want no completion message", logs.String())
+	}
+}
+
+func TestDoStreamComplete(t *testing.T) {
+	network := newTestNetwork()
+	network.serve(t, "server")
+
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	if err := doStream(context.Background(), pb.NewGreeterClient(conn), 3, streamTimeout(3), 1, nil); err != nil {
+		t.Errorf("doStream() = %v, want nil", err)
+	}
+}
+
+func TestDoStreamDeadlineCoversLongStreams(t *testing.T) {
+	network := newTestNetwork()
+	server := network.serve(t, "server")
+
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	// At the server's cadence this stream takes longer than requestTimeout
+	const count = 30
+	needed := count * streamInterval
+	if needed <= requestTimeout {
+		t.Fatalf("stream of %d messages takes %v, want a count exceeding requestTimeout %v", count, needed, requestTimeout)
+	}
+
+	if err := doStream(context.Background(), pb.NewGreeterClient(conn), count, streamTimeout(count), 1, nil); err != nil {
+		t.Fatalf("doStream: %v", err)
+	}
+	if budget := time.Duration(server.streamBudget.Load()); budget < needed {
+		t.Errorf("stream deadline %v away, want at least %v", budget, needed)
+	}
+}
+
+func TestMakeRequestsPerMethodTimeouts(t *testing.T) {
+	network := newTestNetwork()
+	server := network.serve(t, "server")
+
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	timeouts := rpcTimeouts{unary: 3 * time.Second, stream: 40 * time.Second}
+	// The third request of a cycle also streams
+	requestNum := 2
+	if err := makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, "server", defaultRPCMethods, timeouts, nil); err != nil {
+		t.Fatalf("makeRequests: %v", err)
+	}
+
+	for _, tt := range []struct {
+		method string
+		budget time.Duration
+		want   time.Duration
+	}{
+		{"SayHello", time.Duration(server.helloBudget.Load()), timeouts.unary},
+		{"StreamMessages", time.Duration(server.streamBudget.Load()), timeouts.stream},
+	} {
+		if tt.budget > tt.want || tt.budget < tt.want-time.Second {
+			t.Errorf("%s deadline %v away, want just under %v", tt.method, tt.budget, tt.want)
+		}
+	}
+}
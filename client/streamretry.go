@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streamRetryWindow bounds how long a stream broken by the server going away
+// waits for the server to come back before it is left to the next request
+const streamRetryWindow = 10 * time.Second
+
+// retryBrokenStream retries the stream of request requestNum straight away if
+// err shows the stream broke off with Unavailable, as it does when the server
+// restarts mid-stream, rather than leaving recovery to the next request. It
+// waits up to streamRetryWindow for the server to report SERVING again, then
+// streams once more through apiVersion of the Greeter API. It returns err
+// unchanged if the stream is not retried, or the result of the retry.
+func retryBrokenStream(ctx context.Context, conn grpc.ClientConnInterface, apiVersion int, err error, requestNum int, timeout time.Duration, out *responseOutput) error {
+	if !errors.Is(err, errIncompleteStream) || status.Code(err) != codes.Unavailable {
+		return err
+	}
+	log.Printf("Stream of request #%d broken by the server going away, retrying it now", requestNum)
+
+	waitCtx, cancel := context.WithTimeout(ctx, streamRetryWindow)
+	defer cancel()
+	if waitErr := waitForServing(waitCtx, conn, healthPollDelay, healthPollMaxDelay); waitErr != nil {
+		log.Printf("Server not back within %v, leaving the stream to the next request", streamRetryWindow)
+		return err
+	}
+
+	if err := doStream(ctx, newGreeterClient(conn, apiVersion), streamCount, timeout, requestNum, out); err != nil {
+		log.Printf("Error retrying StreamMessages: %v", err)
+		return err
+	}
+	log.Println("Stream completed after retry")
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryBrokenStreamAfterServerRestart(t *testing.T) {
+	network := newTestNetwork()
+	server := network.serve(t, "server")
+	server.streamDelay = 50 * time.Millisecond
+
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Restart the server once the stream is underway
+	restarted := make(chan *testGreeter, 1)
+	go func() {
+		for server.streamBudget.Load() == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		time.Sleep(75 * time.Millisecond)
+		server.server.Stop()
+		restarted <- network.serve(t, "server")
+	}()
+
+	// The third request also streams
+	requestNum := 2
+	err = makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, "server", defaultRPCMethods, defaultRPCTimeouts, nil)
+	if !errors.Is(err, errIncompleteStream) || status.Code(err) != codes.Unavailable {
+		t.Fatalf("makeRequests() = %v, want an incomplete stream with Unavailable", err)
+	}
+	replacement := <-restarted
+
+	start := time.Now()
+	if err := retryBrokenStream(context.Background(), conn, apiV1, err, requestNum, defaultRPCTimeouts.stream, nil); err != nil {
+		t.Fatalf("retryBrokenStream() = %v, want the retried stream to complete", err)
+	}
+	if replacement.streamBudget.Load() == 0 {
+		t.Error("restarted server received no stream")
+	}
+	// Reconnecting should be much quicker than waiting for the next request
+	if elapsed := time.Since(start); elapsed > requestDelay {
+		t.Errorf("retry took %v, want less than the %v until the next request", elapsed, requestDelay)
+	}
+}
+
+func TestRetryBrokenStreamIgnoresOtherErrors(t *testing.T) {
+	network := newTestNetwork()
+	server := network.serve(t, "server")
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	for _, err := range []error{
+		nil,
+		// Unavailable before any stream, from SayHello
+		status.Error(codes.Unavailable, "connection refused"),
+		// A stream that broke off for another reason
+		errors.Join(errIncompleteStream, status.Error(codes.DeadlineExceeded, "deadline exceeded")),
+	} {
+		if got := retryBrokenStream(context.Background(), conn, apiV1, err, 3, defaultRPCTimeouts.stream, nil); got != err {
+			t.Errorf("retryBrokenStream(%v) = %v, want it returned unchanged", err, got)
+		}
+	}
+	if server.streamBudget.Load() != 0 {
+		t.Error("server received a stream, want no retry")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"multi-process-docker/internal/tracing"
+	pb "multi-process-docker/proto/v1"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+func TestSayHelloSpans(t *testing.T) {
+	if _, err := tracing.Setup(context.Background(), "grpc-client"); err != nil {
+		t.Fatalf("tracing.Setup: %v", err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	network := newTestNetwork()
+	greeter := network.serve(t, "server", grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := pb.NewGreeterClient(conn).SayHello(context.Background(), &pb.HelloRequest{Name: "trace"}); err != nil {
+		t.Fatalf("SayHello: %v", err)
+	}
+	// The server span may end after the client has its response, so let the server finish first
+	conn.Close()
+	greeter.server.GracefulStop()
+
+	var client, server *tracetest.SpanStub
+	spans := exporter.GetSpans()
+	for i := range spans {
+		span := &spans[i]
+		switch span.SpanKind {
+		case trace.SpanKindClient:
+			client = span
+		case trace.SpanKindServer:
+			server = span
+		}
+	}
+	if client == nil || server == nil {
+		t.Fatalf("got spans %v, want one client and one server span", spans)
+	}
+
+	const method = "hello.Greeter/SayHello"
+	if client.Name != method || server.Name != method {
+		t.Errorf("span names = %q, %q; want %q", client.Name, server.Name, method)
+	}
+	if server.Parent.TraceID() != client.SpanContext.TraceID() {
+		t.Error("server span is not in the client's trace")
+	}
+	if server.Parent.SpanID() != client.SpanContext.SpanID() {
+		t.Error("server span is not a child of the client span")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// watchConnection logs connectivity transitions of conn and requests a reconnect
+// when the connection has been failing (entered TRANSIENT_FAILURE without getting
+// back to READY) for longer than threshold. It returns when ctx is done or after
+// requesting a reconnect.
+func watchConnection(ctx context.Context, conn *grpc.ClientConn, threshold time.Duration, reconnect chan<- struct{}) {
+	state := conn.GetState()
+	var failingSince time.Time
+
+	for {
+		switch state {
+		case connectivity.Ready:
+			failingSince = time.Time{}
+		case connectivity.TransientFailure:
+			if failingSince.IsZero() {
+				failingSince = time.Now()
+			}
+		}
+
+		waitCtx, cancel := ctx, context.CancelFunc(func() {})
+		if !failingSince.IsZero() {
+			waitCtx, cancel = context.WithDeadline(ctx, failingSince.Add(threshold))
+		}
+		changed := conn.WaitForStateChange(waitCtx, state)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !changed {
+			log.Printf("Connection failing for over %v (state: %v), forcing reconnect", threshold, state)
+			select {
+			case reconnect <- struct{}{}:
+			default:
+			}
+			return
+		}
+
+		newState := conn.GetState()
+		log.Printf("Connection state changed: %v -> %v", state, newState)
+		state = newState
+	}
+}
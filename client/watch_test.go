@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+)
+
+func TestWatchConnectionRecoversFromServerRestart(t *testing.T) {
+	network := newTestNetwork()
+	server := network.serve(t, "server")
+	endpoints := newEndpointPool("passthrough:///server")
+
+	conn, err := connect(context.Background(), endpoints, network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer func() { conn.Close() }()
+	var requestNum int
+	if err := makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, endpoints.Current(), defaultRPCMethods, defaultRPCTimeouts, nil); err != nil {
+		t.Fatalf("request before restart: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reconnect := make(chan struct{}, 1)
+	go watchConnection(ctx, conn, 200*time.Millisecond, reconnect)
+
+	// Take the server down. The client's next request makes the connection
+	// try to reconnect, which leaves it in TRANSIENT_FAILURE.
+	network.down("server")
+	server.server.Stop()
+	if err := makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, endpoints.Current(), defaultRPCMethods, defaultRPCTimeouts, nil); err == nil {
+		t.Fatal("request succeeded while the server was down")
+	}
+
+	select {
+	case <-reconnect:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no reconnect requested while the server was down")
+	}
+
+	// The server comes back; redialing recovers without restarting the client
+	restarted := network.serve(t, "server")
+	conn.Close()
+	conn, err = connect(context.Background(), endpoints, network.dialer())
+	if err != nil {
+		t.Fatalf("reconnect: %v", err)
+	}
+	if err := makeRequests(context.Background(), pb.NewGreeterClient(conn), &requestNum, endpoints.Current(), defaultRPCMethods, defaultRPCTimeouts, nil); err != nil {
+		t.Fatalf("request after restart: %v", err)
+	}
+	if restarted.count.Load() != 1 {
+		t.Errorf("restarted server handled %d requests, want 1", restarted.count.Load())
+	}
+}
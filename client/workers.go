@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+)
+
+// workerStats totals the request cycles run by runWorkers
+type workerStats struct {
+	Completed int64
+	Failed    int64
+	// Cycles not run because the circuit breaker was open
+	Skipped int64
+}
+
+// runWorkers runs request cycles back to back from concurrency workers that
+// share client and breaker, until ctx is done, and returns how many completed,
+// failed and were skipped by the breaker. A worker whose cycle failed or was
+// skipped waits backoff before its next one, so a server that is down is not
+// hammered. Cycles are numbered from one shared counter, so each number is used
+// once whichever worker runs it. RPCs run on rpcCtx, so those in flight when
+// ctx is done can still finish.
+func runWorkers(ctx, rpcCtx context.Context, client pb.GreeterClient, concurrency int, breaker *circuitBreaker, backoff time.Duration, endpoint string, rpcs []rpcMethod, timeouts rpcTimeouts, out *responseOutput) workerStats {
+	var requestNum, completed, failed, skipped atomic.Int64
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				err := breaker.do(func() error {
+					return requestCycle(rpcCtx, client, int(requestNum.Add(1)), endpoint, rpcs, timeouts, out)
+				})
+				switch {
+				case errors.Is(err, errCircuitOpen):
+					skipped.Add(1)
+				case err != nil:
+					completed.Add(1)
+					failed.Add(1)
+				default:
+					completed.Add(1)
+					continue
+				}
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return workerStats{Completed: completed.Load(), Failed: failed.Load(), Skipped: skipped.Load()}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRunWorkers(t *testing.T) {
+	network := newTestNetwork()
+	server := network.serve(t, "server")
+	server.helloDelay = 20 * time.Millisecond
+
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	// responseOutput serializes writes to buf
+	var buf bytes.Buffer
+	out := &responseOutput{w: &buf}
+	const (
+		concurrency = 4
+		interval    = 500 * time.Millisecond
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), interval)
+	defer cancel()
+	stats := runWorkers(ctx, context.Background(), pb.NewGreeterClient(conn), concurrency, newCircuitBreaker(5, time.Minute), time.Second, "server", defaultRPCMethods, defaultRPCTimeouts, out)
+
+	// Each worker completes a cycle about every helloDelay, and may have
+	// one in flight when the interval ends
+	most := int64(concurrency * (interval / server.helloDelay))
+	if stats.Completed < most/2 || stats.Completed > most+concurrency {
+		t.Errorf("%d cycles completed at concurrency %d in %v, want about %d", stats.Completed, concurrency, interval, most)
+	}
+	if stats.Failed != 0 {
+		t.Errorf("%d cycles failed, want none", stats.Failed)
+	}
+	if got := int64(server.count.Load()); got != stats.Completed {
+		t.Errorf("server handled %d SayHello calls, want one per cycle, %d", got, stats.Completed)
+	}
+
+	// Every cycle number was used exactly once
+	seen := make(map[int]bool)
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for dec.More() {
+		var line responseLine
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		if line.Method != "SayHello" {
+			continue
+		}
+		if seen[line.Request] {
+			t.Errorf("request number %d used twice", line.Request)
+		}
+		seen[line.Request] = true
+	}
+	for n := 1; n <= int(stats.Completed); n++ {
+		if !seen[n] {
+			t.Errorf("request number %d missing from 1..%d", n, stats.Completed)
+		}
+	}
+}
+
+func TestRunWorkersBackOffFromFailingServer(t *testing.T) {
+	network := newTestNetwork()
+	var calls atomic.Int32
+	network.serve(t, "server", grpc.UnaryInterceptor(func(context.Context, any, *grpc.UnaryServerInfo, grpc.UnaryHandler) (any, error) {
+		calls.Add(1)
+		return nil, status.Error(codes.Unavailable, "down")
+	}))
+
+	conn, err := connect(context.Background(), newEndpointPool("passthrough:///server"), network.dialer())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	const (
+		concurrency = 4
+		threshold   = 3
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	breaker := newCircuitBreaker(threshold, time.Minute)
+	stats := runWorkers(ctx, context.Background(), pb.NewGreeterClient(conn), concurrency, breaker, 50*time.Millisecond, "server", defaultRPCMethods, defaultRPCTimeouts, nil)
+
+	// Once the breaker opens, only the cycles already in flight reach the server
+	if got := calls.Load(); got > threshold+concurrency {
+		t.Errorf("server got %d calls, want at most %d before the breaker stopped the workers", got, threshold+concurrency)
+	}
+	if stats.Failed != int64(calls.Load()) || stats.Skipped == 0 {
+		t.Errorf("stats = %+v, want every call failed and later cycles skipped", stats)
+	}
+	// Backing off between skipped cycles keeps the workers from spinning
+	if most := int64(concurrency * 500 / 50); stats.Skipped > most {
+		t.Errorf("%d cycles skipped in 500ms, want at most %d with a 50ms back-off", stats.Skipped, most)
+	}
+}
@@ -4,6 +4,6 @@ set -e
 # Generate Go code from proto files
 protoc --go_out=. --go_opt=paths=source_relative \
     --go-grpc_out=. --go-grpc_opt=paths=source_relative \
-    proto/service.proto
+    proto/v1/service.proto proto/v2/service.proto
 
 echo "Proto files generated successfully"
@@ -0,0 +1,46 @@
+// Package buildinfo reports which build of a binary is running. The values are
+// injected at build time, e.g.
+//
+//	go build -ldflags "-X multi-process-docker/internal/buildinfo.Version=v1.2.0 -X multi-process-docker/internal/buildinfo.Commit=$(git rev-parse --short HEAD)"
+package buildinfo
+
+import "fmt"
+
+// Set with -ldflags -X; empty when the binary was built without them
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+const (
+	defaultVersion = "dev"
+	unknown        = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"date"`
+}
+
+// Get returns the build info, substituting defaults for values not injected at build time
+func Get() Info {
+	return Info{
+		Version: orDefault(Version, defaultVersion),
+		Commit:  orDefault(Commit, unknown),
+		Date:    orDefault(Date, unknown),
+	}
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s)", i.Version, i.Commit, i.Date)
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
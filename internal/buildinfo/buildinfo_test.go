@@ -0,0 +1,33 @@
+package buildinfo
+
+import "testing"
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name                  string
+		version, commit, date string
+		want                  Info
+	}{
+		{"injected", "v1.2.0", "abc1234", "2026-10-16T12:00:00Z", Info{Version: "v1.2.0", Commit: "abc1234", Date: "2026-10-16T12:00:00Z"}},
+		{"defaults", "", "", "", Info{Version: "dev", Commit: "unknown", Date: "unknown"}},
+		{"partial", "v1.2.0", "", "", Info{Version: "v1.2.0", Commit: "unknown", Date: "unknown"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := [3]string{Version, Commit, Date}
+			defer func() { Version, Commit, Date = saved[0], saved[1], saved[2] }()
+			Version, Commit, Date = tt.version, tt.commit, tt.date
+
+			if got := Get(); got != tt.want {
+				t.Errorf("Get() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfoString(t *testing.T) {
+	info := Info{Version: "v1.2.0", Commit: "abc1234", Date: "2026-10-16"}
+	if got, want := info.String(), "v1.2.0 (commit abc1234, built 2026-10-16)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
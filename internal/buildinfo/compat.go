@@ -0,0 +1,112 @@
+package buildinfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VersionRange is a range of semantic versions, from Min inclusive up to Max
+// exclusive. An empty bound leaves that side open.
+type VersionRange struct {
+	Min, Max string
+}
+
+// ParseVersionRange parses "min..max", e.g. "v1.2.0..v2.0.0", where either
+// bound may be left out
+func ParseVersionRange(value string) (VersionRange, error) {
+	lower, upper, ok := strings.Cut(value, "..")
+	if !ok {
+		return VersionRange{}, fmt.Errorf("version range %q is not of the form min..max", value)
+	}
+	r := VersionRange{Min: strings.TrimSpace(lower), Max: strings.TrimSpace(upper)}
+	if r.Min == "" && r.Max == "" {
+		return VersionRange{}, fmt.Errorf("version range %q has no bounds", value)
+	}
+	for _, bound := range []string{r.Min, r.Max} {
+		if _, ok := parseVersion(bound); bound != "" && !ok {
+			return VersionRange{}, fmt.Errorf("%q in version range %q is not a version such as v1.2.0", bound, value)
+		}
+	}
+	if r.Min != "" && r.Max != "" && CompareVersions(r.Min, r.Max) >= 0 {
+		return VersionRange{}, fmt.Errorf("version range %q is empty", value)
+	}
+	return r, nil
+}
+
+// Contains reports whether version lies in the range. A version that is not
+// semantic, such as the "dev" of a build without injected values, cannot be
+// placed and is taken to be in it.
+func (r VersionRange) Contains(version string) bool {
+	if _, ok := parseVersion(version); !ok {
+		return true
+	}
+	return (r.Min == "" || CompareVersions(version, r.Min) >= 0) &&
+		(r.Max == "" || CompareVersions(version, r.Max) < 0)
+}
+
+func (r VersionRange) String() string {
+	switch {
+	case r.Min == "":
+		return "below " + r.Max
+	case r.Max == "":
+		return r.Min + " or later"
+	default:
+		return fmt.Sprintf("%s up to but not including %s", r.Min, r.Max)
+	}
+}
+
+// version is a parsed semantic version
+type version struct {
+	numbers    [3]int
+	prerelease string
+}
+
+// parseVersion parses a version such as v1.2.0, 1.2 or v2.0.0-rc.1, ignoring
+// any +build suffix
+func parseVersion(value string) (version, bool) {
+	var v version
+	value, _, _ = strings.Cut(strings.TrimPrefix(value, "v"), "+")
+	value, v.prerelease, _ = strings.Cut(value, "-")
+	parts := strings.Split(value, ".")
+	if len(parts) > len(v.numbers) {
+		return version{}, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.numbers[i] = n
+	}
+	return v, true
+}
+
+// CompareVersions returns -1, 0 or 1 as version a is older than, the same as
+// or newer than b. A pre-release is older than its release. Versions that do
+// not parse compare as equal.
+func CompareVersions(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA || !okB {
+		return 0
+	}
+	for i := range va.numbers {
+		if va.numbers[i] != vb.numbers[i] {
+			if va.numbers[i] < vb.numbers[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case va.prerelease == vb.prerelease:
+		return 0
+	case va.prerelease == "":
+		return 1
+	case vb.prerelease == "":
+		return -1
+	default:
+		return strings.Compare(va.prerelease, vb.prerelease)
+	}
+}
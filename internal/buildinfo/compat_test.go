@@ -0,0 +1,68 @@
+package buildinfo
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.0", "v1.2.0", 0},
+		{"v1.2", "1.2.0", 0},
+		{"v1.2.0", "v1.10.0", -1},
+		{"v2.0.0", "v1.99.99", 1},
+		{"v2.0.0-rc.1", "v2.0.0", -1},
+		{"v2.0.0-rc.2", "v2.0.0-rc.1", 1},
+		{"v1.2.0+abc1234", "v1.2.0", 0},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestVersionRange(t *testing.T) {
+	r, err := ParseVersionRange("v1.2.0..v2.0.0")
+	if err != nil {
+		t.Fatalf("ParseVersionRange: %v", err)
+	}
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"v1.2.0", true},
+		{"v1.9.3", true},
+		{"v1.1.9", false},
+		{"v2.0.0", false},
+		{"v2.0.0-rc.1", true},
+		// Builds without an injected version cannot be placed
+		{"dev", true},
+	}
+	for _, tt := range tests {
+		if got := r.Contains(tt.version); got != tt.want {
+			t.Errorf("%v contains %q = %v, want %v", r, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersionRangeOpenBounds(t *testing.T) {
+	r, err := ParseVersionRange("v1.2.0..")
+	if err != nil {
+		t.Fatalf("ParseVersionRange: %v", err)
+	}
+	if !r.Contains("v9.0.0") || r.Contains("v1.0.0") {
+		t.Errorf("%v should contain v9.0.0 but not v1.0.0", r)
+	}
+	if got, want := r.String(), "v1.2.0 or later"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseVersionRangeInvalid(t *testing.T) {
+	for _, value := range []string{"v1.2.0", "v1.x..v2", "v2.0.0..v1.0.0", "v1.0.0..v1.0.0", ".."} {
+		if _, err := ParseVersionRange(value); err == nil {
+			t.Errorf("ParseVersionRange(%q) succeeded, want an error", value)
+		}
+	}
+}
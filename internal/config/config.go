@@ -0,0 +1,47 @@
+// Package config holds the settings shared by the client and server, so both
+// sides of the socket resolve them the same way.
+package config
+
+import "os"
+
+const (
+	// DefaultSocketPath is the Unix socket the server listens on and the client dials
+	DefaultSocketPath = "/tmp/grpc.sock"
+	// SocketPathEnv overrides DefaultSocketPath when no flag value is given
+	SocketPathEnv = "GRPC_SOCKET_PATH"
+	// StagingSocketEnv tells a replacement server started for a rolling restart
+	// to listen on this path until the process manager renames it into place
+	StagingSocketEnv = "GRPC_SOCKET_STAGING_PATH"
+	// AuthTokenEnv holds the shared secret RPCs are authenticated with when no flag value is given
+	AuthTokenEnv = "GRPC_AUTH_TOKEN"
+	// ClientVersionHeader is the request metadata key carrying the client's
+	// build version, which the server checks against its accepted range
+	ClientVersionHeader = "x-client-version"
+)
+
+// SocketPath resolves the socket path from, in order of precedence, the flag
+// value, the GRPC_SOCKET_PATH environment variable, and DefaultSocketPath.
+func SocketPath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv(SocketPathEnv); env != "" {
+		return env
+	}
+	return DefaultSocketPath
+}
+
+// AuthToken resolves the shared secret from the flag value, falling back to
+// the GRPC_AUTH_TOKEN environment variable. An empty token disables authentication.
+func AuthToken(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return os.Getenv(AuthTokenEnv)
+}
+
+// StagingSocketPath is where a replacement for the server listening on
+// socketPath is started during a rolling restart
+func StagingSocketPath(socketPath string) string {
+	return socketPath + ".next"
+}
@@ -0,0 +1,45 @@
+package config
+
+import "testing"
+
+func TestSocketPathPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		flag string
+		env  string
+		want string
+	}{
+		{"flag wins over env", "/run/flag.sock", "/run/env.sock", "/run/flag.sock"},
+		{"env when flag empty", "", "/run/env.sock", "/run/env.sock"},
+		{"default when both empty", "", "", DefaultSocketPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(SocketPathEnv, tt.env)
+			if got := SocketPath(tt.flag); got != tt.want {
+				t.Errorf("SocketPath(%q) = %q, want %q", tt.flag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthTokenPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		flag string
+		env  string
+		want string
+	}{
+		{"flag wins over env", "from-flag", "from-env", "from-flag"},
+		{"env when flag empty", "", "from-env", "from-env"},
+		{"disabled when both empty", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(AuthTokenEnv, tt.env)
+			if got := AuthToken(tt.flag); got != tt.want {
+				t.Errorf("AuthToken(%q) = %q, want %q", tt.flag, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		proc     Process
+		unstable int
+		want     time.Duration
+	}{
+		{"default", Process{}, 3, defaultRestartDelay},
+		{"no backoff", Process{RestartDelay: time.Second}, 3, time.Second},
+		{"stable", Process{RestartDelay: time.Second, RestartBackoffMax: time.Minute}, 0, time.Second},
+		{"first short run", Process{RestartDelay: time.Second, RestartBackoffMax: time.Minute}, 1, time.Second},
+		{"third short run", Process{RestartDelay: time.Second, RestartBackoffMax: time.Minute}, 3, 4 * time.Second},
+		{"capped", Process{RestartDelay: time.Second, RestartBackoffMax: 10 * time.Second}, 8, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.proc.restartDelay(tt.unstable); got != tt.want {
+				t.Errorf("restartDelay(%d) = %v, want %v", tt.unstable, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanExitsKeepBackingOff(t *testing.T) {
+	// Exits with code 0 well within its minimum stable run, over and over
+	proc := &Process{
+		Name:              "quick",
+		Command:           "sleep",
+		Args:              []string{"0.2"},
+		RestartDelay:      50 * time.Millisecond,
+		RestartBackoffMax: time.Minute,
+		MinStableRun:      time.Second,
+	}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+
+	// Restart delays of 50, 100, 200 and 400ms
+	want := proc.RestartDelay
+	for i := 0; i < 4; i++ {
+		exited := waitEvent(t, events, proc.Name, EventExited, 5*time.Second)
+		if exited.ExitCode != 0 {
+			t.Fatalf("exit code = %d, want a clean exit", exited.ExitCode)
+		}
+		started := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+		if gap := started.Time.Sub(exited.Time); gap < want {
+			t.Errorf("restart %d came %v after the exit, want a backed off delay of at least %v", i+1, gap, want)
+		}
+		want *= 2
+	}
+}
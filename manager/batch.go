@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// processesDone returns a channel closed once the restart loop of every
+// started process has ended, which leaves each task completed or failed and
+// each daemon given up. Loops started while it waits are waited for too.
+func (pm *ProcessManager) processesDone() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			pm.mu.Lock()
+			loops := make([]*processLoop, 0, len(pm.loops))
+			for _, loop := range pm.loops {
+				loops = append(loops, loop)
+			}
+			pm.mu.Unlock()
+
+			for _, loop := range loops {
+				<-loop.done
+			}
+			if pm.loopsDone() {
+				return
+			}
+		}
+	}()
+	return done
+}
+
+// loopsDone reports whether every restart loop has ended
+func (pm *ProcessManager) loopsDone() bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	for _, loop := range pm.loops {
+		select {
+		case <-loop.done:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// batchResult returns an error naming the started processes that did not
+// complete, once all of them have stopped for good, or nil if every one did
+func (pm *ProcessManager) batchResult() error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	var failed []string
+	started := 0
+	for _, proc := range pm.processes {
+		if _, ok := pm.loops[proc.Name]; !ok {
+			// Skipped by its enabledIf
+			continue
+		}
+		started++
+		if state := pm.states[proc.Name]; state == nil || !state.Completed {
+			failed = append(failed, proc.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d processes did not complete: %s", len(failed), started, strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// runWithin runs pm until it returns by itself, failing the test after timeout
+func runWithin(t *testing.T, pm *ProcessManager, timeout time.Duration) error {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := make(chan error, 1)
+	go func() { result <- pm.Run(ctx) }()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		cancel()
+		<-result
+		t.Fatalf("Run did not return by itself within %v", timeout)
+		return nil
+	}
+}
+
+func TestExitWhenDone(t *testing.T) {
+	extract := &Process{Name: "extract", Command: "sh", Args: []string{"-c", "sleep 0.2"}, Kind: KindTask}
+	load := &Process{Name: "load", Command: "sh", Args: []string{"-c", "sleep 0.1"}, Kind: KindTask, DependsOn: []string{"extract"}}
+	pm := NewProcessManager([]*Process{extract, load})
+	pm.StartWait = 10 * time.Millisecond
+	pm.ExitWhenDone = true
+
+	if err := runWithin(t, pm, 5*time.Second); err != nil {
+		t.Fatalf("Run = %v, want nil once both tasks completed", err)
+	}
+	for _, state := range pm.States() {
+		if !state.Completed {
+			t.Errorf("%s did not complete", state.Name)
+		}
+	}
+}
+
+func TestExitWhenDoneReportsFailure(t *testing.T) {
+	ok := &Process{Name: "ok", Command: "true", Kind: KindTask}
+	broken := &Process{Name: "broken", Command: "sh", Args: []string{"-c", "sleep 0.1; exit 3"}, Kind: KindTask}
+	pm := NewProcessManager([]*Process{ok, broken})
+	pm.StartWait = 10 * time.Millisecond
+	pm.ExitWhenDone = true
+
+	err := runWithin(t, pm, 5*time.Second)
+	if want := "1 of 2 processes did not complete: broken"; err == nil || err.Error() != want {
+		t.Errorf("Run = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// Default time the bundle has to become ready before it is reported as timed out
+const defaultBundleReadyTimeout = 2 * time.Minute
+
+// BundleReadiness reports how long the bundle took to become ready after Start
+type BundleReadiness struct {
+	// Whether every critical process has been healthy at once since Start
+	Ready bool `json:"ready"`
+	// Time from Start until the bundle was first ready
+	ReadyAfter time.Duration `json:"readyAfterNs,omitempty"`
+	// Whether the bundle was not ready within the timeout. It stays set if the
+	// bundle becomes ready later.
+	TimedOut bool `json:"timedOut"`
+	// Critical processes that were not healthy when the timeout expired
+	Pending []string `json:"pending,omitempty"`
+}
+
+// BundleReadiness returns the time-to-ready of the bundle
+func (pm *ProcessManager) BundleReadiness() BundleReadiness {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	readiness := pm.bundle
+	readiness.Pending = append([]string(nil), pm.bundle.Pending...)
+	return readiness
+}
+
+// watchBundleReady records how long after startedAt every critical process is
+// healthy, logging when that happens or when bundleReadyTimeout passes first
+func (pm *ProcessManager) watchBundleReady(startedAt time.Time) {
+	timeout := time.After(pm.bundleReadyTimeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		pending := pm.pendingCritical()
+		if len(pending) == 0 {
+			readyAfter := time.Since(startedAt)
+			pm.mu.Lock()
+			pm.bundle.Ready = true
+			pm.bundle.ReadyAfter = readyAfter
+			pm.bundle.Pending = nil
+			pm.mu.Unlock()
+			log.Printf("Bundle ready in %v", readyAfter.Round(time.Millisecond))
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timeout:
+			pm.mu.Lock()
+			pm.bundle.TimedOut = true
+			pm.bundle.Pending = pending
+			pm.mu.Unlock()
+			log.Printf("Warning: bundle not ready after %v, waiting for: %s", pm.bundleReadyTimeout, strings.Join(pending, ", "))
+		case <-pm.ctx.Done():
+			return
+		}
+	}
+}
+
+// pendingCritical returns the critical processes that are not healthy
+func (pm *ProcessManager) pendingCritical() []string {
+	detail := pm.HealthDetail()
+	var pending []string
+	for _, proc := range pm.processList() {
+		if proc.Critical && proc.enabled() && !detail[proc.Name] {
+			pending = append(pending, proc.Name)
+		}
+	}
+	return pending
+}
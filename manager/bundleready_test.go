@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitBundle polls until cond holds for the bundle readiness or timeout passes
+func waitBundle(t *testing.T, pm *ProcessManager, cond func(BundleReadiness) bool, timeout time.Duration) BundleReadiness {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		readiness := pm.BundleReadiness()
+		if cond(readiness) {
+			return readiness
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("bundle readiness still %+v after %v", readiness, timeout)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestBundleReadyDuration(t *testing.T) {
+	const readyDelay = 500 * time.Millisecond
+	var readyAt time.Time
+	proc := &Process{
+		Name:          "server",
+		Command:       "sleep",
+		Args:          []string{"30"},
+		Critical:      true,
+		ReadyInterval: 50 * time.Millisecond,
+		ReadyCheck: func(ctx context.Context) error {
+			if time.Now().Before(readyAt) {
+				return errors.New("warming up")
+			}
+			return nil
+		},
+	}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+
+	readyAt = time.Now().Add(readyDelay)
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	readiness := waitBundle(t, pm, func(r BundleReadiness) bool { return r.Ready }, 5*time.Second)
+	if readiness.ReadyAfter < readyDelay || readiness.ReadyAfter > readyDelay+500*time.Millisecond {
+		t.Errorf("ReadyAfter = %v, want about %v", readiness.ReadyAfter, readyDelay)
+	}
+	if readiness.TimedOut {
+		t.Errorf("TimedOut = true, want false")
+	}
+}
+
+func TestBundleReadyTimeout(t *testing.T) {
+	proc := &Process{
+		Name:          "server",
+		Command:       "sleep",
+		Args:          []string{"30"},
+		Critical:      true,
+		ReadyInterval: 50 * time.Millisecond,
+		ReadyCheck:    func(ctx context.Context) error { return errors.New("never ready") },
+	}
+	pm := NewProcessManager([]*Process{proc, {Name: "client", Command: "sleep", Args: []string{"30"}}})
+	pm.bundleReadyTimeout = 200 * time.Millisecond
+	// Start itself gives up waiting for the critical server well before that
+	pm.readyTimeout = 100 * time.Millisecond
+	defer pm.Shutdown()
+
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	readiness := waitBundle(t, pm, func(r BundleReadiness) bool { return r.TimedOut }, 5*time.Second)
+	if readiness.Ready {
+		t.Errorf("Ready = true, want false")
+	}
+	if want := []string{"server"}; !reflect.DeepEqual(readiness.Pending, want) {
+		t.Errorf("Pending = %q, want %q", readiness.Pending, want)
+	}
+}
@@ -0,0 +1,181 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+	// Subdirectory of the manager's cgroup that holds the per-process cgroups,
+	// kept apart from the leaf the manager may move itself into
+	cgroupProcsDir = "procs"
+	// Period used for cpu.max, in microseconds
+	cgroupCPUPeriod = 100000
+)
+
+// cgroup is a cgroup v2 subtree holding a single managed process
+type cgroup struct {
+	path string
+}
+
+// newCgroup creates a cgroup v2 subtree for proc with its CPU and memory limits applied
+func newCgroup(proc *Process) (*cgroup, error) {
+	if err := checkCgroupName(proc.Name); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 not available: %w", err)
+	}
+
+	parent, err := cgroupParent()
+	if err != nil {
+		return nil, err
+	}
+	return createCgroup(parent, proc)
+}
+
+// createCgroup creates the cgroup for proc under parent and writes its limits
+func createCgroup(parent string, proc *Process) (*cgroup, error) {
+	path := filepath.Join(parent, proc.Name)
+	if err := os.Mkdir(path, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("failed to create cgroup %s: %w", path, err)
+	}
+	cg := &cgroup{path: path}
+
+	if proc.CPUQuota > 0 {
+		quota := int(proc.CPUQuota * cgroupCPUPeriod)
+		if err := cg.write("cpu.max", fmt.Sprintf("%d %d", quota, cgroupCPUPeriod)); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+
+	if proc.MemoryLimitMB > 0 {
+		if err := cg.write("memory.max", strconv.Itoa(proc.MemoryLimitMB*1024*1024)); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+
+	return cg, nil
+}
+
+// checkCgroupName rejects process names that are not a single path element,
+// so that a process's cgroup always stays inside the parent
+func checkCgroupName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return fmt.Errorf("process name %q cannot be used as a cgroup name", name)
+	}
+	return nil
+}
+
+// addProcess moves pid into the cgroup
+func (cg *cgroup) addProcess(pid int) error {
+	return cg.write("cgroup.procs", strconv.Itoa(pid))
+}
+
+// remove deletes the cgroup. It must no longer contain any processes.
+func (cg *cgroup) remove() error {
+	if err := os.Remove(cg.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove cgroup %s: %w", cg.path, err)
+	}
+	return nil
+}
+
+func (cg *cgroup) write(file, value string) error {
+	if err := os.WriteFile(filepath.Join(cg.path, file), []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to write %s to %s: %w", value, file, err)
+	}
+	return nil
+}
+
+var (
+	parentOnce sync.Once
+	parentPath string
+	parentErr  error
+)
+
+// cgroupParent returns the cgroup under which per-process cgroups are created,
+// preparing it on first use. It is a subdirectory of the manager's own cgroup,
+// so no process name can collide with the manager's leaf. The result is cached
+// because preparing it may move the manager into a different cgroup.
+func cgroupParent() (string, error) {
+	parentOnce.Do(func() {
+		self, err := selfCgroupPath()
+		if err == nil {
+			err = enableControllers(self)
+		}
+		if err == nil {
+			parentPath, err = procsCgroup(self)
+		}
+		parentErr = err
+	})
+	return parentPath, parentErr
+}
+
+// selfCgroupPath returns the filesystem path of the manager's own cgroup
+func selfCgroupPath() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", fmt.Errorf("failed to read own cgroup: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// cgroup v2 entries have the form "0::/path"
+		if rel, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return filepath.Join(cgroupRoot, rel), nil
+		}
+	}
+	return "", errors.New("no cgroup v2 entry in /proc/self/cgroup")
+}
+
+// enableControllers enables the cpu and memory controllers for children of parent.
+// cgroup v2 forbids this while parent itself holds processes, so on EBUSY the
+// manager first moves itself into a leaf cgroup.
+func enableControllers(parent string) error {
+	control := filepath.Join(parent, "cgroup.subtree_control")
+	err := os.WriteFile(control, []byte("+cpu +memory"), 0644)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EBUSY) {
+		return fmt.Errorf("failed to enable cgroup controllers: %w", err)
+	}
+
+	leaf := filepath.Join(parent, "manager")
+	if err := os.Mkdir(leaf, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("failed to create manager cgroup: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return fmt.Errorf("failed to move manager into its own cgroup: %w", err)
+	}
+	if err := os.WriteFile(control, []byte("+cpu +memory"), 0644); err != nil {
+		return fmt.Errorf("failed to enable cgroup controllers: %w", err)
+	}
+	return nil
+}
+
+// procsCgroup creates the subdirectory of self that holds the per-process
+// cgroups and enables the cpu and memory controllers for its children
+func procsCgroup(self string) (string, error) {
+	procs := filepath.Join(self, cgroupProcsDir)
+	if err := os.Mkdir(procs, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", fmt.Errorf("failed to create cgroup %s: %w", procs, err)
+	}
+	if err := os.WriteFile(filepath.Join(procs, "cgroup.subtree_control"), []byte("+cpu +memory"), 0644); err != nil {
+		return "", fmt.Errorf("failed to enable cgroup controllers in %s: %w", procs, err)
+	}
+	return procs, nil
+}
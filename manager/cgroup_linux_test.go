@@ -0,0 +1,76 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateCgroupLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		cpuQuota   float64
+		memoryMB   int
+		wantCPU    string
+		wantMemory string
+	}{
+		{"memory only", 0, 64, "", "67108864"},
+		{"cpu only", 0.5, 0, "50000 100000", ""},
+		{"both", 2, 1, "200000 100000", "1048576"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			proc := &Process{Name: "worker", CPUQuota: tt.cpuQuota, MemoryLimitMB: tt.memoryMB}
+
+			cg, err := createCgroup(parent, proc)
+			if err != nil {
+				t.Fatalf("createCgroup: %v", err)
+			}
+			if want := filepath.Join(parent, "worker"); cg.path != want {
+				t.Errorf("cgroup path = %q, want %q", cg.path, want)
+			}
+
+			for file, want := range map[string]string{"cpu.max": tt.wantCPU, "memory.max": tt.wantMemory} {
+				data, err := os.ReadFile(filepath.Join(cg.path, file))
+				if want == "" {
+					if err == nil {
+						t.Errorf("%s written as %q, want it left alone", file, data)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("read %s: %v", file, err)
+				}
+				if got := strings.TrimSpace(string(data)); got != want {
+					t.Errorf("%s = %q, want %q", file, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckCgroupName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"grpc-server", false},
+		{"manager", false},
+		{"", true},
+		{".", true},
+		{"..", true},
+		{"../escape", true},
+		{"nested/name", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkCgroupName(tt.name); (err != nil) != tt.wantErr {
+				t.Errorf("checkCgroupName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
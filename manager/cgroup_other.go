@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// cgroup is a no-op placeholder on platforms without cgroup support
+type cgroup struct{}
+
+func newCgroup(proc *Process) (*cgroup, error) {
+	return nil, errors.New("cgroups are only supported on Linux")
+}
+
+func (cg *cgroup) addProcess(pid int) error { return nil }
+
+func (cg *cgroup) remove() error { return nil }
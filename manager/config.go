@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	sharedconfig "multi-process-docker/internal/config"
+
+	"github.com/BurntSushi/toml"
+)
+
+// config is the on-disk manager configuration
+type config struct {
+	Processes []processConfig `json:"processes"`
+}
+
+// processConfig is the on-disk representation of a Process
+type processConfig struct {
+	Name                  string            `json:"name"`
+	Command               string            `json:"command"`
+	Args                  []string          `json:"args,omitempty"`
+	PreStart              []string          `json:"preStart,omitempty"`
+	PostStop              []string          `json:"postStop,omitempty"`
+	PostStopTimeout       duration          `json:"postStopTimeout,omitempty"`
+	ExpandEnv             bool              `json:"expandEnv,omitempty"`
+	StrictEnv             bool              `json:"strictEnv,omitempty"`
+	EnvFromFile           map[string]string `json:"envFromFile,omitempty"`
+	EnabledIf             string            `json:"enabledIf,omitempty"`
+	Critical              bool              `json:"critical,omitempty"`
+	DependsOn             []string          `json:"dependsOn,omitempty"`
+	Kind                  ProcessKind       `json:"kind,omitempty"`
+	WaitForExit           bool              `json:"waitForExit,omitempty"`
+	RestartDelay          duration          `json:"restartDelay,omitempty"`
+	RestartBackoffMax     duration          `json:"restartBackoffMax,omitempty"`
+	RestartAlertThreshold int               `json:"restartAlertThreshold,omitempty"`
+	RestartAlertWindow    duration          `json:"restartAlertWindow,omitempty"`
+	StartDelay            duration          `json:"startDelay,omitempty"`
+	StartWait             duration          `json:"startWait,omitempty"`
+	CriticalWait          duration          `json:"criticalWait,omitempty"`
+	MinStableRun          duration          `json:"minStableRun,omitempty"`
+	ReadyInterval         duration          `json:"readyInterval,omitempty"`
+	MaxRuntime            duration          `json:"maxRuntime,omitempty"`
+	CPUQuota              float64           `json:"cpuQuota,omitempty"`
+	MemoryLimitMB         int               `json:"memoryLimitMB,omitempty"`
+	Stdin                 string            `json:"stdin,omitempty"`
+	StdinFrom             string            `json:"stdinFrom,omitempty"`
+	GRPCHealthSocket      string            `json:"grpcHealthSocket,omitempty"`
+	ReadyFile             string            `json:"readyFile,omitempty"`
+	Socket                string            `json:"socket,omitempty"`
+	RollingRestart        bool              `json:"rollingRestart,omitempty"`
+	ShutdownPriority      int               `json:"shutdownPriority,omitempty"`
+	User                  string            `json:"user,omitempty"`
+	Group                 string            `json:"group,omitempty"`
+	LogFilter             string            `json:"logFilter,omitempty"`
+	LogFilterKeep         bool              `json:"logFilterKeep,omitempty"`
+	LogLabels             map[string]string `json:"logLabels,omitempty"`
+	WatchPaths            []string          `json:"watchPaths,omitempty"`
+	WatchSignal           namedSignal       `json:"watchSignal,omitempty"`
+
+	StopSignals  []stopStepConfig `json:"stopSignals,omitempty"`
+	DrainTimeout duration         `json:"drainTimeout,omitempty"`
+}
+
+// stopStepConfig is the on-disk representation of a StopStep
+type stopStepConfig struct {
+	Signal namedSignal `json:"signal"`
+	Wait   duration    `json:"wait"`
+}
+
+// namedSignal is a syscall.Signal written by name, such as "SIGINT"
+type namedSignal syscall.Signal
+
+func (s *namedSignal) UnmarshalText(text []byte) error {
+	sig, err := parseSignal(string(text))
+	if err != nil {
+		return err
+	}
+	*s = namedSignal(sig)
+	return nil
+}
+
+func (s namedSignal) MarshalText() ([]byte, error) {
+	return []byte(signalName(syscall.Signal(s))), nil
+}
+
+// duration is a time.Duration written as a string such as "5s"
+type duration time.Duration
+
+func (d *duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// LoadConfig reads the process definitions from one or more config files, in
+// JSON or, for files ending in .toml, TOML.
+// Processes are merged by name: each field set in a later file replaces the
+// same field from earlier files (lists such as args are replaced, not
+// appended), and a process first named in a later file is added after the
+// earlier ones.
+func LoadConfig(paths ...string) ([]*Process, error) {
+	var (
+		merged []map[string]json.RawMessage
+		names  []string
+	)
+	byName := make(map[string]map[string]json.RawMessage)
+	for _, path := range paths {
+		cfg, raw, err := readConfig(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only processes from earlier files are merged into, so duplicates
+		// within one file are still reported by validation
+		start := len(merged)
+		for i, pc := range cfg.Processes {
+			fields, ok := byName[pc.Name]
+			if !ok {
+				merged = append(merged, raw.Processes[i])
+				names = append(names, pc.Name)
+				continue
+			}
+			for key, value := range raw.Processes[i] {
+				fields[key] = value
+			}
+		}
+
+		for i := start; i < len(merged); i++ {
+			if _, ok := byName[names[i]]; !ok && names[i] != "" {
+				byName[names[i]] = merged[i]
+			}
+		}
+	}
+
+	processes := make([]*Process, 0, len(merged))
+	for _, fields := range merged {
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge config: %w", err)
+		}
+		var pc processConfig
+		if err := json.Unmarshal(data, &pc); err != nil {
+			return nil, fmt.Errorf("failed to merge config: %w", err)
+		}
+		processes = append(processes, pc.toProcess())
+	}
+	return processes, nil
+}
+
+// readConfig parses a single config file both into a config and into the raw
+// fields of each process, which tell which fields the file actually sets
+func readConfig(path string) (config, rawConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config{}, rawConfig{}, fmt.Errorf("failed to open config: %w", err)
+	}
+	// TOML is converted to JSON, so both formats are read the same way
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		if data, err = tomlToJSON(data); err != nil {
+			return config{}, rawConfig{}, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+
+	var cfg config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return config{}, rawConfig{}, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	var raw rawConfig
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return config{}, rawConfig{}, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, raw, nil
+}
+
+// tomlToJSON converts a TOML document to the equivalent JSON
+func tomlToJSON(data []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// rawConfig is a config whose processes are kept as their individual JSON fields
+type rawConfig struct {
+	Processes []map[string]json.RawMessage `json:"processes"`
+}
+
+// configPaths collects -config values, given either repeatedly or comma-separated
+type configPaths []string
+
+func (p *configPaths) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *configPaths) Set(value string) error {
+	for _, path := range strings.Split(value, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			*p = append(*p, path)
+		}
+	}
+	return nil
+}
+
+func (pc processConfig) toProcess() *Process {
+	proc := &Process{
+		Name:                  pc.Name,
+		Command:               pc.Command,
+		Args:                  pc.Args,
+		PreStart:              pc.PreStart,
+		PostStop:              pc.PostStop,
+		PostStopTimeout:       time.Duration(pc.PostStopTimeout),
+		ExpandEnv:             pc.ExpandEnv,
+		StrictEnv:             pc.StrictEnv,
+		EnvFromFile:           pc.EnvFromFile,
+		EnabledIf:             pc.EnabledIf,
+		Critical:              pc.Critical,
+		DependsOn:             pc.DependsOn,
+		Kind:                  pc.Kind,
+		WaitForExit:           pc.WaitForExit,
+		RestartDelay:          time.Duration(pc.RestartDelay),
+		RestartBackoffMax:     time.Duration(pc.RestartBackoffMax),
+		RestartAlertThreshold: pc.RestartAlertThreshold,
+		RestartAlertWindow:    time.Duration(pc.RestartAlertWindow),
+		StartDelay:            time.Duration(pc.StartDelay),
+		StartWait:             time.Duration(pc.StartWait),
+		CriticalWait:          time.Duration(pc.CriticalWait),
+		MinStableRun:          time.Duration(pc.MinStableRun),
+		ReadyInterval:         time.Duration(pc.ReadyInterval),
+		MaxRuntime:            time.Duration(pc.MaxRuntime),
+		CPUQuota:              pc.CPUQuota,
+		MemoryLimitMB:         pc.MemoryLimitMB,
+		StdinFrom:             pc.StdinFrom,
+		Socket:                pc.Socket,
+		RollingRestart:        pc.RollingRestart,
+		ShutdownPriority:      pc.ShutdownPriority,
+		User:                  pc.User,
+		Group:                 pc.Group,
+		LogFilter:             pc.LogFilter,
+		LogFilterKeep:         pc.LogFilterKeep,
+		LogLabels:             pc.LogLabels,
+		WatchPaths:            pc.WatchPaths,
+		WatchSignal:           syscall.Signal(pc.WatchSignal),
+		DrainTimeout:          time.Duration(pc.DrainTimeout),
+	}
+	if pc.Stdin != "" {
+		proc.StdinData = []byte(pc.Stdin)
+	}
+	for _, step := range pc.StopSignals {
+		proc.StopSignals = append(proc.StopSignals, StopStep{Signal: syscall.Signal(step.Signal), Wait: time.Duration(step.Wait)})
+	}
+	if pc.GRPCHealthSocket != "" {
+		proc.ReadyCheck = GRPCHealthCheck(pc.GRPCHealthSocket)
+		proc.GRPCHealthSocket = pc.GRPCHealthSocket
+	}
+	if pc.ReadyFile != "" {
+		proc.ReadyCheck = FileReadyCheck(pc.ReadyFile)
+		proc.ReadyFile = pc.ReadyFile
+	}
+	return proc
+}
+
+// toConfig is the reverse of toProcess. A ReadyCheck other than a gRPC health
+// or ready file check cannot be written out and is left out.
+func (proc *Process) toConfig() processConfig {
+	pc := processConfig{
+		Name:                  proc.Name,
+		Command:               proc.Command,
+		Args:                  proc.Args,
+		PreStart:              proc.PreStart,
+		PostStop:              proc.PostStop,
+		PostStopTimeout:       duration(proc.PostStopTimeout),
+		ExpandEnv:             proc.ExpandEnv,
+		StrictEnv:             proc.StrictEnv,
+		EnvFromFile:           proc.EnvFromFile,
+		EnabledIf:             proc.EnabledIf,
+		Critical:              proc.Critical,
+		DependsOn:             proc.DependsOn,
+		Kind:                  proc.Kind,
+		WaitForExit:           proc.WaitForExit,
+		RestartDelay:          duration(proc.RestartDelay),
+		RestartBackoffMax:     duration(proc.RestartBackoffMax),
+		RestartAlertThreshold: proc.RestartAlertThreshold,
+		RestartAlertWindow:    duration(proc.RestartAlertWindow),
+		StartDelay:            duration(proc.StartDelay),
+		StartWait:             duration(proc.StartWait),
+		CriticalWait:          duration(proc.CriticalWait),
+		MinStableRun:          duration(proc.MinStableRun),
+		ReadyInterval:         duration(proc.ReadyInterval),
+		MaxRuntime:            duration(proc.MaxRuntime),
+		CPUQuota:              proc.CPUQuota,
+		MemoryLimitMB:         proc.MemoryLimitMB,
+		Stdin:                 string(proc.StdinData),
+		StdinFrom:             proc.StdinFrom,
+		GRPCHealthSocket:      proc.GRPCHealthSocket,
+		ReadyFile:             proc.ReadyFile,
+		Socket:                proc.Socket,
+		RollingRestart:        proc.RollingRestart,
+		ShutdownPriority:      proc.ShutdownPriority,
+		User:                  proc.User,
+		Group:                 proc.Group,
+		LogFilter:             proc.LogFilter,
+		LogFilterKeep:         proc.LogFilterKeep,
+		LogLabels:             proc.LogLabels,
+		WatchPaths:            proc.WatchPaths,
+		WatchSignal:           namedSignal(proc.WatchSignal),
+		DrainTimeout:          duration(proc.DrainTimeout),
+	}
+	for _, step := range proc.StopSignals {
+		pc.StopSignals = append(pc.StopSignals, stopStepConfig{Signal: namedSignal(step.Signal), Wait: duration(step.Wait)})
+	}
+	return pc
+}
+
+// defaultProcesses is the bundle managed when no config file is given
+func defaultProcesses() []*Process {
+	return []*Process{
+		{
+			Name:             "grpc-server",
+			Command:          "/app/server",
+			Args:             []string{},
+			Critical:         true, // Server must start first
+			RestartDelay:     5 * time.Second,
+			ReadyCheck:       GRPCHealthCheck(sharedconfig.SocketPath("")),
+			GRPCHealthSocket: sharedconfig.SocketPath(""),
+			Socket:           sharedconfig.SocketPath(""),
+			RollingRestart:   true,
+		},
+		{
+			Name:         "grpc-client",
+			Command:      "/app/client",
+			Args:         []string{},
+			Critical:     false,
+			DependsOn:    []string{"grpc-server"},
+			RestartDelay: 5 * time.Second,
+		},
+	}
+}
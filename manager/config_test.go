@@ -0,0 +1,227 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a config file named name into dir, returning its path
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []*Process
+		wantErr string
+	}{
+		{
+			name: "valid",
+			content: `{"processes": [
+				{"name": "server", "command": "/bin/server", "args": ["-socket", "/tmp/a.sock"], "critical": true, "restartDelay": "2s"},
+				{"name": "client", "command": "/bin/client", "dependsOn": ["server"], "stdin": "config"}
+			]}`,
+			want: []*Process{
+				{Name: "server", Command: "/bin/server", Args: []string{"-socket", "/tmp/a.sock"}, Critical: true, RestartDelay: 2 * time.Second},
+				{Name: "client", Command: "/bin/client", DependsOn: []string{"server"}, StdinData: []byte("config")},
+			},
+		},
+		{
+			name: "stop signals",
+			content: `{"processes": [
+				{"name": "worker", "command": "/bin/worker", "stopSignals": [{"signal": "SIGQUIT", "wait": "2s"}, {"signal": "INT", "wait": "5s"}]}
+			]}`,
+			want: []*Process{
+				{Name: "worker", Command: "/bin/worker", StopSignals: []StopStep{{Signal: syscall.SIGQUIT, Wait: 2 * time.Second}, {Signal: syscall.SIGINT, Wait: 5 * time.Second}}},
+			},
+		},
+		{
+			name: "watch paths",
+			content: `{"processes": [
+				{"name": "proxy", "command": "/bin/proxy", "watchPaths": ["/etc/proxy.conf"], "watchSignal": "SIGHUP"}
+			]}`,
+			want: []*Process{
+				{Name: "proxy", Command: "/bin/proxy", WatchPaths: []string{"/etc/proxy.conf"}, WatchSignal: syscall.SIGHUP},
+			},
+		},
+		{
+			name:    "unknown signal",
+			content: `{"processes": [{"name": "worker", "command": "/bin/worker", "stopSignals": [{"signal": "SIGNOPE", "wait": "1s"}]}]}`,
+			wantErr: `unsupported signal "SIGNOPE"`,
+		},
+		{
+			name:    "unknown field",
+			content: `{"processes": [{"name": "server", "comand": "/bin/server"}]}`,
+			wantErr: `unknown field "comand"`,
+		},
+		{
+			name:    "bad duration",
+			content: `{"processes": [{"name": "server", "command": "/bin/server", "restartDelay": "soon"}]}`,
+			wantErr: "invalid duration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, t.TempDir(), "config.json", tt.content)
+
+			processes, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(processes, tt.want) {
+				t.Errorf("LoadConfig() =")
+				for _, proc := range processes {
+					t.Errorf("  %+v", *proc)
+				}
+				t.Errorf("want")
+				for _, proc := range tt.want {
+					t.Errorf("  %+v", *proc)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigTOML(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := writeConfig(t, dir, "config.json", `{"processes": [
+		{"name": "server", "command": "/bin/server", "args": ["-socket", "/tmp/a.sock"], "critical": true, "restartDelay": "2s",
+		 "stopSignals": [{"signal": "SIGQUIT", "wait": "2s"}], "logLabels": {"service": "greeter"}, "cpuQuota": 0.5, "memoryLimitMB": 256},
+		{"name": "client", "command": "/bin/client", "dependsOn": ["server"], "kind": "task", "stdin": "config"}
+	]}`)
+	tomlPath := writeConfig(t, dir, "config.toml", `
+[[processes]]
+name = "server"
+command = "/bin/server"
+args = ["-socket", "/tmp/a.sock"]
+critical = true
+restartDelay = "2s"
+cpuQuota = 0.5
+memoryLimitMB = 256
+stopSignals = [{signal = "SIGQUIT", wait = "2s"}]
+logLabels = {service = "greeter"}
+
+[[processes]]
+name = "client"
+command = "/bin/client"
+dependsOn = ["server"]
+kind = "task"
+stdin = "config"
+`)
+
+	want, err := LoadConfig(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadConfig(JSON): %v", err)
+	}
+	got, err := LoadConfig(tomlPath)
+	if err != nil {
+		t.Fatalf("LoadConfig(TOML): %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TOML config = %+v, %+v, want the same as JSON: %+v, %+v", *got[0], *got[1], *want[0], *want[1])
+	}
+
+	// The formats merge with each other like files of one format
+	override := writeConfig(t, dir, "override.toml", "[[processes]]\nname = \"server\"\nrestartDelay = \"1s\"\n")
+	merged, err := LoadConfig(jsonPath, override)
+	if err != nil {
+		t.Fatalf("LoadConfig(JSON, TOML): %v", err)
+	}
+	if merged[0].RestartDelay != time.Second || merged[0].Command != "/bin/server" {
+		t.Errorf("merged server = %+v, want the TOML restart delay over the JSON process", *merged[0])
+	}
+
+	for name, content := range map[string]string{
+		"unknown field": "[[processes]]\nname = \"server\"\ncomand = \"/bin/server\"\n",
+		"invalid TOML":  "[[processes]\nname = \"server\"\n",
+	} {
+		if _, err := LoadConfig(writeConfig(t, dir, "bad.toml", content)); err == nil {
+			t.Errorf("LoadConfig() with %s succeeded", name)
+		}
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if err == nil || !strings.Contains(err.Error(), "failed to open config") {
+		t.Errorf("LoadConfig() error = %v, want a failure to open the config", err)
+	}
+}
+
+func TestLoadConfigMerge(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfig(t, dir, "base.json", `{"processes": [
+		{"name": "server", "command": "/bin/server", "args": ["-socket", "/tmp/a.sock"], "critical": true, "restartDelay": "5s"},
+		{"name": "client", "command": "/bin/client", "dependsOn": ["server"]}
+	]}`)
+	override := writeConfig(t, dir, "prod.json", `{"processes": [
+		{"name": "server", "args": ["-socket", "/run/b.sock"], "restartDelay": "1s"},
+		{"name": "metrics", "command": "/bin/metrics"}
+	]}`)
+
+	processes, err := LoadConfig(base, override)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	want := []*Process{
+		// Fields the override sets replace the base ones, args included; the rest are kept
+		{Name: "server", Command: "/bin/server", Args: []string{"-socket", "/run/b.sock"}, Critical: true, RestartDelay: time.Second},
+		{Name: "client", Command: "/bin/client", DependsOn: []string{"server"}},
+		// Only in the override, so added after the base processes
+		{Name: "metrics", Command: "/bin/metrics"},
+	}
+	if !reflect.DeepEqual(processes, want) {
+		for _, proc := range processes {
+			t.Errorf("got  %+v", *proc)
+		}
+		for _, proc := range want {
+			t.Errorf("want %+v", *proc)
+		}
+	}
+}
+
+func TestLoadConfigMergeKeepsDuplicatesWithinFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "config.json", `{"processes": [
+		{"name": "server", "command": "/bin/a"},
+		{"name": "server", "command": "/bin/b"}
+	]}`)
+
+	processes, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(processes) != 2 {
+		t.Errorf("LoadConfig() returned %d processes, want both duplicates for validation to report", len(processes))
+	}
+}
+
+func TestConfigPathsFlag(t *testing.T) {
+	var paths configPaths
+	for _, value := range []string{"base.json", "a.json, b.json", ""} {
+		if err := paths.Set(value); err != nil {
+			t.Fatalf("Set(%q): %v", value, err)
+		}
+	}
+	if want := (configPaths{"base.json", "a.json", "b.json"}); !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %q, want %q", paths, want)
+	}
+}
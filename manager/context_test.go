@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParentContextCancelStopsProcesses(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	procs := []*Process{
+		{Name: "a", Command: "sleep", Args: []string{"30"}},
+		{Name: "b", Command: "sleep", Args: []string{"30"}},
+	}
+	pm := NewProcessManagerWithContext(ctx, procs)
+	events := pm.Events()
+
+	for _, proc := range procs {
+		if err := pm.startProcess(proc, false); err != nil {
+			t.Fatalf("startProcess(%s): %v", proc.Name, err)
+		}
+		waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	}
+
+	cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		pm.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatal("processes still running after the parent context was cancelled")
+	}
+	for _, state := range pm.States() {
+		if state.Running {
+			t.Errorf("process %s still running", state.Name)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestResolveCredential(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		group   string
+		want    *syscall.Credential
+		wantErr bool
+	}{
+		{"unset", "", "", nil, false},
+		{"user name", "daemon", "", &syscall.Credential{Uid: 1, Gid: 1}, false},
+		{"numeric user", "1", "", &syscall.Credential{Uid: 1, Gid: 1}, false},
+		{"numeric user without entry", "4321", "", &syscall.Credential{Uid: 4321, Gid: 4321}, false},
+		{"user and group", "daemon", "root", &syscall.Credential{Uid: 1, Gid: 0}, false},
+		{"group only", "", "4321", &syscall.Credential{Uid: uint32(os.Geteuid()), Gid: 4321}, false},
+		{"unknown user", "no-such-user-here", "", nil, true},
+		{"unknown group", "", "no-such-group-here", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveCredential(&Process{Name: "p", User: tt.user, Group: tt.group})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveCredential() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveCredential() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessRunsAsUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing user requires root")
+	}
+
+	proc := &Process{Name: "id", Command: "sh", Args: []string{"-c", "id -u; id -g"}, User: "4321", Group: "4322", RestartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventExited, 5*time.Second)
+
+	want := []string{"4321", "4322"}
+	if got := pm.logs[proc.Name].tail(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("child reported uid and gid %q, want %q", got, want)
+	}
+}
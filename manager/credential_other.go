@@ -0,0 +1,19 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func setCredential(cmd *exec.Cmd, proc *Process) error {
+	return checkCredential(proc)
+}
+
+func checkCredential(proc *Process) error {
+	if proc.User != "" || proc.Group != "" {
+		return errors.New("user and group are only supported on Unix")
+	}
+	return nil
+}
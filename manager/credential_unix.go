@@ -0,0 +1,106 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// setCredential makes cmd run as the User and Group of proc. It leaves cmd
+// alone when they match the manager's own user and group.
+func setCredential(cmd *exec.Cmd, proc *Process) error {
+	cred, err := resolveCredential(proc)
+	if err != nil || cred == nil {
+		return err
+	}
+	if cred.Uid == uint32(os.Geteuid()) && cred.Gid == uint32(os.Getegid()) {
+		return nil
+	}
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("running as uid %d gid %d requires the manager to run as root", cred.Uid, cred.Gid)
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = cred
+	return nil
+}
+
+// checkCredential reports whether the User and Group of proc can be resolved
+func checkCredential(proc *Process) error {
+	_, err := resolveCredential(proc)
+	return err
+}
+
+// resolveCredential looks up the User and Group of proc. Without a Group the
+// process runs with the user's primary group; without a User it keeps the
+// manager's user. Supplementary groups are dropped.
+func resolveCredential(proc *Process) (*syscall.Credential, error) {
+	if proc.User == "" && proc.Group == "" {
+		return nil, nil
+	}
+	cred := &syscall.Credential{Uid: uint32(os.Geteuid()), Gid: uint32(os.Getegid())}
+
+	if proc.User != "" {
+		u, err := lookupUser(proc.User)
+		if err != nil {
+			return nil, err
+		}
+		if cred.Uid, err = parseID(u.Uid); err != nil {
+			return nil, fmt.Errorf("user %q: %w", proc.User, err)
+		}
+		if cred.Gid, err = parseID(u.Gid); err != nil {
+			return nil, fmt.Errorf("user %q: %w", proc.User, err)
+		}
+	}
+
+	if proc.Group != "" {
+		gid, err := lookupGroup(proc.Group)
+		if err != nil {
+			return nil, err
+		}
+		cred.Gid = gid
+	}
+	return cred, nil
+}
+
+// lookupUser resolves a user name or numeric uid
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u, nil
+	}
+	if _, parseErr := parseID(name); parseErr == nil {
+		if u, err := user.LookupId(name); err == nil {
+			return u, nil
+		}
+		// A numeric uid without a passwd entry runs with the same gid
+		return &user.User{Uid: name, Gid: name}, nil
+	}
+	return nil, fmt.Errorf("unknown user %q: %w", name, err)
+}
+
+// lookupGroup resolves a group name or numeric gid
+func lookupGroup(name string) (uint32, error) {
+	if gid, err := parseID(name); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %q: %w", name, err)
+	}
+	return parseID(g.Gid)
+}
+
+func parseID(s string) (uint32, error) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", s)
+	}
+	return uint32(id), nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+)
+
+// restartDependents restarts the running processes that depend on proc, once
+// proc's new run is ready, so they reconnect to it. Restarting a dependent
+// restarts its own dependents in turn. It gives up if ctx is done first.
+func (pm *ProcessManager) restartDependents(ctx context.Context, proc *Process) {
+	if proc.ReadyCheck != nil {
+		if pending := pm.waitReady([]string{proc.Name}, pm.readyTimeout); pending != "" {
+			if ctx.Err() == nil {
+				log.Printf("Process %s: not ready after %v, not restarting its dependents", proc.Name, pm.readyTimeout)
+			}
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	for _, dependent := range pm.processList() {
+		if !slices.Contains(dependent.DependsOn, proc.Name) {
+			continue
+		}
+		pm.mu.Lock()
+		_, running := pm.running[dependent.Name]
+		pm.mu.Unlock()
+		// One that is not running picks up the new run when it next starts
+		if !running {
+			continue
+		}
+		log.Printf("Process %s: restarting because its dependency %s restarted", dependent.Name, proc.Name)
+		if err := pm.Restart(dependent.Name); err != nil {
+			log.Printf("Warning: failed to restart process %s: %v", dependent.Name, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartDependentsOnRestart(t *testing.T) {
+	server := &Process{Name: "server", Command: "sleep", Args: []string{"30"}, RestartDelay: time.Hour}
+	client := &Process{Name: "client", Command: "sleep", Args: []string{"30"}, DependsOn: []string{"server"}, RestartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{server, client})
+	pm.StartWait = 10 * time.Millisecond
+	pm.RestartDependentsOnRestart = true
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitEvent(t, events, server.Name, EventStarted, 5*time.Second)
+	first := waitEvent(t, events, client.Name, EventStarted, 5*time.Second)
+
+	if err := pm.Restart(server.Name); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	restarted := waitEvent(t, events, server.Name, EventStarted, 5*time.Second)
+	// Well within the client's hour-long restart delay, which the restart skips
+	second := waitEvent(t, events, client.Name, EventStarted, 5*time.Second)
+	if second.PID == first.PID {
+		t.Errorf("client PID after the server restarted = %d, want a new process", second.PID)
+	}
+	if second.Time.Before(restarted.Time) {
+		t.Error("client restarted before the server's new run started")
+	}
+}
+
+func TestDependentsNotRestartedByDefault(t *testing.T) {
+	server := &Process{Name: "server", Command: "sleep", Args: []string{"30"}, RestartDelay: time.Hour}
+	client := &Process{Name: "client", Command: "sleep", Args: []string{"30"}, DependsOn: []string{"server"}, RestartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{server, client})
+	pm.StartWait = 10 * time.Millisecond
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitEvent(t, events, client.Name, EventStarted, 5*time.Second)
+	if err := pm.Restart(server.Name); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	waitEvent(t, events, server.Name, EventStarted, 5*time.Second)
+
+	deadline := time.After(300 * time.Millisecond)
+	for {
+		select {
+		case event := <-events:
+			if event.Name == client.Name {
+				t.Fatalf("client %s after the server restarted, want it left running", event.Type)
+			}
+		case <-deadline:
+			return
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// processLoop is the restart loop of a process, started by startProcess
+type processLoop struct {
+	cancel context.CancelFunc
+	// Closed once the loop has returned
+	done chan struct{}
+}
+
+// processList returns the managed processes in configuration order. The slice
+// is replaced, never modified, when processes are added or removed, so it may
+// be used after the lock is released.
+func (pm *ProcessManager) processList() []*Process {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.processes
+}
+
+// logBuffer returns the output history of the named process, or nil if it is not managed
+func (pm *ProcessManager) logBuffer(name string) *logBuffer {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.logs[name]
+}
+
+// AddProcess starts managing proc while the manager is running. Its
+// dependencies must already be managed, and it is started once those with a
+// ReadyCheck report ready, as during Start. A failed first run is returned as
+// an error, but the process stays managed and is retried like any other.
+// StdinFrom cannot be used for a process added at runtime.
+func (pm *ProcessManager) AddProcess(proc *Process) error {
+	if proc.Name == "" {
+		return errors.New("process with empty name")
+	}
+	if proc.StdinFrom != "" {
+		return fmt.Errorf("process %q: stdinFrom is not supported for a process added at runtime", proc.Name)
+	}
+
+	pm.mu.Lock()
+	if pm.ctx.Err() != nil {
+		pm.mu.Unlock()
+		return fmt.Errorf("process %q: the manager is shut down", proc.Name)
+	}
+	if _, ok := pm.states[proc.Name]; ok {
+		pm.mu.Unlock()
+		return fmt.Errorf("duplicate process name %q", proc.Name)
+	}
+	for _, dep := range proc.DependsOn {
+		if !slices.ContainsFunc(pm.processes, func(p *Process) bool { return p.Name == dep }) {
+			pm.mu.Unlock()
+			return fmt.Errorf("process %q: depends on unknown process %q", proc.Name, dep)
+		}
+	}
+	// Clipped so the append copies, leaving earlier snapshots untouched
+	pm.processes = append(slices.Clip(pm.processes), proc)
+	pm.states[proc.Name] = &ProcessState{Name: proc.Name, LastExitCode: -1}
+	pm.logs[proc.Name] = newLogBuffer(logHistoryLines)
+	pm.mu.Unlock()
+
+	log.Printf("Process %s: added", proc.Name)
+	if !proc.enabled() {
+		log.Printf("Process %s: skipped, enabledIf %q does not hold", proc.Name, proc.EnabledIf)
+		return nil
+	}
+
+	if len(proc.DependsOn) > 0 && !pm.waitForDependencies(proc) {
+		if pm.ctx.Err() != nil {
+			return fmt.Errorf("shutdown requested while starting process %s", proc.Name)
+		}
+		log.Printf("Warning: starting process %s before its dependencies are ready", proc.Name)
+	}
+	if pm.process(proc.Name) != proc {
+		return fmt.Errorf("process %s was removed before it started", proc.Name)
+	}
+	return pm.startProcess(proc, true)
+}
+
+// RemoveProcess stops the named process and stops managing it. It returns once
+// the process has exited. Processes that depend on it must be removed first.
+func (pm *ProcessManager) RemoveProcess(name string) error {
+	pm.mu.Lock()
+	i := slices.IndexFunc(pm.processes, func(p *Process) bool { return p.Name == name })
+	if i < 0 {
+		pm.mu.Unlock()
+		return fmt.Errorf("unknown process %q", name)
+	}
+	for _, other := range pm.processes {
+		if slices.Contains(other.DependsOn, name) || other.StdinFrom == name {
+			pm.mu.Unlock()
+			return fmt.Errorf("process %q is needed by process %q", name, other.Name)
+		}
+	}
+	proc := pm.processes[i]
+	pm.processes = slices.Delete(slices.Clone(pm.processes), i, i+1)
+	loop := pm.loops[name]
+	delete(pm.loops, name)
+	pm.mu.Unlock()
+
+	log.Printf("Process %s: removing", name)
+	// End the restart loop before stopping the process, so it is not brought back
+	if loop != nil {
+		loop.cancel()
+	}
+	pm.mu.Lock()
+	handle, running := pm.running[name]
+	pm.mu.Unlock()
+	if running {
+		stopProcess(proc, handle)
+	}
+	if loop != nil {
+		<-loop.done
+	}
+	if pipe := pm.stdinPipes[proc.StdinFrom]; pipe != nil {
+		pipe.close()
+	}
+
+	pm.mu.Lock()
+	delete(pm.states, name)
+	delete(pm.logs, name)
+	delete(pm.restartRequested, name)
+	delete(pm.restartTimes, name)
+	delete(pm.flapping, name)
+	delete(pm.completed, name)
+	delete(pm.shed, name)
+	pm.shedOrder = slices.DeleteFunc(pm.shedOrder, func(n string) bool { return n == name })
+	pm.mu.Unlock()
+
+	log.Printf("Process %s: removed", name)
+	return nil
+}
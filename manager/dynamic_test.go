@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// stateOf returns the state of the named process and whether it is managed
+func stateOf(pm *ProcessManager, name string) (ProcessState, bool) {
+	for _, state := range pm.States() {
+		if state.Name == name {
+			return state, true
+		}
+	}
+	return ProcessState{}, false
+}
+
+func TestAddAndRemoveProcess(t *testing.T) {
+	base := &Process{Name: "base", Command: "sleep", Args: []string{"30"}}
+	pm := NewProcessManager([]*Process{base})
+	pm.StartWait = 10 * time.Millisecond
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	if _, ok := stateOf(pm, "sidecar"); ok {
+		t.Fatal("sidecar managed before it was added")
+	}
+	sidecar := &Process{Name: "sidecar", Command: "sleep", Args: []string{"30"}, DependsOn: []string{"base"}}
+	if err := pm.AddProcess(sidecar); err != nil {
+		t.Fatalf("AddProcess: %v", err)
+	}
+	started := waitEvent(t, events, "sidecar", EventStarted, 5*time.Second)
+	if state, ok := stateOf(pm, "sidecar"); !ok || !state.Running || state.PID != started.PID {
+		t.Errorf("state after AddProcess = %+v (managed %v), want running as PID %d", state, ok, started.PID)
+	}
+
+	if err := pm.RemoveProcess("sidecar"); err != nil {
+		t.Fatalf("RemoveProcess: %v", err)
+	}
+	waitEvent(t, events, "sidecar", EventExited, 5*time.Second)
+	if state, ok := stateOf(pm, "sidecar"); ok {
+		t.Errorf("state after RemoveProcess = %+v, want the process gone", state)
+	}
+	if pm.logBuffer("sidecar") != nil {
+		t.Error("logs of a removed process are still kept")
+	}
+	// The restart loop ended with the removal
+	select {
+	case event := <-events:
+		if event.Name == "sidecar" {
+			t.Errorf("event %v after RemoveProcess", event.Type)
+		}
+	case <-time.After(200 * time.Millisecond):
+	}
+	if state, _ := stateOf(pm, "base"); !state.Running {
+		t.Errorf("base state = %+v, want it still running", state)
+	}
+
+	// A removed name can be added again
+	if err := pm.AddProcess(&Process{Name: "sidecar", Command: "sleep", Args: []string{"30"}}); err != nil {
+		t.Fatalf("AddProcess after removal: %v", err)
+	}
+	waitEvent(t, events, "sidecar", EventStarted, 5*time.Second)
+}
+
+func TestAddProcessErrors(t *testing.T) {
+	pm := NewProcessManager([]*Process{{Name: "base", Command: "true"}})
+	defer pm.Shutdown()
+
+	tests := []struct {
+		name string
+		proc *Process
+	}{
+		{"empty name", &Process{Command: "true"}},
+		{"duplicate", &Process{Name: "base", Command: "true"}},
+		{"unknown dependency", &Process{Name: "new", Command: "true", DependsOn: []string{"missing"}}},
+		{"stdinFrom", &Process{Name: "new", Command: "cat", StdinFrom: "base"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pm.AddProcess(tt.proc); err == nil {
+				t.Error("AddProcess succeeded")
+			}
+		})
+	}
+	if got := names(pm.processList()); len(got) != 1 {
+		t.Errorf("processes = %v, want only base", got)
+	}
+
+	pm.Shutdown()
+	if err := pm.AddProcess(&Process{Name: "late", Command: "true"}); err == nil {
+		t.Error("AddProcess after shutdown succeeded")
+	}
+}
+
+func TestRemoveProcessErrors(t *testing.T) {
+	pm := NewProcessManager(procs("base", "dependent:base"))
+	defer pm.Shutdown()
+
+	if err := pm.RemoveProcess("missing"); err == nil {
+		t.Error("RemoveProcess of an unknown process succeeded")
+	}
+	if err := pm.RemoveProcess("base"); err == nil {
+		t.Error("RemoveProcess of a process with a dependent succeeded")
+	}
+	// Removing the dependent first frees the process it depended on
+	if err := pm.RemoveProcess("dependent"); err != nil {
+		t.Errorf("RemoveProcess(dependent): %v", err)
+	}
+	if err := pm.RemoveProcess("base"); err != nil {
+		t.Errorf("RemoveProcess(base): %v", err)
+	}
+	if states := pm.States(); len(states) != 0 {
+		t.Errorf("States() = %+v, want none", states)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// enabled reports whether proc's EnabledIf condition holds in the manager's
+// environment: "VAR" holds when VAR is set and not empty, "VAR=value" when VAR
+// is set to exactly value. A process without a condition is always enabled.
+func (proc *Process) enabled() bool {
+	if proc.EnabledIf == "" {
+		return true
+	}
+	name, want, equality := strings.Cut(proc.EnabledIf, "=")
+	value, set := os.LookupEnv(name)
+	if !equality {
+		return value != ""
+	}
+	return set && value == want
+}
+
+// checkEnabledIf reports whether an EnabledIf condition names a variable
+func checkEnabledIf(condition string) error {
+	name, _, _ := strings.Cut(condition, "=")
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid enabledIf %q: want VAR or VAR=value", condition)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProcessEnabled(t *testing.T) {
+	t.Setenv("MPD_ENABLED_DEBUG", "1")
+	t.Setenv("MPD_ENABLED_EMPTY", "")
+
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{"", true},
+		{"MPD_ENABLED_DEBUG", true},
+		{"MPD_ENABLED_DEBUG=1", true},
+		{"MPD_ENABLED_DEBUG=0", false},
+		{"MPD_ENABLED_EMPTY", false},
+		{"MPD_ENABLED_EMPTY=", true},
+		{"MPD_ENABLED_UNSET", false},
+		{"MPD_ENABLED_UNSET=", false},
+	}
+	for _, tt := range tests {
+		proc := &Process{Name: "debug", EnabledIf: tt.condition}
+		if got := proc.enabled(); got != tt.want {
+			t.Errorf("enabled() with EnabledIf %q = %v, want %v", tt.condition, got, tt.want)
+		}
+	}
+}
+
+func TestStartSkipsDisabledProcess(t *testing.T) {
+	for _, debug := range []string{"0", "1"} {
+		t.Run("DEBUG="+debug, func(t *testing.T) {
+			t.Setenv("MPD_TEST_DEBUG", debug)
+			app := &Process{Name: "app", Command: "sleep", Args: []string{"30"}}
+			sidecar := &Process{Name: "sidecar", Command: "sleep", Args: []string{"30"}, Critical: true, MinStableRun: time.Millisecond, EnabledIf: "MPD_TEST_DEBUG=1"}
+			// The app waits for the sidecar only when it runs
+			app.DependsOn = []string{"sidecar"}
+			sidecar.ReadyCheck = func(context.Context) error { return nil }
+			pm := NewProcessManager([]*Process{sidecar, app})
+			defer pm.Shutdown()
+
+			if err := pm.Start(); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			states := pm.States()
+			if want := debug == "1"; states[0].Running != want {
+				t.Errorf("sidecar running = %v, want %v", states[0].Running, want)
+			}
+			if !states[1].Running {
+				t.Error("app not running")
+			}
+			if debug == "0" && !pm.Healthy() {
+				t.Error("bundle unhealthy with the critical sidecar disabled")
+			}
+		})
+	}
+}
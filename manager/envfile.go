@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// fileEnv returns the variables of proc's EnvFromFile as NAME=value, each set
+// to the contents of its file without the trailing newline. The files are
+// read on every start, so a restart picks up a rotated secret. A file that
+// cannot be read fails the start with StrictEnv, and otherwise leaves its
+// variable unset. Values are never logged.
+func fileEnv(proc *Process) ([]string, error) {
+	var env []string
+	for _, name := range slices.Sorted(maps.Keys(proc.EnvFromFile)) {
+		path := proc.EnvFromFile[name]
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if proc.StrictEnv {
+				return nil, fmt.Errorf("environment variable %s: %w", name, err)
+			}
+			log.Printf("Process %s: leaving %s unset: %v", proc.Name, name, err)
+			continue
+		}
+		value := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// checkEnvFromFile returns the problems with proc's EnvFromFile mapping: names
+// that cannot be environment variables, and with StrictEnv, files that cannot
+// be read now
+func checkEnvFromFile(proc *Process) []error {
+	var problems []error
+	for _, name := range slices.Sorted(maps.Keys(proc.EnvFromFile)) {
+		switch {
+		case name == "" || strings.ContainsAny(name, "=\x00"):
+			problems = append(problems, fmt.Errorf("process %q: invalid envFromFile variable name %q", proc.Name, name))
+		case proc.EnvFromFile[name] == "":
+			problems = append(problems, fmt.Errorf("process %q: envFromFile variable %s has no file", proc.Name, name))
+		case proc.StrictEnv:
+			if _, err := os.Stat(proc.EnvFromFile[name]); err != nil {
+				problems = append(problems, fmt.Errorf("process %q: environment variable %s: %v", proc.Name, name, err))
+			}
+		}
+	}
+	return problems
+}
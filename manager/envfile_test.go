@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvFromFile(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "pw")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	proc := &Process{
+		Name:         "app",
+		Command:      "sh",
+		Args:         []string{"-c", `echo "password=$PASSWORD"; exec sleep 30`},
+		EnvFromFile:  map[string]string{"PASSWORD": secret},
+		RestartDelay: 10 * time.Millisecond,
+	}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitForTail(t, pm, proc.Name, "password=s3cret")
+
+	// A rotated secret is picked up by the next start
+	if err := os.WriteFile(secret, []byte("rotated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.Restart(proc.Name); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	waitForTail(t, pm, proc.Name, "password=rotated")
+}
+
+// waitForTail waits for line to be among the latest output of the named
+// process. Unlike waitForLine it also finds lines written before the call.
+func waitForTail(t *testing.T, pm *ProcessManager, name, line string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(pm.logBuffer(name).tail(20), line) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s output = %q, want the line %q", name, pm.logBuffer(name).tail(20), line)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEnvFromMissingFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	t.Run("lenient", func(t *testing.T) {
+		proc := &Process{
+			Name:        "app",
+			Command:     "sh",
+			Args:        []string{"-c", `echo "password ${PASSWORD-unset}"`},
+			EnvFromFile: map[string]string{"PASSWORD": missing},
+			Kind:        KindTask,
+		}
+		pm := NewProcessManager([]*Process{proc})
+		defer pm.Shutdown()
+		events := pm.Events()
+		if err := pm.startProcess(proc, false); err != nil {
+			t.Fatalf("startProcess: %v", err)
+		}
+		waitEvent(t, events, proc.Name, EventExited, 5*time.Second)
+		if lines := pm.logBuffer(proc.Name).tail(10); !slices.Contains(lines, "password unset") {
+			t.Errorf("process output = %q, want PASSWORD left unset", lines)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		proc := &Process{
+			Name:        "app",
+			Command:     "true",
+			EnvFromFile: map[string]string{"PASSWORD": missing},
+			StrictEnv:   true,
+			Critical:    true,
+		}
+		pm := NewProcessManager([]*Process{proc})
+		defer pm.Shutdown()
+		err := pm.startProcess(proc, true)
+		if err == nil || !strings.Contains(err.Error(), "environment variable PASSWORD") {
+			t.Errorf("startProcess() = %v, want the unreadable file to fail the start", err)
+		}
+		if problems := checkEnvFromFile(proc); len(problems) != 1 {
+			t.Errorf("checkEnvFromFile() = %v, want the missing file reported", problems)
+		}
+	})
+}
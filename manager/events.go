@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// EventType identifies a process lifecycle transition
+type EventType string
+
+const (
+	EventStarted    EventType = "Started"
+	EventExited     EventType = "Exited"
+	EventRestarting EventType = "Restarting"
+	EventGaveUp     EventType = "GaveUp"
+	EventFlapping   EventType = "Flapping"
+)
+
+// eventBufferSize is how many events are held for a slow observer before new ones are dropped
+const eventBufferSize = 64
+
+// ProcessEvent describes a lifecycle transition of a managed process
+type ProcessEvent struct {
+	Name string
+	Type EventType
+	PID  int
+	// Exit code of the process, only meaningful for Exited events (-1 if unknown)
+	ExitCode int
+	Time     time.Time
+}
+
+// Events returns a channel that receives process lifecycle events.
+// Events are dropped if the channel buffer is full, so the restart loop never blocks.
+func (pm *ProcessManager) Events() <-chan ProcessEvent {
+	return pm.events
+}
+
+// emit publishes an event without blocking
+func (pm *ProcessManager) emit(name string, typ EventType, pid, exitCode int) {
+	event := ProcessEvent{
+		Name:     name,
+		Type:     typ,
+		PID:      pid,
+		ExitCode: exitCode,
+		Time:     time.Now(),
+	}
+	pm.notify(event)
+
+	select {
+	case pm.events <- event:
+	default:
+		// No reader keeping up, drop the event
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// waitEvent returns the next event of type typ for the named process,
+// skipping any others, or fails the test after timeout
+func waitEvent(t *testing.T, events <-chan ProcessEvent, name string, typ EventType, timeout time.Duration) ProcessEvent {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Name == name && ev.Type == typ {
+				return ev
+			}
+		case <-deadline:
+			t.Fatalf("no %s event for %s within %v", typ, name, timeout)
+		}
+	}
+}
+
+func TestEventsShortLivedProcess(t *testing.T) {
+	proc := &Process{Name: "short", Command: "sh", Args: []string{"-c", "exit 3"}, RestartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+
+	started := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	if started.PID <= 0 {
+		t.Errorf("Started event PID = %d, want a real PID", started.PID)
+	}
+	exited := waitEvent(t, events, proc.Name, EventExited, 5*time.Second)
+	if exited.PID != started.PID {
+		t.Errorf("Exited event PID = %d, want %d", exited.PID, started.PID)
+	}
+	if exited.ExitCode != 3 {
+		t.Errorf("Exited event exit code = %d, want 3", exited.ExitCode)
+	}
+	if exited.Time.Before(started.Time) {
+		t.Error("Exited event is timestamped before Started")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestExitReasonSignaled(t *testing.T) {
+	proc := &Process{Name: "victim", Command: "sleep", Args: []string{"30"}, RestartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	started := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	if err := syscall.Kill(started.PID, syscall.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, proc.Name, EventExited, 5*time.Second)
+
+	if state := pm.States()[0]; state.ExitReason != ExitSignaled || state.ExitSignal != syscall.SIGKILL {
+		t.Errorf("exit = %s/%v, want %s/%v", state.ExitReason, state.ExitSignal, ExitSignaled, syscall.SIGKILL)
+	}
+}
+
+func TestExitReason(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		maxRuntime time.Duration
+		want       ExitReason
+		wantSignal syscall.Signal
+	}{
+		{"normal", []string{"-c", "exit 0"}, 0, ExitNormal, 0},
+		{"failure", []string{"-c", "exit 3"}, 0, ExitFailure, 0},
+		{"signaled", []string{"-c", "kill -TERM $$"}, 0, ExitSignaled, syscall.SIGTERM},
+		{"timed out", []string{"-c", "exec sleep 30"}, 200 * time.Millisecond, ExitTimedOut, syscall.SIGTERM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc := &Process{Name: "proc", Command: "sh", Args: tt.args, MaxRuntime: tt.maxRuntime, RestartDelay: time.Hour}
+			pm := NewProcessManager([]*Process{proc})
+			defer pm.Shutdown()
+			events := pm.Events()
+
+			if err := pm.startProcess(proc, false); err != nil {
+				t.Fatalf("startProcess: %v", err)
+			}
+			waitEvent(t, events, proc.Name, EventExited, 5*time.Second)
+
+			if state := pm.States()[0]; state.ExitReason != tt.want || state.ExitSignal != tt.wantSignal {
+				t.Errorf("exit = %s/%v, want %s/%v", state.ExitReason, state.ExitSignal, tt.want, tt.wantSignal)
+			}
+		})
+	}
+}
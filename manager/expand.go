@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expandCommand returns the Command and Args of proc with ${VAR} and $VAR
+// references replaced from the process's environment: the manager's, which
+// the process inherits, followed by env, the variables it adds. $$ stands for
+// a literal $. Without ExpandEnv they are returned as they are, so a $ meant
+// for a shell script reaches it untouched.
+func expandCommand(proc *Process, env []string) (string, []string, error) {
+	if !proc.ExpandEnv {
+		return proc.Command, proc.Args, nil
+	}
+
+	// Later entries win, as they do for the process itself
+	values := make(map[string]string)
+	for _, kv := range append(os.Environ(), env...) {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			values[name] = value
+		}
+	}
+	var missing []string
+	lookup := func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		value, ok := values[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	}
+
+	command := os.Expand(proc.Command, lookup)
+	var args []string
+	if proc.Args != nil {
+		args = make([]string, len(proc.Args))
+		for i, arg := range proc.Args {
+			args[i] = os.Expand(arg, lookup)
+		}
+	}
+
+	if proc.StrictEnv && len(missing) > 0 {
+		return "", nil, fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+	return command, args, nil
+}
+
+// resolveCommand returns command with a glob pattern such as /app/server-*
+// resolved to the one executable file it matches, for binaries dropped in
+// with versioned names. A command without glob characters is returned as it
+// is. Matching no executable, or more than one, is an error.
+func resolveCommand(command string) (string, error) {
+	if !strings.ContainsAny(command, "*?[") {
+		return command, nil
+	}
+	matches, err := filepath.Glob(command)
+	if err != nil {
+		return "", fmt.Errorf("invalid command pattern %q: %w", command, err)
+	}
+
+	var executables []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() && info.Mode()&0o111 != 0 {
+			executables = append(executables, match)
+		}
+	}
+	switch len(executables) {
+	case 0:
+		return "", fmt.Errorf("no executable matches command pattern %q", command)
+	case 1:
+		return executables[0], nil
+	default:
+		return "", fmt.Errorf("command pattern %q matches %d executables: %s", command, len(executables), strings.Join(executables, ", "))
+	}
+}
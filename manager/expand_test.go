@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandCommand(t *testing.T) {
+	t.Setenv("SERVER_BIN", "/app/server")
+	t.Setenv("SOCKET", "/tmp/grpc.sock")
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		name        string
+		proc        Process
+		env         []string
+		wantCommand string
+		wantArgs    []string
+		wantErr     bool
+	}{
+		{"plain", Process{Command: "sleep", Args: []string{"30"}, ExpandEnv: true}, nil, "sleep", []string{"30"}, false},
+		{"braced and bare", Process{Command: "${SERVER_BIN}", Args: []string{"-socket", "$SOCKET", "--addr=${SOCKET}.next"}, ExpandEnv: true}, nil, "/app/server", []string{"-socket", "/tmp/grpc.sock", "--addr=/tmp/grpc.sock.next"}, false},
+		{"missing expands to empty", Process{Command: "${SERVER_BIN}", Args: []string{"-flag=${NOT_SET_ANYWHERE}"}, ExpandEnv: true}, nil, "/app/server", []string{"-flag="}, false},
+		{"missing in strict mode", Process{Command: "${SERVER_BIN}", Args: []string{"${NOT_SET_ANYWHERE}"}, ExpandEnv: true, StrictEnv: true}, nil, "", nil, true},
+		{"escaped dollar", Process{Command: "sh", Args: []string{"-c", "for i in 1 2; do echo $$i; done"}, ExpandEnv: true, StrictEnv: true}, nil, "sh", []string{"-c", "for i in 1 2; do echo $i; done"}, false},
+		{"empty but set in strict mode", Process{Command: "${SERVER_BIN}", Args: []string{"${EMPTY}"}, ExpandEnv: true, StrictEnv: true}, nil, "/app/server", []string{""}, false},
+		{"process variables", Process{Command: "${SERVER_BIN}", Args: []string{"-password=$PASSWORD"}, ExpandEnv: true, StrictEnv: true}, []string{"PASSWORD=s3cret", "SERVER_BIN=/opt/server"}, "/opt/server", []string{"-password=s3cret"}, false},
+		{"not expanded by default", Process{Command: "sh", Args: []string{"-c", `kill -TERM $$; echo "$1" ${SOCKET}`}, StrictEnv: true}, nil, "sh", []string{"-c", `kill -TERM $$; echo "$1" ${SOCKET}`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, args, err := expandCommand(&tt.proc, tt.env)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if command != tt.wantCommand || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("expandCommand() = %q %q, want %q %q", command, args, tt.wantCommand, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestStrictEnvFailsStart(t *testing.T) {
+	proc := &Process{Name: "strict", Command: "sh", Args: []string{"-c", "${NOT_SET_ANYWHERE}"}, ExpandEnv: true, StrictEnv: true, Critical: true}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+
+	err := pm.startProcess(proc, true)
+	if want := "environment variables not set: NOT_SET_ANYWHERE"; err == nil || err.Error() != want {
+		t.Errorf("startProcess() = %v, want %q", err, want)
+	}
+}
+
+// writeExecutable creates an executable shell script at path that prints its name
+func writeExecutable(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho \"$0\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveCommandSingleMatch(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "server-1.4.2")
+	writeExecutable(t, binary)
+	// Neither a directory nor a file without the execute bit counts as a match
+	if err := os.Mkdir(filepath.Join(dir, "server-data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "server-notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := resolveCommand(filepath.Join(dir, "server-*"))
+	if err != nil || got != binary {
+		t.Fatalf("resolveCommand() = %q, %v, want %q", got, err, binary)
+	}
+	if got, err := resolveCommand("sleep"); err != nil || got != "sleep" {
+		t.Errorf("resolveCommand(sleep) = %q, %v, want it unchanged", got, err)
+	}
+
+	// The process runs the resolved binary
+	proc := &Process{Name: "server", Command: filepath.Join(dir, "server-*"), Kind: KindTask}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventExited, 5*time.Second)
+	if lines := pm.logBuffer(proc.Name).tail(10); len(lines) != 1 || lines[0] != binary {
+		t.Errorf("process printed %q, want the resolved path %q", lines, binary)
+	}
+}
+
+func TestResolveCommandAmbiguous(t *testing.T) {
+	dir := t.TempDir()
+	writeExecutable(t, filepath.Join(dir, "server-1.4.2"))
+	writeExecutable(t, filepath.Join(dir, "server-1.5.0"))
+
+	_, err := resolveCommand(filepath.Join(dir, "server-*"))
+	if err == nil || !strings.Contains(err.Error(), "matches 2 executables") {
+		t.Errorf("resolveCommand() error = %v, want several matches reported", err)
+	}
+	_, err = resolveCommand(filepath.Join(dir, "client-*"))
+	if err == nil || !strings.Contains(err.Error(), "no executable matches") {
+		t.Errorf("resolveCommand() error = %v, want no match reported", err)
+	}
+
+	// Validation and startup fail the same way
+	proc := &Process{Name: "server", Command: filepath.Join(dir, "server-*"), Critical: true}
+	if problems := validateProcesses([]*Process{proc}); len(problems) != 1 || !strings.Contains(problems[0].Error(), "matches 2 executables") {
+		t.Errorf("validateProcesses() = %v, want the ambiguous command reported", problems)
+	}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	if err := pm.startProcess(proc, true); err == nil || !strings.Contains(err.Error(), "matches 2 executables") {
+		t.Errorf("startProcess() = %v, want the ambiguous command reported", err)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// FileReadyCheck returns a ReadyCheck that passes while a file exists at path,
+// such as one the gRPC server creates with -ready-file once it is serving and
+// removes on shutdown
+func FileReadyCheck(path string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("ready file not present: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileReadyCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ready")
+	check := FileReadyCheck(path)
+
+	if err := check(context.Background()); err == nil {
+		t.Error("check passed without the ready file")
+	}
+	if err := os.WriteFile(path, []byte("1234\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := check(context.Background()); err != nil {
+		t.Errorf("check failed with the ready file present: %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := check(context.Background()); err == nil {
+		t.Error("check passed after the ready file was removed")
+	}
+}
+
+func TestLoadConfigReadyFile(t *testing.T) {
+	dir := t.TempDir()
+	readyPath := filepath.Join(dir, "ready")
+	path := writeConfig(t, dir, "config.json", `{"processes": [
+		{"name": "server", "command": "/bin/server", "args": ["-ready-file", "`+readyPath+`"], "readyFile": "`+readyPath+`"}
+	]}`)
+
+	processes, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	proc := processes[0]
+	if proc.ReadyFile != readyPath || proc.ReadyCheck == nil {
+		t.Fatalf("ready file = %q, check set %v; want %q with a check", proc.ReadyFile, proc.ReadyCheck != nil, readyPath)
+	}
+	if err := proc.ReadyCheck(context.Background()); err == nil {
+		t.Error("ReadyCheck passed without the ready file")
+	}
+	if got := proc.toConfig().ReadyFile; got != readyPath {
+		t.Errorf("toConfig().ReadyFile = %q, want %q", got, readyPath)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// defaultRestartAlertWindow is the window restarts are counted in for
+// RestartAlertThreshold when no RestartAlertWindow is set
+const defaultRestartAlertWindow = time.Minute
+
+// checkFlapping records a restart of proc and raises a flapping alert when its
+// restarts within the alert window exceed RestartAlertThreshold. The alert is
+// raised once when the threshold is crossed, and again only after the restart
+// rate has dropped back under it.
+func (pm *ProcessManager) checkFlapping(proc *Process, pid int) {
+	if proc.RestartAlertThreshold <= 0 {
+		return
+	}
+	window := proc.RestartAlertWindow
+	if window == 0 {
+		window = defaultRestartAlertWindow
+	}
+
+	now := time.Now()
+	pm.mu.Lock()
+	times := append(pm.restartTimes[proc.Name], now)
+	for len(times) > 0 && now.Sub(times[0]) > window {
+		times = times[1:]
+	}
+	pm.restartTimes[proc.Name] = times
+	over := len(times) > proc.RestartAlertThreshold
+	alert := over && !pm.flapping[proc.Name]
+	pm.flapping[proc.Name] = over
+	pm.mu.Unlock()
+
+	if alert {
+		log.Printf("ALERT: process %s is flapping: %d restarts in the last %v (threshold %d)", proc.Name, len(times), window, proc.RestartAlertThreshold)
+		pm.emit(proc.Name, EventFlapping, pid, -1)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlappingEventOnRapidRestarts(t *testing.T) {
+	proc := &Process{Name: "crasher", Command: "false", RestartDelay: 10 * time.Millisecond, RestartAlertThreshold: 3}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+
+	// The alert comes with the restart that takes the count over the threshold
+	restarts := 0
+	deadline := time.After(10 * time.Second)
+	for flapping := false; !flapping; {
+		select {
+		case ev := <-events:
+			switch ev.Type {
+			case EventRestarting:
+				restarts++
+			case EventFlapping:
+				flapping = true
+			}
+		case <-deadline:
+			t.Fatalf("no Flapping event after %d restarts", restarts)
+		}
+	}
+	if restarts != proc.RestartAlertThreshold+1 {
+		t.Errorf("Flapping after %d restarts, want %d", restarts, proc.RestartAlertThreshold+1)
+	}
+
+	// Further restarts while still over the threshold do not repeat the alert
+	for restarts := 0; restarts < 3; {
+		select {
+		case ev := <-events:
+			switch ev.Type {
+			case EventRestarting:
+				restarts++
+			case EventFlapping:
+				t.Fatal("second Flapping event while still flapping")
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("process stopped restarting")
+		}
+	}
+}
+
+func TestFlappingAlertRearmsAfterWindow(t *testing.T) {
+	proc := &Process{Name: "crasher", RestartAlertThreshold: 1, RestartAlertWindow: 100 * time.Millisecond}
+	pm := NewProcessManager([]*Process{proc})
+	events := pm.Events()
+
+	pm.checkFlapping(proc, 1)
+	pm.checkFlapping(proc, 2)
+	nextEvent(t, events, EventFlapping, time.Second)
+
+	// Once the earlier restarts have left the window the rate is back under
+	// the threshold, and crossing it again raises a new alert
+	time.Sleep(150 * time.Millisecond)
+	pm.checkFlapping(proc, 3)
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected %s event for a single restart in the window", ev.Type)
+	default:
+	}
+	pm.checkFlapping(proc, 4)
+	if ev := nextEvent(t, events, EventFlapping, time.Second); ev.PID != 4 {
+		t.Errorf("Flapping event PID = %d, want 4", ev.PID)
+	}
+}
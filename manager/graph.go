@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// describeStartOrder lists processes in start order, each with the processes
+// it waits for, e.g. "migrate, server (after migrate), client (after server)"
+func describeStartOrder(order []*Process) string {
+	parts := make([]string, 0, len(order))
+	for _, proc := range order {
+		if len(proc.DependsOn) == 0 {
+			parts = append(parts, proc.Name)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s (after %s)", proc.Name, strings.Join(proc.DependsOn, ", ")))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// runPrintGraph writes the dependency graph of processes to w in Graphviz DOT
+// format and returns the process exit code. Edges point from a process to the
+// processes that wait for it, so they follow the start order; critical
+// processes are drawn bold and stdinFrom pipes as dashed edges.
+func runPrintGraph(processes []*Process, w io.Writer) int {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph processes {")
+	for _, proc := range processes {
+		if proc.Critical {
+			fmt.Fprintf(out, "  %q [style=bold];\n", proc.Name)
+		} else {
+			fmt.Fprintf(out, "  %q;\n", proc.Name)
+		}
+	}
+	for _, proc := range processes {
+		for _, dep := range proc.DependsOn {
+			fmt.Fprintf(out, "  %q -> %q;\n", dep, proc.Name)
+		}
+		if proc.StdinFrom != "" {
+			fmt.Fprintf(out, "  %q -> %q [style=dashed, label=\"stdout\"];\n", proc.StdinFrom, proc.Name)
+		}
+	}
+	fmt.Fprintln(out, "}")
+
+	if err := out.Flush(); err != nil {
+		return 1
+	}
+	return 0
+}
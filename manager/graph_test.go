@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintGraph(t *testing.T) {
+	processes, err := LoadConfig(writeConfig(t, t.TempDir(), "graph.json", `{"processes": [
+		{"name": "migrate", "command": "/bin/migrate", "waitForExit": true},
+		{"name": "server", "command": "/bin/server", "critical": true, "dependsOn": ["migrate"]},
+		{"name": "client", "command": "/bin/client", "dependsOn": ["server", "migrate"]},
+		{"name": "indexer", "command": "/bin/indexer", "stdinFrom": "client"}
+	]}`))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	var out bytes.Buffer
+	if code := runPrintGraph(processes, &out); code != 0 {
+		t.Fatalf("runPrintGraph = %d", code)
+	}
+
+	want := `digraph processes {
+  "migrate";
+  "server" [style=bold];
+  "client";
+  "indexer";
+  "migrate" -> "server";
+  "server" -> "client";
+  "migrate" -> "client";
+  "client" -> "indexer" [style=dashed, label="stdout"];
+}
+`
+	if got := out.String(); got != want {
+		t.Errorf("graph:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDescribeStartOrder(t *testing.T) {
+	order, err := startOrder(procs("client:server,migrate", "server:migrate", "migrate"))
+	if err != nil {
+		t.Fatalf("startOrder: %v", err)
+	}
+	if got, want := describeStartOrder(order), "migrate, server (after migrate), client (after server, migrate)"; got != want {
+		t.Errorf("describeStartOrder() = %q, want %q", got, want)
+	}
+}
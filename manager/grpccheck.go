@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Interval between attempts while a gRPC health check waits for the server
+const grpcHealthRetryInterval = 200 * time.Millisecond
+
+// GRPCHealthCheck returns a ReadyCheck that dials the gRPC server on the Unix
+// socket at socketPath and asks its health service for the overall status. It
+// keeps retrying until the server reports SERVING or ctx is done, so a socket
+// that does not exist yet just counts as not ready.
+func GRPCHealthCheck(socketPath string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		conn, err := grpc.NewClient("unix://"+socketPath,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create gRPC client: %w", err)
+		}
+		defer conn.Close()
+
+		client := healthpb.NewHealthClient(conn)
+		for {
+			resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+			if err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING {
+				return nil
+			}
+			if err == nil {
+				err = fmt.Errorf("health status %v", resp.Status)
+			}
+
+			select {
+			case <-time.After(grpcHealthRetryInterval):
+			case <-ctx.Done():
+				return fmt.Errorf("gRPC server on %s not serving: %w", socketPath, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// serveHealth serves a gRPC health service on a Unix socket in a temporary
+// directory, initially NOT_SERVING, and returns the socket path
+func serveHealth(t *testing.T) (string, *health.Server) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "grpc.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+	return socket, healthServer
+}
+
+func TestGRPCHealthCheck(t *testing.T) {
+	socket, healthServer := serveHealth(t)
+	check := GRPCHealthCheck(socket)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := check(ctx); err == nil {
+		t.Error("check passed while the server was NOT_SERVING")
+	}
+
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := check(ctx); err != nil {
+		t.Errorf("check failed while the server was SERVING: %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := GRPCHealthCheck(filepath.Join(t.TempDir(), "missing.sock"))(ctx); err == nil {
+		t.Error("check passed for a socket that does not exist")
+	}
+}
+
+func TestDependentWaitsForServing(t *testing.T) {
+	socket, healthServer := serveHealth(t)
+	server := &Process{Name: "server", Command: "sleep", Args: []string{"30"}, ReadyCheck: GRPCHealthCheck(socket)}
+	client := &Process{Name: "client", Command: "sleep", Args: []string{"30"}, DependsOn: []string{"server"}}
+	pm := NewProcessManager([]*Process{server, client})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	const serveAfter = 800 * time.Millisecond
+	serving := make(chan time.Time, 1)
+	time.AfterFunc(serveAfter, func() {
+		serving <- time.Now()
+		healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	})
+
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	started := waitEvent(t, events, client.Name, EventStarted, 10*time.Second)
+	if servingAt := <-serving; started.Time.Before(servingAt) {
+		t.Error("client started before the server's health service reported SERVING")
+	}
+	if !pm.States()[0].Ready {
+		t.Error("server not marked ready")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// Default interval between ReadyCheck probes
+	defaultReadyInterval = 5 * time.Second
+	// Maximum time a single ReadyCheck probe may take
+	readyCheckTimeout = 3 * time.Second
+	// Default maximum time Start waits for a process to become ready, whether
+	// a critical process or the dependencies of the next one
+	defaultReadyTimeout = 60 * time.Second
+)
+
+// Healthy reports whether every critical process is healthy
+func (pm *ProcessManager) Healthy() bool {
+	detail := pm.HealthDetail()
+	for _, proc := range pm.processList() {
+		if proc.Critical && proc.enabled() && !detail[proc.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// HealthDetail returns the health of each process. A process is healthy if it is
+// running and, when it has a ReadyCheck, the last probe passed, or if it is a
+// task that has completed.
+func (pm *ProcessManager) HealthDetail() map[string]bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	detail := make(map[string]bool, len(pm.processes))
+	for _, proc := range pm.processes {
+		state := pm.states[proc.Name]
+		detail[proc.Name] = state.Completed || state.Running && (proc.ReadyCheck == nil || state.Ready)
+	}
+	return detail
+}
+
+// waitForDependencies blocks until every dependency of proc that has a
+// ReadyCheck reports ready. It returns false if a dependency is still not ready
+// after the ready timeout or the manager shuts down first.
+func (pm *ProcessManager) waitForDependencies(proc *Process) bool {
+	pending := pm.waitReady(proc.DependsOn, pm.readyTimeout)
+	if pending == "" {
+		return true
+	}
+	if pm.ctx.Err() == nil {
+		log.Printf("Process %s: dependency %s not ready after %v", proc.Name, pending, pm.readyTimeout)
+	}
+	return false
+}
+
+// waitReady blocks until every named process that has a ReadyCheck reports
+// ready. It returns "" once they all are, or the first one still pending when
+// timeout passes or the manager shuts down.
+func (pm *ProcessManager) waitReady(names []string, timeout time.Duration) string {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		pending := ""
+		pm.mu.Lock()
+		for _, name := range names {
+			if pm.readyCheckFor(name) && !pm.states[name].Ready {
+				pending = name
+				break
+			}
+		}
+		pm.mu.Unlock()
+
+		if pending == "" {
+			return ""
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return pending
+		case <-pm.ctx.Done():
+			return pending
+		}
+	}
+}
+
+// waitCriticalReady waits for a critical process that was just started to
+// report ready. Unlike waitReady it also watches the run that was started, and
+// fails as soon as that run exits instead of waiting out the ready timeout, since
+// a crashed process will not become ready. Still not being ready once the
+// timeout passes is only a warning.
+func (pm *ProcessManager) waitCriticalReady(proc *Process) error {
+	pm.mu.Lock()
+	pid := pm.states[proc.Name].PID
+	pm.mu.Unlock()
+
+	deadline := time.After(pm.readyTimeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		pm.mu.Lock()
+		state := *pm.states[proc.Name]
+		pm.mu.Unlock()
+
+		// A changed PID is a run that exited and was already restarted
+		// between two checks
+		if !state.Running || state.PID != pid {
+			return fmt.Errorf("critical process %s exited before becoming ready", proc.Name)
+		}
+		if state.Ready {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			log.Printf("Warning: critical process %s not ready after %v, starting the next process", proc.Name, pm.readyTimeout)
+			return nil
+		case <-pm.ctx.Done():
+			return fmt.Errorf("shutdown requested while starting process %s", proc.Name)
+		}
+	}
+}
+
+// readyCheckFor reports whether the named process has a ReadyCheck to wait
+// for. A process skipped by its EnabledIf condition has nothing to wait for.
+func (pm *ProcessManager) readyCheckFor(name string) bool {
+	for _, proc := range pm.processes {
+		if proc.Name == name {
+			return proc.ReadyCheck != nil && proc.enabled()
+		}
+	}
+	return false
+}
+
+// probeReadiness runs the process's ReadyCheck periodically until ctx is cancelled
+func (pm *ProcessManager) probeReadiness(ctx context.Context, proc *Process) {
+	interval := proc.ReadyInterval
+	if interval == 0 {
+		interval = defaultReadyInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		probeCtx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+		err := proc.ReadyCheck(probeCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		ready := err == nil
+		pm.updateState(proc.Name, func(s *ProcessState) {
+			if s.Ready != ready {
+				if ready {
+					log.Printf("Process %s: ready", proc.Name)
+				} else {
+					log.Printf("Process %s: readiness check failed: %v", proc.Name, err)
+				}
+			}
+			s.Ready = ready
+		})
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthy(t *testing.T) {
+	readyCheck := func(ctx context.Context) error { return nil }
+	tests := []struct {
+		name         string
+		critical     ProcessState
+		other        ProcessState
+		withReady    bool
+		wantHealthy  bool
+		wantCritical bool
+		wantOther    bool
+	}{
+		{"all running", ProcessState{Running: true}, ProcessState{Running: true}, false, true, true, true},
+		{"critical down", ProcessState{}, ProcessState{Running: true}, false, false, false, true},
+		{"non-critical down", ProcessState{Running: true}, ProcessState{}, false, true, true, false},
+		{"critical not ready", ProcessState{Running: true}, ProcessState{Running: true}, true, false, false, true},
+		{"critical ready", ProcessState{Running: true, Ready: true}, ProcessState{Running: true}, true, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			critical := &Process{Name: "server", Critical: true}
+			if tt.withReady {
+				critical.ReadyCheck = readyCheck
+			}
+			pm := NewProcessManager([]*Process{critical, {Name: "client"}})
+			pm.updateState("server", func(s *ProcessState) { s.Running, s.Ready = tt.critical.Running, tt.critical.Ready })
+			pm.updateState("client", func(s *ProcessState) { s.Running, s.Ready = tt.other.Running, tt.other.Ready })
+
+			if got := pm.Healthy(); got != tt.wantHealthy {
+				t.Errorf("Healthy() = %v, want %v", got, tt.wantHealthy)
+			}
+			detail := pm.HealthDetail()
+			if detail["server"] != tt.wantCritical || detail["client"] != tt.wantOther {
+				t.Errorf("HealthDetail() = %v, want server=%v client=%v", detail, tt.wantCritical, tt.wantOther)
+			}
+		})
+	}
+}
+
+func TestHealthzStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		running bool
+		want    int
+	}{
+		{"healthy", true, http.StatusOK},
+		{"unhealthy", false, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := NewProcessManager([]*Process{{Name: "server", Critical: true}})
+			pm.updateState("server", func(s *ProcessState) { s.Running = tt.running })
+
+			rec := httptest.NewRecorder()
+			newHTTPHandler(pm).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			if rec.Code != tt.want {
+				t.Errorf("GET /healthz = %d, want %d", rec.Code, tt.want)
+			}
+			var detail map[string]bool
+			if err := json.NewDecoder(rec.Body).Decode(&detail); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if detail["server"] != tt.running {
+				t.Errorf("body server = %v, want %v", detail["server"], tt.running)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Default limit on how long a PostStop command may run
+const defaultPostStopTimeout = 10 * time.Second
+
+// runPreStart runs the PreStart command of proc, if any, to completion
+func (pm *ProcessManager) runPreStart(proc *Process) error {
+	if len(proc.PreStart) == 0 {
+		return nil
+	}
+	return pm.runHook(pm.ctx, proc, "pre-start", proc.PreStart)
+}
+
+// runPostStop runs the PostStop command of proc, if any, after a run has
+// exited. It is given PostStopTimeout to finish, even during shutdown, and a
+// failure is only logged, so the restart loop always moves on.
+func (pm *ProcessManager) runPostStop(proc *Process) {
+	if len(proc.PostStop) == 0 {
+		return
+	}
+	timeout := proc.PostStopTimeout
+	if timeout == 0 {
+		timeout = defaultPostStopTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := pm.runHook(ctx, proc, "post-stop", proc.PostStop); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("post-stop timed out after %v: %w", timeout, err)
+		}
+		log.Printf("Process %s: %v", proc.Name, err)
+	}
+}
+
+// runHook runs argv, a hook of proc, to completion, killing it once ctx is
+// done. Its output is logged like the process's own. Like Command and Args,
+// argv is expanded with ExpandEnv, from the manager's environment the hook
+// runs with, subject to StrictEnv.
+func (pm *ProcessManager) runHook(ctx context.Context, proc *Process, hook string, argv []string) error {
+	command, args, err := expandCommand(&Process{Command: argv[0], Args: argv[1:], ExpandEnv: proc.ExpandEnv, StrictEnv: proc.StrictEnv}, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", hook, err)
+	}
+
+	log.Printf("Process %s: running %s %s", proc.Name, hook, command)
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdout = pm.newOutputWriter(proc, "stdout", os.Stdout)
+	cmd.Stderr = pm.newOutputWriter(proc, "stderr", os.Stderr)
+	// Output still held open by a background child must not outlast ctx
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %w", hook, command, err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreStartPreparesProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prepared")
+	proc := &Process{
+		Name:     "reader",
+		Command:  "cat",
+		Args:     []string{path},
+		PreStart: []string{"sh", "-c", "echo prepared by pre-start > " + path},
+		Kind:     KindTask,
+	}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+
+	if exited := waitEvent(t, events, proc.Name, EventExited, 5*time.Second); exited.ExitCode != 0 {
+		t.Fatalf("process exited with %d, want 0 after reading the prepared file", exited.ExitCode)
+	}
+	if lines := pm.logBuffer(proc.Name).tail(10); !slices.Contains(lines, "prepared by pre-start") {
+		t.Errorf("process output = %q, want the file written by the pre-start", lines)
+	}
+}
+
+func TestPreStartRunsBeforeRollingRestart(t *testing.T) {
+	dir := t.TempDir()
+	record := filepath.Join(dir, "record")
+	fail := filepath.Join(dir, "fail")
+	proc := &Process{
+		Name:           "server",
+		Command:        "sleep",
+		Args:           []string{"30"},
+		PreStart:       []string{"sh", "-c", "echo run >> " + record + "; test ! -e " + fail},
+		RestartDelay:   time.Hour,
+		Socket:         filepath.Join(dir, "grpc.sock"),
+		RollingRestart: true,
+	}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	first := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	runs := func() int {
+		data, _ := os.ReadFile(record)
+		return strings.Count(string(data), "run\n")
+	}
+
+	// The replacement is prepared like any start. It exits without listening,
+	// so the rollout fails after starting it.
+	marker := filepath.Join(dir, "replaced")
+	proc.Command, proc.Args = "touch", []string{marker}
+	if err := pm.Restart(proc.Name); err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Fatalf("Restart() = %v, want the replacement not ready", err)
+	}
+	if n := runs(); n != 2 {
+		t.Errorf("pre-start ran %d times, want once more for the replacement", n)
+	}
+
+	// A failed pre-start aborts the rollout before the replacement starts
+	os.Remove(marker)
+	if err := os.WriteFile(fail, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.Restart(proc.Name); err == nil || !strings.Contains(err.Error(), "pre-start sh failed") {
+		t.Fatalf("Restart() = %v, want the pre-start failure", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("replacement started despite the failed pre-start (stat: %v)", err)
+	}
+	if state, _ := stateOf(pm, proc.Name); state.PID != first.PID || !state.Running {
+		t.Errorf("state = PID %d, running %v, want PID %d still running", state.PID, state.Running, first.PID)
+	}
+}
+
+func TestPreStartFailureFailsStart(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "started")
+	proc := &Process{
+		Name:     "guarded",
+		Command:  "touch",
+		Args:     []string{marker},
+		PreStart: []string{"sh", "-c", "exit 3"},
+		Critical: true,
+	}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+
+	err := pm.startProcess(proc, true)
+	if err == nil || !strings.Contains(err.Error(), "pre-start sh failed: exit status 3") {
+		t.Fatalf("startProcess() = %v, want the pre-start failure", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("process ran despite the failed pre-start (stat: %v)", err)
+	}
+}
+
+func TestPostStopRunsAfterExit(t *testing.T) {
+	dir := t.TempDir()
+	lock := filepath.Join(dir, "lock")
+	record := filepath.Join(dir, "record")
+	proc := &Process{
+		Name:    "locker",
+		Command: "sh",
+		Args:    []string{"-c", "touch " + lock + "; echo ran >> " + record},
+		// Records whether the process had exited, leaving its lock behind
+		PostStop: []string{"sh", "-c", "test -e " + lock + " && rm " + lock + " && echo cleaned >> " + record},
+		Kind:     KindTask,
+	}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventExited, 5*time.Second)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(record)
+		if string(data) == "ran\ncleaned\n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("record = %q, want the post-stop to have run after the process", data)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if _, err := os.Stat(lock); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind (stat: %v)", err)
+	}
+}
+
+func TestPostStopTimeout(t *testing.T) {
+	proc := &Process{
+		Name:            "slow",
+		PostStop:        []string{"sleep", "30"},
+		PostStopTimeout: 100 * time.Millisecond,
+	}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+
+	start := time.Now()
+	pm.runPostStop(proc)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("post-stop held the restart loop for %v, want it killed after its 100ms timeout", elapsed)
+	}
+}
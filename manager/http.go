@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"multi-process-docker/internal/buildinfo"
+)
+
+// Number of log lines returned when ?tail is not given
+const defaultLogTail = 100
+
+// newHTTPHandler builds the manager's HTTP API
+func newHTTPHandler(pm *ProcessManager) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", pm.handleHealthz)
+	mux.HandleFunc("GET /status", pm.handleStatus)
+	mux.HandleFunc("GET /logs/{name}", pm.handleLogs)
+	mux.HandleFunc("POST /processes/{name}/restart", pm.handleRestart)
+	mux.Handle("GET /metrics", pm.metricsHandler())
+	if pm.ShutdownToken != "" {
+		mux.HandleFunc("POST /shutdown", pm.handleShutdown)
+	}
+	return mux
+}
+
+// handleHealthz reports 200 when the bundle is healthy and 503 otherwise
+func (pm *ProcessManager) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	if !pm.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, pm.HealthDetail())
+}
+
+// statusResponse is the body of GET /status
+type statusResponse struct {
+	Build     buildinfo.Info  `json:"build"`
+	Bundle    BundleReadiness `json:"bundle"`
+	Processes []ProcessState  `json:"processes"`
+}
+
+// handleStatus reports the manager's build, how long the bundle took to become
+// ready and the state of every managed process
+func (pm *ProcessManager) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, statusResponse{Build: buildinfo.Get(), Bundle: pm.BundleReadiness(), Processes: pm.States()})
+}
+
+// handleLogs returns the last ?tail=N output lines of a process (100 by default).
+// With ?follow=true it keeps streaming new lines as server-sent events.
+func (pm *ProcessManager) handleLogs(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	history := pm.logBuffer(name)
+	if history == nil {
+		http.Error(w, fmt.Sprintf("unknown process %q", name), http.StatusNotFound)
+		return
+	}
+
+	tail := defaultLogTail
+	if v := r.URL.Query().Get("tail"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "tail must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		tail = n
+	}
+
+	if r.URL.Query().Get("follow") != "true" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, line := range history.tail(tail) {
+			fmt.Fprintln(w, line)
+		}
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before reading the history so no line falls in between
+	lines, stop := history.follow()
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	for _, line := range history.tail(tail) {
+		fmt.Fprintf(w, "data: %s\n\n", line)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case line := <-lines:
+			fmt.Fprintf(w, "data: %s\n\n", line)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleRestart restarts a single process on demand
+func (pm *ProcessManager) handleRestart(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if pm.logBuffer(name) == nil {
+		http.Error(w, fmt.Sprintf("unknown process %q", name), http.StatusNotFound)
+		return
+	}
+
+	if err := pm.Restart(name); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleShutdown starts a graceful shutdown of the manager, returning once
+// teardown has begun. It requires the ShutdownToken as a bearer token.
+func (pm *ProcessManager) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(pm.ShutdownToken)) != 1 {
+		http.Error(w, "invalid shutdown token", http.StatusUnauthorized)
+		return
+	}
+
+	if pm.ctx.Err() != nil || !pm.remoteShutdown.CompareAndSwap(false, true) {
+		http.Error(w, "shutdown already in progress", http.StatusConflict)
+		return
+	}
+
+	log.Printf("Shutdown requested over HTTP by %s", r.RemoteAddr)
+	go pm.Shutdown()
+	<-pm.ctx.Done()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// writeJSON writes v as a JSON response body with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write HTTP response: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"multi-process-docker/internal/buildinfo"
+)
+
+func TestHandleLogs(t *testing.T) {
+	pm := NewProcessManager([]*Process{{Name: "server"}})
+	for i := 1; i <= 5; i++ {
+		pm.logs["server"].add(fmt.Sprintf("line %d", i))
+	}
+	srv := httptest.NewServer(newHTTPHandler(pm))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"default tail", "/logs/server", http.StatusOK, "line 1\nline 2\nline 3\nline 4\nline 5\n"},
+		{"tail", "/logs/server?tail=2", http.StatusOK, "line 4\nline 5\n"},
+		{"tail above history", "/logs/server?tail=50", http.StatusOK, "line 1\nline 2\nline 3\nline 4\nline 5\n"},
+		{"zero tail", "/logs/server?tail=0", http.StatusBadRequest, "tail must be a positive integer\n"},
+		{"negative tail", "/logs/server?tail=-1", http.StatusBadRequest, "tail must be a positive integer\n"},
+		{"bad tail", "/logs/server?tail=all", http.StatusBadRequest, "tail must be a positive integer\n"},
+		{"unknown process", "/logs/missing", http.StatusNotFound, "unknown process \"missing\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("GET %s = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+			}
+			if got := string(body); got != tt.wantBody {
+				t.Errorf("GET %s body = %q, want %q", tt.path, got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHandleStatus(t *testing.T) {
+	pm := NewProcessManager([]*Process{{Name: "server"}, {Name: "client"}})
+	pm.updateState("server", func(s *ProcessState) {
+		s.Running = true
+		s.PID = 42
+	})
+
+	rec := httptest.NewRecorder()
+	newHTTPHandler(pm).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	var status statusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if status.Build != buildinfo.Get() {
+		t.Errorf("GET /status build = %+v, want %+v", status.Build, buildinfo.Get())
+	}
+	states := status.Processes
+	if len(states) != 2 || states[0].Name != "server" || !states[0].Running || states[0].PID != 42 || states[1].Running {
+		t.Errorf("GET /status = %+v, want server running as PID 42 and client stopped", states)
+	}
+}
+
+func TestHandleShutdown(t *testing.T) {
+	proc := &Process{Name: "server", Command: "sleep", Args: []string{"30"}}
+	pm := NewProcessManager([]*Process{proc})
+	pm.ShutdownToken = "s3cret"
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+
+	handler := newHTTPHandler(pm)
+	post := func(auth string) int {
+		req := httptest.NewRequest(http.MethodPost, "/shutdown", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := post(""); code != http.StatusUnauthorized {
+		t.Errorf("POST /shutdown without a token = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := post("Bearer wrong"); code != http.StatusUnauthorized {
+		t.Errorf("POST /shutdown with a wrong token = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if pm.ctx.Err() != nil {
+		t.Fatal("unauthorized request started a shutdown")
+	}
+
+	if code := post("Bearer s3cret"); code != http.StatusAccepted {
+		t.Fatalf("POST /shutdown = %d, want %d", code, http.StatusAccepted)
+	}
+	if pm.ctx.Err() == nil {
+		t.Error("manager not shutting down after POST /shutdown returned")
+	}
+	if code := post("Bearer s3cret"); code != http.StatusConflict {
+		t.Errorf("second POST /shutdown = %d, want %d", code, http.StatusConflict)
+	}
+
+	waitEvent(t, events, proc.Name, EventExited, 10*time.Second)
+}
+
+func TestHandleShutdownDisabledWithoutToken(t *testing.T) {
+	pm := NewProcessManager([]*Process{{Name: "server"}})
+	rec := httptest.NewRecorder()
+	newHTTPHandler(pm).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/shutdown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("POST /shutdown without a configured token = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if pm.ctx.Err() != nil {
+		t.Error("manager shutting down although the endpoint is disabled")
+	}
+}
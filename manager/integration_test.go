@@ -0,0 +1,68 @@
+//go:build integration
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestBundle runs the manager with the real server and client binaries and
+// checks the bundle comes up, serves a request and shuts down cleanly.
+// Run with: go test -tags integration ./manager
+func TestBundle(t *testing.T) {
+	dir := t.TempDir()
+	manager := buildBinary(t, dir, "./manager")
+	server := buildBinary(t, dir, "./server")
+	client := buildBinary(t, dir, "./client")
+
+	socket := filepath.Join(dir, "grpc.sock")
+	config := writeConfig(t, dir, "bundle.json", fmt.Sprintf(`{"processes": [
+		{"name": "grpc-server", "command": %q, "args": ["-socket", %q], "critical": true, "grpcHealthSocket": %q},
+		{"name": "grpc-client", "command": %q, "args": ["-socket", %q], "dependsOn": ["grpc-server"]}
+	]}`, server, socket, socket, client, socket))
+
+	var out lockedBuffer
+	cmd := exec.Command(manager, "-config", config)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Own process group, so a failed test can kill the whole bundle
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	// Stops the bundle if the test fails before it shuts down
+	defer syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+
+	waitForOutput(t, &out, "gRPC Server is ready to accept connections", 30*time.Second)
+	waitForOutput(t, &out, "Response: Hello, Docker Client!", 30*time.Second)
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("signal manager: %v", err)
+	}
+	select {
+	case err := <-exited:
+		if err != nil {
+			t.Fatalf("manager exited with %v:\n%s", err, out.String())
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatalf("manager still running 30s after SIGTERM:\n%s", out.String())
+	}
+
+	for _, want := range []string{"gRPC Server stopped", "Process Manager exited"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("no %q in output after shutdown:\n%s", want, out.String())
+		}
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("socket %s left behind after shutdown (stat: %v)", socket, err)
+	}
+}
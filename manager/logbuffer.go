@@ -0,0 +1,83 @@
+package main
+
+import "sync"
+
+const (
+	// Number of recent output lines retained per process
+	logHistoryLines = 1000
+	// Lines queued for a follower before new lines are dropped
+	logFollowBuffer = 100
+)
+
+// logBuffer retains a bounded history of a process's recent output lines
+// and fans new lines out to followers
+type logBuffer struct {
+	mu        sync.Mutex
+	lines     []string
+	next      int
+	full      bool
+	followers map[chan string]struct{}
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{
+		lines:     make([]string, size),
+		followers: make(map[chan string]struct{}),
+	}
+}
+
+// add records a line and forwards it to followers without blocking
+func (b *logBuffer) add(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+
+	for ch := range b.followers {
+		select {
+		case ch <- line:
+		default:
+			// Follower not keeping up, drop the line
+		}
+	}
+}
+
+// tail returns up to n of the most recent lines, oldest first
+func (b *logBuffer) tail(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.lines)
+	}
+	if n > count || n <= 0 {
+		n = count
+	}
+
+	result := make([]string, 0, n)
+	for i := n; i > 0; i-- {
+		idx := (b.next - i + len(b.lines)) % len(b.lines)
+		result = append(result, b.lines[idx])
+	}
+	return result
+}
+
+// follow subscribes to new lines until the returned cancel function is called
+func (b *logBuffer) follow() (<-chan string, func()) {
+	ch := make(chan string, logFollowBuffer)
+
+	b.mu.Lock()
+	b.followers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.followers, ch)
+		b.mu.Unlock()
+	}
+}
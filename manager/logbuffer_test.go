@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLogBufferTail(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		added int
+		n     int
+		want  []string
+	}{
+		{"empty", 3, 0, 0, []string{}},
+		{"all of a partial buffer", 3, 2, 0, []string{"line 1", "line 2"}},
+		{"more than retained", 3, 2, 5, []string{"line 1", "line 2"}},
+		{"last of a partial buffer", 3, 2, 1, []string{"line 2"}},
+		{"exactly full", 3, 3, 0, []string{"line 1", "line 2", "line 3"}},
+		{"wrapped", 3, 5, 0, []string{"line 3", "line 4", "line 5"}},
+		{"last of a wrapped buffer", 3, 5, 2, []string{"line 4", "line 5"}},
+		{"negative n", 3, 4, -1, []string{"line 2", "line 3", "line 4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newLogBuffer(tt.size)
+			for i := 1; i <= tt.added; i++ {
+				b.add(fmt.Sprintf("line %d", i))
+			}
+			if got := b.tail(tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tail(%d) = %q, want %q", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogBufferFollow(t *testing.T) {
+	b := newLogBuffer(10)
+	b.add("before")
+
+	lines, cancel := b.follow()
+	b.add("after")
+	if got := <-lines; got != "after" {
+		t.Errorf("follower got %q, want %q", got, "after")
+	}
+
+	// A follower that falls behind loses lines rather than blocking add
+	for i := 0; i < logFollowBuffer+10; i++ {
+		b.add("flood")
+	}
+	if len(lines) != logFollowBuffer {
+		t.Errorf("follower has %d lines queued, want %d", len(lines), logFollowBuffer)
+	}
+
+	cancel()
+	for len(lines) > 0 {
+		<-lines
+	}
+	b.add("cancelled")
+	if len(lines) != 0 {
+		t.Error("cancelled follower still receives lines")
+	}
+}
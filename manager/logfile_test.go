@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogFileCombinesOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manager.log")
+	file, err := openRotatingFile(path, defaultLogFileMaxSize, defaultLogFileKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	log.SetOutput(io.MultiWriter(os.Stderr, file))
+	defer log.SetOutput(os.Stderr)
+
+	proc := &Process{Name: "greeter", Command: "sh", Args: []string{"-c", "echo hello from the child; exec sleep 30"}}
+	pm := NewProcessManager([]*Process{proc})
+	pm.LogFile = file
+	pm.StartWait = 10 * time.Millisecond
+	defer pm.Shutdown()
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	var content string
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content = string(data)
+		if strings.Contains(content, "[greeter] hello from the child") || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	for _, want := range []string{"Starting process: greeter", "[greeter] hello from the child\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("log file missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manager.log")
+	file, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// Four 6-byte lines, two files' worth over the limit
+	for i := 1; i <= 4; i++ {
+		fmt.Fprintf(file, "line%d\n", i)
+	}
+
+	for name, want := range map[string]string{
+		path:        "line4\n",
+		path + ".1": "line3\n",
+		path + ".2": "line2\n",
+	} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Errorf("read %s: %v", filepath.Base(name), err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), data, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 kept beyond the 2 generations (stat: %v)", filepath.Base(path), err)
+	}
+}
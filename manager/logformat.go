@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Supported log output formats
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logEntry is a single line of JSON log output
+type logEntry struct {
+	Time    string `json:"time"`
+	Process string `json:"process,omitempty"`
+	Stream  string `json:"stream,omitempty"`
+	Message string `json:"message"`
+	// Extra fields added after the standard ones. Labels named like a
+	// standard field are left out.
+	Labels map[string]string `json:"-"`
+}
+
+// reservedLogFields are the JSON fields of logEntry, which labels cannot replace
+var reservedLogFields = map[string]bool{"time": true, "process": true, "stream": true, "message": true}
+
+// encode returns the entry as a newline-terminated JSON object
+func (e logEntry) encode() []byte {
+	if e.Time == "" {
+		e.Time = time.Now().Format(time.RFC3339Nano)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		// Marshalling only strings cannot fail, but never lose the line
+		return []byte(e.Message + "\n")
+	}
+	return append(appendLabels(data, e.Labels), '\n')
+}
+
+// appendLabels adds labels, in key order, as fields of the JSON object in data
+func appendLabels(data []byte, labels map[string]string) []byte {
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		if reservedLogFields[key] {
+			continue
+		}
+		// Strings always marshal
+		name, _ := json.Marshal(key)
+		value, _ := json.Marshal(labels[key])
+		data = append(data[:len(data)-1], ',')
+		data = append(append(append(data, name...), ':'), value...)
+		data = append(data, '}')
+	}
+	return data
+}
+
+// jsonLogWriter re-encodes the manager's own log lines as JSON objects.
+// It is installed with log.SetOutput and relies on the logger issuing one Write per entry.
+type jsonLogWriter struct {
+	dest io.Writer
+}
+
+func (w *jsonLogWriter) Write(p []byte) (int, error) {
+	entry := logEntry{Message: strings.TrimSuffix(string(p), "\n")}
+	if _, err := w.dest.Write(entry.encode()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestJSONOutputLabels(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	pw := &prefixedWriter{
+		dest:    w,
+		json:    true,
+		process: "api",
+		stream:  "stdout",
+		labels:  map[string]string{"service": "greeter", "tier": "backend", "message": "ignored"},
+	}
+	fmt.Fprint(pw, "first\nsecond\n")
+	w.Close()
+
+	scanner := bufio.NewScanner(r)
+	var messages []string
+	for scanner.Scan() {
+		var line map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", scanner.Text(), err)
+		}
+		for key, want := range map[string]string{"process": "api", "stream": "stdout", "service": "greeter", "tier": "backend"} {
+			if line[key] != want {
+				t.Errorf("line %q: %s = %q, want %q", scanner.Text(), key, line[key], want)
+			}
+		}
+		messages = append(messages, line["message"])
+	}
+	// A label cannot replace a standard field
+	if want := []string{"first", "second"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("messages = %q, want %q", messages, want)
+	}
+}
@@ -1,55 +1,381 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"multi-process-docker/internal/buildinfo"
+)
+
+// ProcessKind is what a process is expected to do once started
+type ProcessKind string
+
+const (
+	// A daemon runs until shutdown and is restarted whenever it exits
+	KindDaemon ProcessKind = "daemon"
+	// A task runs once, and is completed by exiting successfully
+	KindTask ProcessKind = "task"
 )
 
 // Process represents a managed process
 type Process struct {
-	Name    string
+	Name string
+	// Command and Args may reference environment variables as ${VAR} or $VAR
 	Command string
 	Args    []string
+	// Optional command and arguments run to completion before every start of
+	// the process, restarts included, e.g. to prepare a data directory. It runs
+	// as the manager's own user, and its failure fails that start attempt.
+	PreStart []string
+	// Optional command and arguments run after every run of the process exits,
+	// before any restart, e.g. to remove a lock file. It runs as the manager's
+	// own user; a failure is only logged.
+	PostStop []string
+	// Limit on how long PostStop may run before it is killed (defaults to 10s)
+	PostStopTimeout time.Duration
+	// If true, ${VAR} and $VAR references in Command and Args are expanded
+	// from the process's environment, EnvFromFile included, and those in
+	// PreStart and PostStop from the manager's. $$ stands for a literal $.
+	ExpandEnv bool
+	// If true, a variable referenced by an expanded Command or Args that is
+	// not set, or an EnvFromFile file that cannot be read, fails the start
+	// instead of expanding to an empty string or leaving the variable unset
+	StrictEnv bool
+	// Environment variables set from the contents of files, by variable name,
+	// e.g. {"PASSWORD": "/run/secrets/pw"} for secrets mounted as files. The
+	// files are read on every start, and a trailing newline is dropped.
+	EnvFromFile map[string]string
+	// Condition on the manager's environment for running the process at all:
+	// "VAR" requires VAR to be set and not empty, "VAR=value" requires that
+	// value. Start skips a process whose condition does not hold.
+	EnabledIf string
 	// If true, this process must start successfully before starting the next process
 	Critical bool
+	// Names of processes that must be started before this one
+	DependsOn []string
+	// Whether the process is a daemon that runs for as long as the manager does
+	// (the default) or a task that runs once. Start waits for a task to exit
+	// successfully before starting the next process, and a completed task is
+	// not restarted.
+	Kind ProcessKind
+	// Same as a Kind of KindTask, kept for older configs
+	WaitForExit bool
 	// Restart delay after failure
 	RestartDelay time.Duration
+	// If set, each consecutive restart after a run shorter than MinStableRun
+	// doubles the restart delay, up to this maximum. Only a run lasting
+	// MinStableRun resets it, whatever its exit code.
+	RestartBackoffMax time.Duration
+	// More restarts than this within RestartAlertWindow (defaults to 1m) raise a
+	// flapping alert: an ALERT log line and a Flapping event (0 disables it)
+	RestartAlertThreshold int
+	RestartAlertWindow    time.Duration
+	// Optional probe reporting whether the running process is ready to serve
+	ReadyCheck func(ctx context.Context) error
+	// Unix socket whose gRPC health service is the ReadyCheck, if it was set up with GRPCHealthCheck
+	GRPCHealthSocket string
+	// File whose presence is the ReadyCheck, if it was set up with FileReadyCheck
+	ReadyFile string
+	// Interval between ReadyCheck probes (defaults to 5s)
+	ReadyInterval time.Duration
+	// Delay before this process is launched during Start
+	StartDelay time.Duration
+	// Overrides ProcessManager.StartWait and CriticalWait for this process (0 uses those)
+	StartWait    time.Duration
+	CriticalWait time.Duration
+	// An initial run of a critical process that exits sooner than this is a
+	// start failure, and a shorter run does not reset RestartBackoffMax (defaults to 2s)
+	MinStableRun time.Duration
+	// CPU limit in cores, e.g. 0.5 (Linux cgroup v2 only, 0 means unlimited)
+	CPUQuota float64
+	// Memory limit in MiB (Linux cgroup v2 only, 0 means unlimited)
+	MemoryLimitMB int
+	// Data written to the process's stdin, re-fed on every restart
+	StdinData []byte
+	// Name of another process whose stdout is piped into this process's stdin
+	// instead of being logged. The pipe is re-established when either restarts.
+	StdinFrom string
+	// Maximum duration of a single run before the process is terminated (0 means unlimited)
+	MaxRuntime time.Duration
+	// Regular expression matched against each line of output; matching lines
+	// are dropped, or with LogFilterKeep they are the only ones kept
+	LogFilter     string
+	LogFilterKeep bool
+	// Fields added to every line of output in JSON log mode, e.g. {"service": "api"}
+	LogLabels map[string]string
+	// Signals sent in turn to stop the process, each followed by a wait for it
+	// to exit, before escalating to SIGKILL (defaults to SIGTERM with 30s)
+	StopSignals []StopStep
+	// How long a running task is left to complete on shutdown before its stop
+	// sequence begins (defaults to 10s, at most half the stop sequence; daemons
+	// are stopped straight away). The drain is taken out of the stop sequence,
+	// so drain and stop together last no longer than the stop sequence's
+	// waits: by default 10s to complete, then SIGTERM and 20s to exit.
+	DrainTimeout time.Duration
+	// Processes with a higher priority are stopped first on shutdown. Within a
+	// priority, dependents are stopped before the processes they depend on.
+	ShutdownPriority int
+	// User and group to run the process as, by name or numeric id (Unix only).
+	// Without a Group the user's primary group is used.
+	User  string
+	Group string
+	// Unix socket the process listens on, required for RollingRestart
+	Socket string
+	// If true, Restart brings up a replacement on a staging socket and swaps it
+	// into Socket before stopping the running instance, so the socket never goes away
+	RollingRestart bool
+	// Files whose changes restart the process, e.g. the config of a sidecar.
+	// A burst of changes restarts it once, after they have settled.
+	WatchPaths []string
+	// Signal sent on a change to WatchPaths instead of restarting, for a
+	// process that reloads its config in place, e.g. SIGHUP
+	WatchSignal syscall.Signal
 }
 
+const (
+	// Default delay before a process that exited is started again
+	defaultRestartDelay = 5 * time.Second
+	// Default minimum run time for an initial start to be considered successful
+	defaultMinStableRun = 2 * time.Second
+	// Time between SIGTERM and SIGKILL when a process exceeds its MaxRuntime
+	maxRuntimeKillGrace = 5 * time.Second
+	// Default time Start watches a noncritical process for an early failure
+	defaultStartWait = 500 * time.Millisecond
+	// Default pause after starting a critical process without a ReadyCheck
+	defaultCriticalWait = 1 * time.Second
+)
+
 // ProcessManager manages multiple processes with restart capabilities
 type ProcessManager struct {
+	// Delay inserted between each process launch during Start
+	Stagger time.Duration
+	// How long Start watches a newly launched noncritical process for an early
+	// failure before moving on to the next one
+	StartWait time.Duration
+	// Pause after a critical process has survived its minimum stable run before
+	// Start moves on. A critical process with a ReadyCheck is instead waited
+	// for until it reports ready.
+	CriticalWait time.Duration
+	// Output format for child output and the shutdown summary ("text" or "json")
+	LogFormat string
+	// Optional file receiving a copy of all process output and of the shutdown
+	// summary, as written to the console. Set before Start.
+	LogFile io.Writer
+	// Bearer token required by POST /shutdown, which is disabled when empty
+	ShutdownToken string
+	// Memory limit in bytes for the manager and its processes. Above it,
+	// noncritical processes are stopped until usage recovers (0 disables it).
+	MaxMemory uint64
+	// Interval between resource usage samples of the running processes, which
+	// are logged and exported as metrics (0 disables it; Linux only)
+	UsageInterval time.Duration
+	// If true, a process that restarts has the processes that depend on it
+	// restarted too, once it is ready again
+	RestartDependentsOnRestart bool
+	// If true, config warnings, such as a process others depend on being
+	// noncritical without a readiness check, fail Start instead of being logged
+	Strict bool
+	// If true, Run returns once every process has stopped for good, as in a
+	// batch of tasks, failing unless all of them completed
+	ExitWhenDone bool
+	// Receive every process event, each in its own goroutine. Set before Start.
+	Notifiers []Notifier
+
 	processes []*Process
 	ctx       context.Context
 	cancel    context.CancelFunc
 	wg        sync.WaitGroup
 	mu        sync.Mutex
-	running   map[string]*exec.Cmd
+	running   map[string]*waitedHandle
+	runner    commandRunner
+	events    chan ProcessEvent
+	states    map[string]*ProcessState
+	logs      map[string]*logBuffer
+	// Processes stopped by Restart that should come back without the restart delay
+	restartRequested map[string]bool
+	// Processes with a rolling restart in progress
+	rolling map[string]bool
+	// Instances started by a rolling restart, adopted by the restart loop in
+	// place of launching a new run
+	replacements map[string]*waitedHandle
+	// Run-to-completion processes that completed before the manager re-executed
+	completed map[string]bool
+	// Receives the error of the first critical process that gives up, ending Run
+	failed chan error
+	// Time-to-ready of the bundle, recorded by watchBundleReady
+	bundle             BundleReadiness
+	bundleReadyTimeout time.Duration
+	// Longest wait for a process to report ready during Start
+	readyTimeout time.Duration
+	// Memory usage source and check interval of the memory watchdog
+	memoryUsage    func() (uint64, error)
+	memoryInterval time.Duration
+	// How long WatchPaths must stay unchanged before the process is restarted
+	watchDebounce time.Duration
+	// Processes stopped by the memory watchdog, each with a channel closed
+	// when it may restart, and the order they were stopped in
+	shed      map[string]chan struct{}
+	shedOrder []string
+	// Latest resource usage sample of each running process, by name
+	usage map[string]ResourceUsage
+	// Recent restart times of processes with a RestartAlertThreshold, and
+	// whether each is currently over it
+	restartTimes map[string][]time.Time
+	flapping     map[string]bool
+	// Pipes from processes named in a StdinFrom to their consumers, by producer
+	stdinPipes map[string]*stdinPipe
+	// Restart loop of each process, so RemoveProcess can end it
+	loops map[string]*processLoop
+
+	shutdownOnce sync.Once
+	// Set by the first shutdown requested over HTTP
+	remoteShutdown atomic.Bool
 }
 
 // NewProcessManager creates a new process manager
 func NewProcessManager(processes []*Process) *ProcessManager {
-	ctx, cancel := context.WithCancel(context.Background())
-	return &ProcessManager{
+	return NewProcessManagerWithContext(context.Background(), processes)
+}
+
+// NewProcessManagerWithContext creates a process manager whose lifetime is bound to ctx.
+// Cancelling ctx shuts down all managed processes.
+func NewProcessManagerWithContext(parent context.Context, processes []*Process) *ProcessManager {
+	ctx, cancel := context.WithCancel(parent)
+	states := make(map[string]*ProcessState, len(processes))
+	logs := make(map[string]*logBuffer, len(processes))
+	stdinPipes := make(map[string]*stdinPipe)
+	for _, proc := range processes {
+		states[proc.Name] = &ProcessState{Name: proc.Name, LastExitCode: -1}
+		logs[proc.Name] = newLogBuffer(logHistoryLines)
+		if proc.StdinFrom != "" {
+			stdinPipes[proc.StdinFrom] = &stdinPipe{}
+		}
+	}
+
+	pm := &ProcessManager{
+		StartWait:    defaultStartWait,
+		CriticalWait: defaultCriticalWait,
+
 		processes: processes,
 		ctx:       ctx,
 		cancel:    cancel,
-		running:   make(map[string]*exec.Cmd),
+		running:   make(map[string]*waitedHandle),
+		runner:    execRunner{},
+		events:    make(chan ProcessEvent, eventBufferSize),
+		states:    states,
+		logs:      logs,
+
+		restartRequested: make(map[string]bool),
+		rolling:          make(map[string]bool),
+		replacements:     make(map[string]*waitedHandle),
+		completed:        make(map[string]bool),
+		failed:           make(chan error, 1),
+
+		bundleReadyTimeout: defaultBundleReadyTimeout,
+		readyTimeout:       defaultReadyTimeout,
+		memoryInterval:     defaultMemoryInterval,
+		watchDebounce:      defaultWatchDebounce,
+		shed:               make(map[string]chan struct{}),
+		restartTimes:       make(map[string][]time.Time),
+		flapping:           make(map[string]bool),
+		stdinPipes:         stdinPipes,
+		loops:              make(map[string]*processLoop),
 	}
+	pm.memoryUsage = pm.processTreeRSS
+
+	// Turn cancellation of the parent into a full graceful shutdown
+	if parent.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			if parent.Err() != nil {
+				pm.Shutdown()
+			}
+		}()
+	}
+
+	return pm
 }
 
 // Start begins managing all processes
 func (pm *ProcessManager) Start() error {
-	log.Println("Process Manager starting...")
+	log.Printf("Process Manager %s starting...", buildinfo.Get())
+
+	order, err := startOrder(pm.processList())
+	if err != nil {
+		return err
+	}
+	if problems := criticalDependencyProblems(order); len(problems) > 0 {
+		return errors.Join(problems...)
+	}
+	if warnings := ungatedWarnings(order); len(warnings) > 0 {
+		if pm.Strict {
+			return errors.Join(warnings...)
+		}
+		for _, warning := range warnings {
+			log.Printf("Warning: %v", warning)
+		}
+	}
+	log.Printf("Start order: %s", describeStartOrder(order))
+	go pm.watchBundleReady(time.Now())
+	if pm.MaxMemory > 0 {
+		go pm.watchMemory()
+	}
+	if pm.UsageInterval > 0 {
+		if usageSupported {
+			go pm.sampleUsage()
+		} else {
+			log.Println("Warning: resource usage sampling is only supported on Linux, not sampling")
+		}
+	}
+
+	// Start processes in dependency order
+	for i, proc := range order {
+		if !proc.enabled() {
+			log.Printf("Process %s: skipped, enabledIf %q does not hold", proc.Name, proc.EnabledIf)
+			continue
+		}
+
+		pm.mu.Lock()
+		completed := pm.completed[proc.Name]
+		pm.mu.Unlock()
+		if completed {
+			log.Printf("Process %s: completed before the manager re-executed, not running it again", proc.Name)
+			continue
+		}
+
+		delay := proc.StartDelay
+		if i > 0 {
+			delay += pm.Stagger
+		}
+		if delay > 0 {
+			log.Printf("Waiting %v before starting process %s", delay, proc.Name)
+			if !pm.sleep(delay) {
+				return fmt.Errorf("shutdown requested while starting process %s", proc.Name)
+			}
+		}
+
+		if len(proc.DependsOn) > 0 && !pm.waitForDependencies(proc) {
+			if pm.ctx.Err() != nil {
+				return fmt.Errorf("shutdown requested while starting process %s", proc.Name)
+			}
+			log.Printf("Warning: starting process %s before its dependencies are ready", proc.Name)
+		}
 
-	// Start processes in order
-	for _, proc := range pm.processes {
 		if err := pm.startProcess(proc, true); err != nil {
 			if proc.Critical {
 				return fmt.Errorf("failed to start critical process %s: %w", proc.Name, err)
@@ -57,9 +383,11 @@ func (pm *ProcessManager) Start() error {
 			log.Printf("Warning: failed to start process %s: %v", proc.Name, err)
 		}
 
-		// If critical, wait a bit to ensure it's stable
+		// Let a critical process settle, or become ready, before moving on
 		if proc.Critical {
-			time.Sleep(1 * time.Second)
+			if err := pm.waitCritical(proc); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -67,149 +395,528 @@ func (pm *ProcessManager) Start() error {
 	return nil
 }
 
+// isTask reports whether proc runs once rather than being kept running
+func (proc *Process) isTask() bool {
+	return proc.Kind == KindTask || proc.WaitForExit
+}
+
 // startProcess starts a single process and monitors it
 func (pm *ProcessManager) startProcess(proc *Process, initial bool) error {
+	minStableRun := proc.MinStableRun
+	if minStableRun == 0 {
+		minStableRun = defaultMinStableRun
+	}
+
+	// Reports a failed first run back to the initial caller
+	var result chan error
+	if initial {
+		result = make(chan error, 1)
+	}
+
+	// The loop ends with the manager, or earlier if RemoveProcess cancels it
+	ctx, cancel := context.WithCancel(pm.ctx)
+	loop := &processLoop{cancel: cancel, done: make(chan struct{})}
+	pm.mu.Lock()
+	pm.loops[proc.Name] = loop
+	pm.mu.Unlock()
+
+	if len(proc.WatchPaths) > 0 {
+		go pm.watchPaths(ctx, proc)
+	}
+
 	pm.wg.Add(1)
 
-	go func() {
+	go func(report chan<- error) {
 		defer pm.wg.Done()
+		defer close(loop.done)
+		defer cancel()
+		defer pm.discardReplacement(proc)
 
+		// Consecutive runs shorter than minStableRun, which back off restarts
+		unstable := 0
+		// Runs started so far; any after the first is a restart
+		runs := 0
 		for {
 			select {
-			case <-pm.ctx.Done():
+			case <-ctx.Done():
 				log.Printf("Process %s: shutdown requested", proc.Name)
 				return
 			default:
 			}
 
-			log.Printf("Starting process: %s", proc.Name)
-
-			cmd := exec.CommandContext(pm.ctx, proc.Command, proc.Args...)
-			cmd.Stdout = &prefixedWriter{prefix: fmt.Sprintf("[%s] ", proc.Name), dest: os.Stdout}
-			cmd.Stderr = &prefixedWriter{prefix: fmt.Sprintf("[%s] ", proc.Name), dest: os.Stderr}
+			// A rolling restart has already started this run's instance
+			handle := pm.takeReplacement(proc.Name)
+			if handle != nil {
+				log.Printf("Process %s: switching to replacement with PID: %d", proc.Name, handle.Pid())
+			} else {
+				log.Printf("Starting process: %s", proc.Name)
+				var cmd *exec.Cmd
+				err := pm.runPreStart(proc)
+				if err == nil {
+					cmd, err = pm.command(proc)
+				}
+				var started processHandle
+				if err == nil {
+					started, err = pm.runner.Start(cmd)
+					closeStdinPipe(proc, cmd)
+				}
+				if err != nil {
+					log.Printf("Process %s: failed to start: %v", proc.Name, err)
 
-			// Store the running command
-			pm.mu.Lock()
-			pm.running[proc.Name] = cmd
-			pm.mu.Unlock()
+					// Critical and run-to-completion processes fail the startup
+					// outright; anything else reports the failure and keeps
+					// retrying, since its binary may not be in place yet.
+					if report != nil {
+						report <- err
+						report = nil
+						if proc.Critical || proc.isTask() {
+							pm.giveUp(proc, 0, -1, err)
+							return
+						}
+					}
 
-			if err := cmd.Start(); err != nil {
-				log.Printf("Process %s: failed to start: %v", proc.Name, err)
+					unstable++
+					if !pm.waitBeforeRestart(ctx, proc, 0, unstable) {
+						return
+					}
+					continue
+				}
+				handle = newWaitedHandle(started)
+			}
 
-				pm.mu.Lock()
-				delete(pm.running, proc.Name)
+			// Store the running process, unless shutdown began while it was
+			// starting: shutdown only stops the runs it finds stored, so this
+			// one would be left running with nothing to stop it
+			pm.mu.Lock()
+			if ctx.Err() != nil {
 				pm.mu.Unlock()
+				log.Printf("Process %s: started during shutdown, stopping it", proc.Name)
+				stopProcess(proc, handle)
+				return
+			}
+			pm.running[proc.Name] = handle
+			pm.mu.Unlock()
 
-				if initial {
-					return
-				}
+			pid := handle.Pid()
+			startedAt := time.Now()
+			log.Printf("Process %s started with PID: %d", proc.Name, pid)
 
-				// Wait before restarting
-				delay := proc.RestartDelay
-				if delay == 0 {
-					delay = 5 * time.Second
-				}
+			cg := pm.applyLimits(proc, pid)
+			stopRuntimeLimit := limitRuntime(proc, handle)
+			pm.updateState(proc.Name, func(s *ProcessState) {
+				s.PID = pid
+				s.Running = true
+				s.Ready = false
+				s.StartedAt = startedAt
+			})
+			pm.emit(proc.Name, EventStarted, pid, -1)
 
-				select {
-				case <-time.After(delay):
-					continue
-				case <-pm.ctx.Done():
-					return
-				}
+			runs++
+			if runs > 1 && pm.RestartDependentsOnRestart {
+				go pm.restartDependents(ctx, proc)
 			}
 
-			log.Printf("Process %s started with PID: %d", proc.Name, cmd.Process.Pid)
+			// Probe readiness for as long as this run lasts
+			probeCtx, stopProbe := context.WithCancel(ctx)
+			if proc.ReadyCheck != nil {
+				go pm.probeReadiness(probeCtx, proc)
+			}
 
 			// Wait for process to complete
-			err := cmd.Wait()
+			err := handle.Wait()
+			timedOut := stopRuntimeLimit()
+			stopProbe()
+			if cg != nil {
+				if err := cg.remove(); err != nil {
+					log.Printf("Process %s: %v", proc.Name, err)
+				}
+			}
+			exitCode := handle.ExitCode()
+			reason, signal := classifyExit(err, timedOut)
+			pm.updateState(proc.Name, func(s *ProcessState) {
+				s.Running = false
+				s.Ready = false
+				s.Uptime += time.Since(startedAt)
+				s.LastExitCode = exitCode
+				s.TimedOut = timedOut
+				s.ExitReason = reason
+				s.ExitSignal = signal
+			})
+			pm.emit(proc.Name, EventExited, pid, exitCode)
 
 			pm.mu.Lock()
 			delete(pm.running, proc.Name)
 			pm.mu.Unlock()
 
+			pm.runPostStop(proc)
+
+			// Tasks are done after a single run
+			if proc.isTask() {
+				if err != nil {
+					err = fmt.Errorf("exited before completing: %w", err)
+					log.Printf("Process %s: %v", proc.Name, err)
+					pm.giveUp(proc, pid, exitCode, err)
+				} else {
+					log.Printf("Process %s: completed", proc.Name)
+					pm.updateState(proc.Name, func(s *ProcessState) { s.Completed = true })
+				}
+				if report != nil {
+					report <- err
+				}
+				return
+			}
+
+			// A first run that did not survive the stability window counts as a failed start
+			if report != nil {
+				if runtime := time.Since(startedAt); runtime < minStableRun {
+					report <- fmt.Errorf("exited after %v (minimum stable run is %v): %v", runtime.Round(time.Millisecond), minStableRun, err)
+				}
+				report = nil
+			}
+
 			// Check if shutdown was requested
 			select {
-			case <-pm.ctx.Done():
+			case <-ctx.Done():
 				log.Printf("Process %s: exited during shutdown", proc.Name)
 				return
 			default:
 			}
 
 			if err != nil {
-				log.Printf("Process %s: exited with error: %v", proc.Name, err)
+				log.Printf("Process %s: exited with error (%s): %v", proc.Name, reason, err)
 			} else {
 				log.Printf("Process %s: exited normally", proc.Name)
 			}
 
-			// Restart after delay
-			delay := proc.RestartDelay
-			if delay == 0 {
-				delay = 5 * time.Second
+			// A quick clean exit is no sign of health: a process crash-looping
+			// with exit code 0 must back off all the same. A run ended by the
+			// memory watchdog says nothing either way.
+			if !pm.isShed(proc.Name) {
+				if time.Since(startedAt) < minStableRun {
+					unstable++
+				} else {
+					unstable = 0
+				}
 			}
-
-			log.Printf("Process %s: restarting in %v...", proc.Name, delay)
-
-			select {
-			case <-time.After(delay):
-				// Continue to restart
-			case <-pm.ctx.Done():
+			if !pm.waitBeforeRestart(ctx, proc, pid, unstable) {
 				return
 			}
 		}
-	}()
+	}(result)
 
-	// Don't return immediately on first start
-	if initial {
-		time.Sleep(500 * time.Millisecond)
+	if !initial {
+		return nil
 	}
 
+	// Tasks must finish before dependents are started
+	if proc.isTask() {
+		log.Printf("Waiting for process %s to complete...", proc.Name)
+		select {
+		case err := <-result:
+			return err
+		case <-pm.ctx.Done():
+			return fmt.Errorf("shutdown requested before process %s completed", proc.Name)
+		}
+	}
+
+	// Don't return immediately on first start. Critical processes must also
+	// survive the stability window before dependents are started.
+	wait := pm.StartWait
+	if proc.StartWait > 0 {
+		wait = proc.StartWait
+	}
+	if proc.Critical {
+		wait = minStableRun
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// waitCritical waits after starting a critical process: until it reports
+// ready if it has a ReadyCheck, otherwise for its CriticalWait. It returns an
+// error if the process exits before it is ready or the manager shuts down first.
+func (pm *ProcessManager) waitCritical(proc *Process) error {
+	if proc.ReadyCheck != nil {
+		return pm.waitCriticalReady(proc)
+	}
+
+	wait := pm.CriticalWait
+	if proc.CriticalWait > 0 {
+		wait = proc.CriticalWait
+	}
+	if !pm.sleep(wait) {
+		return fmt.Errorf("shutdown requested while starting process %s", proc.Name)
+	}
 	return nil
 }
 
-// Shutdown gracefully shuts down all processes
+// giveUp records that proc will not be run again. A critical process giving up ends Run.
+func (pm *ProcessManager) giveUp(proc *Process, pid, exitCode int, err error) {
+	pm.emit(proc.Name, EventGaveUp, pid, exitCode)
+	if !proc.Critical {
+		return
+	}
+
+	select {
+	case pm.failed <- fmt.Errorf("critical process %s gave up: %w", proc.Name, err):
+	default:
+		// Run already has a terminal error
+	}
+}
+
+// Run starts all processes and blocks until ctx is cancelled, the manager is
+// shut down or a critical process gives up, then shuts down gracefully. With
+// ExitWhenDone it also returns once every process has stopped for good. It
+// returns the error that ended the run, or nil if it was stopped on request
+// or every process completed.
+func (pm *ProcessManager) Run(ctx context.Context) error {
+	// Cancelling ctx also interrupts a Start that is still in progress
+	stop := context.AfterFunc(ctx, pm.cancel)
+	defer stop()
+
+	err := pm.Start()
+	if err == nil {
+		// Never ready unless ExitWhenDone is set
+		var done <-chan struct{}
+		if pm.ExitWhenDone {
+			done = pm.processesDone()
+		}
+		select {
+		case <-pm.ctx.Done():
+		case err = <-pm.failed:
+			log.Printf("Process Manager: %v", err)
+		case <-done:
+			if err = pm.batchResult(); err != nil {
+				log.Printf("Process Manager: %v", err)
+			} else {
+				log.Println("Process Manager: all processes completed")
+			}
+		}
+	} else if ctx.Err() != nil {
+		err = nil
+	}
+
+	pm.Shutdown()
+	return err
+}
+
+// Shutdown gracefully shuts down all processes. It is safe to call more than once
+// and from several goroutines; later calls block until the first one has finished.
 func (pm *ProcessManager) Shutdown() {
+	pm.shutdownOnce.Do(pm.shutdown)
+}
+
+func (pm *ProcessManager) shutdown() {
 	log.Println("Process Manager: initiating graceful shutdown...")
 
 	// Cancel context to stop restart loops
 	pm.cancel()
 
-	// Send SIGTERM to all running processes
-	pm.mu.Lock()
-	for name, cmd := range pm.running {
-		if cmd.Process != nil {
-			log.Printf("Sending SIGTERM to process: %s (PID: %d)", name, cmd.Process.Pid)
-			if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
-				log.Printf("Failed to send SIGTERM to %s: %v", name, err)
+	// Take the running processes through their stop sequences tier by tier,
+	// waiting for each tier to exit before signalling the next. Running tasks
+	// are first left to complete for their drain timeout. Every stop sequence
+	// ends in SIGKILL, so no tier can hold up the rest for good.
+	for _, tier := range shutdownTiers(pm.processList()) {
+		var stopping sync.WaitGroup
+		pm.mu.Lock()
+		for _, proc := range tier {
+			if handle, ok := pm.running[proc.Name]; ok {
+				stopping.Add(1)
+				go func() {
+					defer stopping.Done()
+					drainProcess(proc, handle)
+				}()
 			}
 		}
+		pm.mu.Unlock()
+		stopping.Wait()
 	}
+
+	pm.wg.Wait()
+
+	// Processes adopted after a re-exec whose restart loop never started
+	pm.mu.Lock()
+	leftover := pm.replacements
+	pm.replacements = make(map[string]*waitedHandle)
 	pm.mu.Unlock()
+	for name, handle := range leftover {
+		stopProcess(pm.process(name), handle)
+	}
+	for _, pipe := range pm.stdinPipes {
+		pipe.close()
+	}
+	log.Println("All processes exited")
 
-	// Wait for processes to exit gracefully (with timeout)
-	done := make(chan struct{})
-	go func() {
-		pm.wg.Wait()
-		close(done)
-	}()
+	pm.printSummary()
+	log.Println("Process Manager shutdown complete")
+}
 
-	select {
-	case <-done:
-		log.Println("All processes exited gracefully")
-	case <-time.After(30 * time.Second):
-		log.Println("Timeout waiting for processes to exit, forcing shutdown...")
+// applyLimits places pid in a cgroup with the process's resource limits.
+// Limits are best effort: if cgroups are unavailable the process runs unconstrained.
+func (pm *ProcessManager) applyLimits(proc *Process, pid int) *cgroup {
+	if proc.CPUQuota <= 0 && proc.MemoryLimitMB <= 0 {
+		return nil
+	}
 
-		// Force kill remaining processes
-		pm.mu.Lock()
-		for name, cmd := range pm.running {
-			if cmd.Process != nil {
-				log.Printf("Force killing process: %s (PID: %d)", name, cmd.Process.Pid)
-				cmd.Process.Kill()
-			}
+	cg, err := newCgroup(proc)
+	if err != nil {
+		log.Printf("Process %s: skipping resource limits: %v", proc.Name, err)
+		return nil
+	}
+
+	if err := cg.addProcess(pid); err != nil {
+		log.Printf("Process %s: skipping resource limits: %v", proc.Name, err)
+		cg.remove()
+		return nil
+	}
+
+	return cg
+}
+
+// limitRuntime terminates the process with SIGTERM, then SIGKILL, if it runs longer
+// than proc.MaxRuntime. The returned function cancels the limit once the process
+// has exited and reports whether the limit was hit.
+func limitRuntime(proc *Process, handle processHandle) func() bool {
+	if proc.MaxRuntime <= 0 {
+		return func() bool { return false }
+	}
+
+	var (
+		mu    sync.Mutex
+		fired bool
+		kill  *time.Timer
+	)
+
+	timer := time.AfterFunc(proc.MaxRuntime, func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fired = true
+		log.Printf("Process %s: exceeded max runtime of %v, sending SIGTERM", proc.Name, proc.MaxRuntime)
+		if err := handle.Signal(syscall.SIGTERM); err != nil {
+			log.Printf("Failed to send SIGTERM to %s: %v", proc.Name, err)
 		}
-		pm.mu.Unlock()
+
+		kill = time.AfterFunc(maxRuntimeKillGrace, func() {
+			log.Printf("Process %s: still running %v after SIGTERM, killing", proc.Name, maxRuntimeKillGrace)
+			handle.Signal(os.Kill)
+		})
+	})
+
+	return func() bool {
+		timer.Stop()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if kill != nil {
+			kill.Stop()
+		}
+		return fired
 	}
+}
 
-	log.Println("Process Manager shutdown complete")
+// waitBeforeRestart records a restart of proc and waits out its restart delay,
+// backed off for unstable consecutive short runs, returning false if the
+// restart loop's ctx is done first. This is the only place a restart cycle
+// waits, and a restart requested through Restart skips the delay.
+func (pm *ProcessManager) waitBeforeRestart(ctx context.Context, proc *Process, pid, unstable int) bool {
+	delay := proc.restartDelay(unstable)
+
+	pm.mu.Lock()
+	if pm.restartRequested[proc.Name] {
+		delete(pm.restartRequested, proc.Name)
+		delay = 0
+	}
+	resume, shed := pm.shed[proc.Name]
+	pm.mu.Unlock()
+
+	// A process stopped by the memory watchdog comes back once memory
+	// recovers. It was stopped on purpose, so this restart is not counted
+	// and does not raise a flapping alert.
+	if shed {
+		log.Printf("Process %s: stopped under memory pressure, waiting for usage to recover", proc.Name)
+		select {
+		case <-resume:
+		case <-ctx.Done():
+			return false
+		}
+		log.Printf("Process %s: memory usage recovered, restarting", proc.Name)
+		pm.emit(proc.Name, EventRestarting, pid, -1)
+		return true
+	}
+
+	log.Printf("Process %s: restarting in %v...", proc.Name, delay)
+	pm.updateState(proc.Name, func(s *ProcessState) { s.Restarts++ })
+	pm.emit(proc.Name, EventRestarting, pid, -1)
+	pm.checkFlapping(proc, pid)
+
+	return sleepContext(ctx, delay)
+}
+
+// restartDelay is the delay before restarting proc after unstable consecutive
+// short runs. With a RestartBackoffMax it doubles for every short run after
+// the first, up to that maximum.
+func (proc *Process) restartDelay(unstable int) time.Duration {
+	delay := proc.RestartDelay
+	if delay == 0 {
+		delay = defaultRestartDelay
+	}
+	if proc.RestartBackoffMax <= 0 {
+		return delay
+	}
+	for i := 1; i < unstable && delay < proc.RestartBackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, proc.RestartBackoffMax)
+}
+
+// Restart stops the named process and lets the restart loop bring it back
+// immediately, skipping the restart delay. For a process with RollingRestart it
+// returns once the replacement has taken over the socket.
+func (pm *ProcessManager) Restart(name string) error {
+	if proc := pm.process(name); proc != nil && proc.RollingRestart {
+		return pm.rollingRestart(proc)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, ok := pm.states[name]; !ok {
+		return fmt.Errorf("unknown process %q", name)
+	}
+
+	handle, ok := pm.running[name]
+	if !ok {
+		return fmt.Errorf("process %q is not running", name)
+	}
+
+	log.Printf("Restart requested for process: %s (PID: %d)", name, handle.Pid())
+	pm.restartRequested[name] = true
+	if err := handle.Signal(syscall.SIGTERM); err != nil {
+		delete(pm.restartRequested, name)
+		return fmt.Errorf("failed to stop process %q: %w", name, err)
+	}
+	return nil
+}
+
+// sleep waits for d, returning false if the manager is shut down first
+func (pm *ProcessManager) sleep(d time.Duration) bool {
+	return sleepContext(pm.ctx, d)
+}
+
+// sleepContext waits for d, returning false if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 // Wait blocks until shutdown is complete
@@ -217,11 +924,121 @@ func (pm *ProcessManager) Wait() {
 	pm.wg.Wait()
 }
 
-// prefixedWriter adds a prefix to each line written
+// process returns the definition of the named process, or nil if there is none
+func (pm *ProcessManager) process(name string) *Process {
+	for _, proc := range pm.processList() {
+		if proc.Name == name {
+			return proc
+		}
+	}
+	return nil
+}
+
+// command builds the command for one run of proc. The process outlives the
+// manager's context, since shutdown stops it with its stop sequence instead.
+func (pm *ProcessManager) command(proc *Process) (*exec.Cmd, error) {
+	env, err := fileEnv(proc)
+	if err != nil {
+		return nil, err
+	}
+	command, args, err := expandCommand(proc, env)
+	if err != nil {
+		return nil, err
+	}
+	// Resolved on every run, so a restart picks up a newly dropped binary
+	if command, err = resolveCommand(command); err != nil {
+		return nil, err
+	}
+	cmd := pm.runner.Command(context.WithoutCancel(pm.ctx), command, args...)
+	if err := setCredential(cmd, proc); err != nil {
+		return nil, err
+	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = pm.outputWriter(proc, "stdout", os.Stdout)
+	cmd.Stderr = pm.outputWriter(proc, "stderr", os.Stderr)
+	if proc.StdinData != nil {
+		cmd.Stdin = bytes.NewReader(proc.StdinData)
+	}
+	if proc.StdinFrom != "" {
+		r, err := pm.stdinPipes[proc.StdinFrom].connect()
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect stdin to %s: %w", proc.StdinFrom, err)
+		}
+		cmd.Stdin = r
+	}
+	return cmd, nil
+}
+
+// outputWriter returns where one output stream of a process goes: the stdin
+// of its consumer for the stdout of a StdinFrom producer, the log otherwise
+func (pm *ProcessManager) outputWriter(proc *Process, stream string, dest *os.File) io.Writer {
+	if pipe := pm.stdinPipes[proc.Name]; pipe != nil && stream == "stdout" {
+		return pipe
+	}
+	return pm.newOutputWriter(proc, stream, dest)
+}
+
+// newOutputWriter returns the writer for one output stream of a process
+func (pm *ProcessManager) newOutputWriter(proc *Process, stream string, dest *os.File) *prefixedWriter {
+	pw := &prefixedWriter{
+		prefix:  fmt.Sprintf("[%s] ", proc.Name),
+		dest:    pm.console(dest),
+		json:    pm.LogFormat == logFormatJSON,
+		process: proc.Name,
+		stream:  stream,
+		history: pm.logBuffer(proc.Name),
+		labels:  proc.LogLabels,
+		keep:    proc.LogFilterKeep,
+	}
+	if proc.LogFilter != "" {
+		filter, err := regexp.Compile(proc.LogFilter)
+		if err != nil {
+			log.Printf("Process %s: ignoring invalid log filter: %v", proc.Name, err)
+		} else {
+			pw.filter = filter
+		}
+	}
+	return pw
+}
+
+const (
+	// maxLineLength caps how much of a line without a newline is buffered,
+	// so a runaway child cannot grow the manager's memory without bound
+	maxLineLength = 64 * 1024
+	// truncatedMarker ends each chunk of a line that exceeded maxLineLength
+	truncatedMarker = " [truncated]"
+)
+
+// prefixedWriter adds a prefix to each line written, or wraps each line in a JSON object in JSON mode
 type prefixedWriter struct {
 	prefix string
-	dest   *os.File
+	dest   io.Writer
 	buffer []byte
+
+	json    bool
+	process string
+	stream  string
+	// Extra fields of each JSON line
+	labels map[string]string
+	// Optional buffer retaining recent lines for the HTTP API
+	history *logBuffer
+
+	// Longest line held in buffer before it is flushed truncated, maxLineLength if zero
+	maxLine int
+
+	// Optional filter dropping matching lines, or all other lines if keep is set
+	filter *regexp.Regexp
+	keep   bool
+}
+
+// suppressed reports whether the filter drops line
+func (pw *prefixedWriter) suppressed(line []byte) bool {
+	if pw.filter == nil {
+		return false
+	}
+	return pw.filter.Match(line) != pw.keep
 }
 
 func (pw *prefixedWriter) Write(p []byte) (n int, err error) {
@@ -232,26 +1049,29 @@ func (pw *prefixedWriter) Write(p []byte) (n int, err error) {
 	// Append incoming data to buffer
 	pw.buffer = append(pw.buffer, p...)
 
+	limit := pw.maxLine
+	if limit <= 0 {
+		limit = maxLineLength
+	}
+
 	// Process complete lines
 	for {
-		lineEnd := -1
-		for i, b := range pw.buffer {
-			if b == '\n' {
-				lineEnd = i
+		lineEnd := bytes.IndexByte(pw.buffer, '\n')
+		if lineEnd == -1 || lineEnd > limit {
+			if len(pw.buffer) <= limit {
+				// No complete line yet, keep buffering
 				break
 			}
+			// The line is too long to hold on to, flush what fits and carry on
+			// with the rest as if it were a new line
+			if err := pw.writeLine(pw.buffer[:limit], truncatedMarker); err != nil {
+				return originalLen, nil
+			}
+			pw.buffer = pw.buffer[limit:]
+			continue
 		}
 
-		if lineEnd == -1 {
-			// No complete line yet, keep buffering
-			break
-		}
-
-		// Write the line with prefix
-		line := pw.buffer[:lineEnd+1]
-		prefixed := append([]byte(pw.prefix), line...)
-
-		if _, err := pw.dest.Write(prefixed); err != nil {
+		if err := pw.writeLine(pw.buffer[:lineEnd], ""); err != nil {
 			// Even if we fail to write, we should return the original length
 			// to avoid breaking the pipe on the caller's side
 			return originalLen, nil
@@ -265,43 +1085,173 @@ func (pw *prefixedWriter) Write(p []byte) (n int, err error) {
 	return originalLen, nil
 }
 
+// writeLine writes one line, without its newline, to dest and the history
+func (pw *prefixedWriter) writeLine(line []byte, marker string) error {
+	if pw.suppressed(line) {
+		return nil
+	}
+	message := string(line) + marker
+
+	// Write the line with prefix
+	var prefixed []byte
+	if pw.json {
+		prefixed = logEntry{Process: pw.process, Stream: pw.stream, Message: message, Labels: pw.labels}.encode()
+	} else {
+		prefixed = []byte(pw.prefix + message + "\n")
+	}
+
+	if _, err := pw.dest.Write(prefixed); err != nil {
+		return err
+	}
+
+	if pw.history != nil {
+		pw.history.add(message)
+	}
+	return nil
+}
+
 func main() {
+	httpAddr := flag.String("http", "", "Address for the HTTP API, e.g. :8080 (disabled when empty)")
+	stagger := flag.Duration("stagger", 0, "Delay between each process launch")
+	startWait := flag.Duration("start-wait", defaultStartWait, "How long each noncritical process is watched for an early failure before the next one starts")
+	criticalWait := flag.Duration("critical-wait", defaultCriticalWait, "Pause after each critical process starts before the next one; critical processes with a readiness check are waited for until ready instead")
+	var configs configPaths
+	flag.Var(&configs, "config", "Path to a JSON or TOML (.toml) process config, repeatable or comma-separated with later files overriding earlier ones (defaults to the built-in gRPC bundle)")
+	validate := flag.Bool("validate", false, "Validate the config and exit without starting processes")
+	printGraph := flag.Bool("print-graph", false, "Print the process dependency graph in Graphviz DOT format and exit")
+	printConfig := flag.Bool("print-config", false, "Print the effective config as JSON, with files merged, defaults applied and environment variables expanded, and exit")
+	logFormat := flag.String("log-format", logFormatText, "Log output format: text or json")
+	logFile := flag.String("logfile", "", "Also write the manager's logs and all process output to this file, rotated by size (disabled when empty)")
+	logFileMaxSize := flag.Int64("logfile-max-size", defaultLogFileMaxSize>>20, "Size in MiB at which the -logfile is rotated")
+	logFileKeep := flag.Int("logfile-keep", defaultLogFileKeep, "Number of rotated -logfile generations kept, as <logfile>.1 (newest) to <logfile>.<n>")
+	usageInterval := flag.Duration("usage-interval", 0, "Interval between CPU and memory samples of each process, logged and exported on /metrics (disabled when 0; Linux only)")
+	maxMemory := flag.Uint64("max-memory", 0, "Memory limit in MiB for the manager and its processes; above it noncritical processes are stopped until usage recovers (disabled when 0)")
+	strict := flag.Bool("strict", false, "Fail startup on config warnings, such as a process others depend on being noncritical without a readiness check, instead of logging them")
+	restartDependents := flag.Bool("restart-dependents", false, "When a process restarts, also restart the processes that depend on it once it is ready again")
+	webhook := flag.String("webhook", "", "URL that every process event is POSTed to as JSON (disabled when empty)")
+	exitWhenDone := flag.Bool("exit-when-done", false, "Exit once every process has stopped for good, as in a batch of tasks: with code 0 if all of them completed, otherwise 1")
+	shutdownToken := flag.String("shutdown-token", "", "Bearer token for POST /shutdown on the HTTP API (endpoint disabled when empty)")
+	version := flag.Bool("version", false, "Print the version and exit")
+	flag.Parse()
+
+	if *version {
+		fmt.Println("manager", buildinfo.Get())
+		return
+	}
+
+	// The manager's own logs, with a copy to the -logfile if set
+	var logDest io.Writer = os.Stderr
+	var combined *rotatingFile
+	if *logFile != "" {
+		if *logFileMaxSize <= 0 || *logFileKeep < 0 {
+			log.Fatalf("Invalid -logfile-max-size or -logfile-keep: the size must be positive and the count not negative")
+		}
+		var err error
+		combined, err = openRotatingFile(*logFile, *logFileMaxSize<<20, *logFileKeep)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer combined.Close()
+		logDest = io.MultiWriter(os.Stderr, combined)
+	}
+
+	switch *logFormat {
+	case logFormatText:
+		log.SetOutput(logDest)
+	case logFormatJSON:
+		log.SetFlags(0)
+		log.SetOutput(&jsonLogWriter{dest: logDest})
+	default:
+		log.Fatalf("Unknown log format %q (expected text or json)", *logFormat)
+	}
+
 	// Define the processes to manage
-	processes := []*Process{
-		{
-			Name:         "grpc-server",
-			Command:      "/app/server",
-			Args:         []string{},
-			Critical:     true, // Server must start first
-			RestartDelay: 5 * time.Second,
-		},
-		{
-			Name:         "grpc-client",
-			Command:      "/app/client",
-			Args:         []string{},
-			Critical:     false,
-			RestartDelay: 5 * time.Second,
-		},
+	processes := defaultProcesses()
+	if len(configs) > 0 {
+		var err error
+		processes, err = LoadConfig(configs...)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+
+	if *validate {
+		os.Exit(runValidate(processes))
+	}
+
+	if *printConfig {
+		os.Exit(runPrintConfig(processes, os.Stdout))
+	}
+
+	if *printGraph {
+		os.Exit(runPrintGraph(processes, os.Stdout))
 	}
 
 	// Create process manager
 	pm := NewProcessManager(processes)
+	pm.Stagger = *stagger
+	pm.StartWait = *startWait
+	pm.CriticalWait = *criticalWait
+	pm.LogFormat = *logFormat
+	pm.ShutdownToken = *shutdownToken
+	if combined != nil {
+		pm.LogFile = combined
+	}
+	pm.MaxMemory = *maxMemory << 20
+	pm.UsageInterval = *usageInterval
+	pm.RestartDependentsOnRestart = *restartDependents
+	pm.Strict = *strict
+	pm.ExitWhenDone = *exitWhenDone
+	if *webhook != "" {
+		pm.Notifiers = append(pm.Notifiers, NewWebhookNotifier(*webhook))
+	}
+
+	// Take over the processes and HTTP listener of a manager that re-executed into this one
+	httpListener, err := pm.resumeHandoff()
+	if err != nil {
+		log.Fatalf("Failed to resume after re-exec: %v", err)
+	}
+
+	if *httpAddr != "" {
+		if httpListener == nil {
+			httpListener, err = net.Listen("tcp", *httpAddr)
+			if err != nil {
+				log.Fatalf("Failed to start HTTP API: %v", err)
+			}
+		}
+		go func() {
+			log.Printf("HTTP API listening on %s", httpListener.Addr())
+			if err := http.Serve(httpListener, newHTTPHandler(pm)); err != nil {
+				log.Printf("HTTP API stopped: %v", err)
+			}
+		}()
+	} else if httpListener != nil {
+		httpListener.Close()
+		httpListener = nil
+	}
 
 	// Set up signal handling
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigChan
+		log.Printf("Received signal: %v", sig)
+		cancel()
+	}()
 
-	// Start all processes
-	if err := pm.Start(); err != nil {
-		log.Fatalf("Failed to start processes: %v", err)
-	}
+	// SIGUSR1 dumps the process table for debugging without the HTTP API
+	pm.dumpStatusOnSignal(ctx, pm.console(os.Stderr))
 
-	// Wait for shutdown signal
-	sig := <-sigChan
-	log.Printf("Received signal: %v", sig)
+	// SIGUSR2 re-executes the manager binary without stopping the processes
+	pm.reexecOnSignal(ctx, httpListener)
 
-	// Shutdown gracefully
-	pm.Shutdown()
+	// Run until a shutdown signal arrives or a critical process gives up, or
+	// with -exit-when-done until every process is done
+	if err := pm.Run(ctx); err != nil {
+		log.Fatalf("Process Manager failed: %v", err)
+	}
 
 	log.Println("Process Manager exited")
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestStartMinStableRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		critical bool
+		wantErr  bool
+	}{
+		{"critical exits after 100ms", "sleep 0.1", true, true},
+		{"critical exits immediately", "exit 1", true, true},
+		{"critical stays up", "exec sleep 5", true, false},
+		{"non-critical exits after 100ms", "sleep 0.1", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc := &Process{
+				Name:         "proc",
+				Command:      "sh",
+				Args:         []string{"-c", tt.script},
+				Critical:     tt.critical,
+				MinStableRun: 500 * time.Millisecond,
+				RestartDelay: time.Hour,
+			}
+			pm := NewProcessManager([]*Process{proc})
+			defer pm.Shutdown()
+
+			err := pm.Start()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Start() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrefixedWriterHistory(t *testing.T) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	history := newLogBuffer(10)
+	pw := &prefixedWriter{prefix: "[p] ", dest: devNull, history: history}
+	for i := 1; i <= 3; i++ {
+		fmt.Fprintf(pw, "line %d\n", i)
+	}
+	want := []string{"line 1", "line 2", "line 3"}
+	if got := history.tail(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+}
+
+func TestPrefixedWriterFilter(t *testing.T) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	tests := []struct {
+		name string
+		keep bool
+		want []string
+	}{
+		{"drop matching", false, []string{"INFO ready", "WARN slow DEBUG"}},
+		{"keep matching", true, []string{"DEBUG cache miss", "DEBUG split line"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := newLogBuffer(10)
+			pw := &prefixedWriter{
+				prefix:  "[p] ",
+				dest:    devNull,
+				history: history,
+				filter:  regexp.MustCompile(`^DEBUG`),
+				keep:    tt.keep,
+			}
+			fmt.Fprint(pw, "DEBUG cache miss\nINFO ready\nDE")
+			// The filter only sees the split line once it is complete
+			fmt.Fprint(pw, "BUG split line\nWARN slow DEBUG\n")
+			if got := history.tail(0); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("history = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixedWriterTruncatesLongLines(t *testing.T) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	history := newLogBuffer(10)
+	pw := &prefixedWriter{prefix: "[p] ", dest: devNull, history: history, maxLine: 4}
+	for _, chunk := range []string{"abc", "defghij", "kl\nok\n"} {
+		fmt.Fprint(pw, chunk)
+		if len(pw.buffer) > pw.maxLine {
+			t.Fatalf("buffered %d bytes after writing %q, want at most %d", len(pw.buffer), chunk, pw.maxLine)
+		}
+	}
+	want := []string{"abcd [truncated]", "efgh [truncated]", "ijkl", "ok"}
+	if got := history.tail(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+}
+
+func TestInitialStartFailureRetried(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "late")
+	proc := &Process{Name: "late", Command: binary, RestartDelay: 200 * time.Millisecond}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventRestarting, 5*time.Second)
+
+	// The binary is only put in place after the first attempt failed
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\nexec sleep 30\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	if state := pm.States()[0]; !state.Running {
+		t.Errorf("state = %+v, want the process running", state)
+	}
+}
+
+func TestCriticalInitialStartFailureGivesUp(t *testing.T) {
+	proc := &Process{Name: "missing", Command: filepath.Join(t.TempDir(), "missing"), Critical: true, RestartDelay: 100 * time.Millisecond}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.Start(); err == nil {
+		t.Fatal("Start succeeded with a missing critical binary")
+	}
+	waitEvent(t, events, proc.Name, EventGaveUp, 5*time.Second)
+}
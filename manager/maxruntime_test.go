@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxRuntime(t *testing.T) {
+	const limit = 300 * time.Millisecond
+	proc := &Process{Name: "hang", Command: "sleep", Args: []string{"30"}, MaxRuntime: limit, RestartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	started := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	exited := waitEvent(t, events, proc.Name, EventExited, 5*time.Second)
+
+	if ran := exited.Time.Sub(started.Time); ran < limit || ran > limit+2*time.Second {
+		t.Errorf("process ran for %v, want it stopped at the %v deadline", ran, limit)
+	}
+	if state := pm.States()[0]; !state.TimedOut {
+		t.Errorf("state = %+v, want TimedOut", state)
+	}
+}
+
+func TestMaxRuntimeNotReached(t *testing.T) {
+	proc := &Process{Name: "quick", Command: "sleep", Args: []string{"0.1"}, MaxRuntime: 5 * time.Second, RestartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	exited := waitEvent(t, events, proc.Name, EventExited, 5*time.Second)
+	if exited.ExitCode != 0 {
+		t.Errorf("exit code = %d, want 0", exited.ExitCode)
+	}
+	if state := pm.States()[0]; state.TimedOut {
+		t.Error("run within its limit recorded as timed out")
+	}
+}
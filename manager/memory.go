@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// Default interval between memory usage checks when MaxMemory is set
+	defaultMemoryInterval = 5 * time.Second
+	// Shed processes are restarted once usage is below this share of MaxMemory,
+	// so a process is not stopped and started again on every check
+	memoryRecoverRatio = 0.9
+)
+
+// watchMemory checks memory usage every memoryInterval until shutdown. While
+// usage is above MaxMemory it stops one noncritical process per check, in
+// shedTiers order; once usage has recovered it restarts them one per check, the
+// last one stopped first.
+func (pm *ProcessManager) watchMemory() {
+	ticker := time.NewTicker(pm.memoryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-pm.ctx.Done():
+			return
+		}
+
+		usage, err := pm.memoryUsage()
+		if err != nil {
+			log.Printf("Memory watchdog: failed to read memory usage: %v", err)
+			continue
+		}
+
+		switch {
+		case usage > pm.MaxMemory:
+			if proc, handle := pm.nextToShed(); proc != nil {
+				log.Printf("Memory watchdog: usage %s above limit %s, stopping process %s", formatMiB(usage), formatMiB(pm.MaxMemory), proc.Name)
+				go stopProcess(proc, handle)
+			}
+		case float64(usage) < float64(pm.MaxMemory)*memoryRecoverRatio:
+			if name := pm.resumeShed(); name != "" {
+				log.Printf("Memory watchdog: usage %s recovered, restarting process %s", formatMiB(usage), name)
+			}
+		}
+	}
+}
+
+// shedTiers groups processes into the order the memory watchdog stops them
+// in: the lowest ShutdownPriority first and, within a priority, dependents
+// before the processes they depend on, as on shutdown
+func shedTiers(processes []*Process) [][]*Process {
+	tiers := shutdownTiers(processes)
+	slices.SortStableFunc(tiers, func(a, b []*Process) int {
+		return cmp.Compare(a[0].ShutdownPriority, b[0].ShutdownPriority)
+	})
+	return tiers
+}
+
+// nextToShed marks the first running noncritical process in shed order as
+// shed and returns it with its handle, or nil if there is nothing left to stop
+func (pm *ProcessManager) nextToShed() (*Process, *waitedHandle) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for _, tier := range shedTiers(pm.processes) {
+		for _, proc := range tier {
+			if proc.Critical {
+				continue
+			}
+			if _, shed := pm.shed[proc.Name]; shed {
+				continue
+			}
+			handle, ok := pm.running[proc.Name]
+			if !ok {
+				continue
+			}
+			pm.shed[proc.Name] = make(chan struct{})
+			pm.shedOrder = append(pm.shedOrder, proc.Name)
+			return proc, handle
+		}
+	}
+	return nil, nil
+}
+
+// isShed reports whether the named process is stopped by the memory watchdog
+func (pm *ProcessManager) isShed(name string) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	_, shed := pm.shed[name]
+	return shed
+}
+
+// resumeShed lets the most recently shed process restart and returns its name,
+// or "" if no process is shed
+func (pm *ProcessManager) resumeShed() string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if len(pm.shedOrder) == 0 {
+		return ""
+	}
+	name := pm.shedOrder[len(pm.shedOrder)-1]
+	pm.shedOrder = pm.shedOrder[:len(pm.shedOrder)-1]
+	close(pm.shed[name])
+	delete(pm.shed, name)
+	return name
+}
+
+// processTreeRSS returns the resident memory of the manager and its running processes
+func (pm *ProcessManager) processTreeRSS() (uint64, error) {
+	pids := []int{os.Getpid()}
+	pm.mu.Lock()
+	for _, handle := range pm.running {
+		pids = append(pids, handle.Pid())
+	}
+	pm.mu.Unlock()
+
+	var total uint64
+	for _, pid := range pids {
+		rss, err := readRSS(pid)
+		if err != nil {
+			if pid == os.Getpid() {
+				return 0, err
+			}
+			// The process may have just exited
+			continue
+		}
+		total += rss
+	}
+	return total, nil
+}
+
+// readRSS returns the resident set size of pid in bytes from /proc (Linux only)
+func readRSS(pid int) (uint64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, err
+	}
+	// statm holds sizes in pages: total, resident, shared, ...
+	fields := bytes.Fields(data)
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected statm format: %q", data)
+	}
+	pages, err := strconv.ParseUint(string(fields[1]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected statm format: %q", data)
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
+
+// formatMiB formats a byte count in MiB
+func formatMiB(bytes uint64) string {
+	return fmt.Sprintf("%.1fMiB", float64(bytes)/(1<<20))
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestProcessTreeRSS(t *testing.T) {
+	pm := NewProcessManager(nil)
+	defer pm.Shutdown()
+
+	own, err := readRSS(os.Getpid())
+	if err != nil {
+		t.Fatalf("readRSS: %v", err)
+	}
+	if own == 0 {
+		t.Error("readRSS of the test process = 0, want its resident size")
+	}
+
+	total, err := pm.processTreeRSS()
+	if err != nil {
+		t.Fatalf("processTreeRSS: %v", err)
+	}
+	if total == 0 {
+		t.Error("processTreeRSS() = 0, want at least the manager's own resident size")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryWatchdogShedsAndRestores(t *testing.T) {
+	server := &Process{Name: "server", Command: "sleep", Args: []string{"30"}, Critical: true}
+	worker := &Process{Name: "worker", Command: "sleep", Args: []string{"30"}}
+	batch := &Process{Name: "batch", Command: "sleep", Args: []string{"30"}, ShutdownPriority: 1}
+	pm := NewProcessManager([]*Process{server, worker, batch})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	const limit = 100 << 20
+	var usage atomic.Uint64
+	pm.MaxMemory = limit
+	pm.memoryUsage = func() (uint64, error) { return usage.Load(), nil }
+	pm.memoryInterval = 50 * time.Millisecond
+
+	for _, proc := range pm.processes {
+		if err := pm.startProcess(proc, false); err != nil {
+			t.Fatalf("startProcess %s: %v", proc.Name, err)
+		}
+		waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	}
+	go pm.watchMemory()
+
+	// Over the limit, noncritical processes are stopped one by one, lowest priority first
+	usage.Store(limit + 1)
+	for _, name := range []string{"worker", "batch"} {
+		ev := nextEvent(t, events, EventExited, 5*time.Second)
+		if ev.Name != name {
+			t.Fatalf("%s stopped, want %s", ev.Name, name)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	if states := pm.States(); !states[0].Running || states[1].Running || states[2].Running {
+		t.Fatalf("running = %v/%v/%v, want only the critical server", states[0].Running, states[1].Running, states[2].Running)
+	}
+
+	// Just under the limit is not enough to recover
+	usage.Store(limit - 1)
+	time.Sleep(200 * time.Millisecond)
+	if states := pm.States(); states[1].Running || states[2].Running {
+		t.Fatal("processes restarted before usage dropped below the recovery threshold")
+	}
+
+	// Once usage recovers they come back, the last one stopped first
+	usage.Store(limit / 2)
+	for _, name := range []string{"batch", "worker"} {
+		ev := nextEvent(t, events, EventStarted, 5*time.Second)
+		if ev.Name != name {
+			t.Fatalf("%s restarted, want %s", ev.Name, name)
+		}
+	}
+
+	// Being shed on purpose is not counted as a restart
+	for _, name := range []string{"worker", "batch"} {
+		if state, _ := stateOf(pm, name); state.Restarts != 0 {
+			t.Errorf("%s restarts = %d, want 0 after being shed", name, state.Restarts)
+		}
+	}
+}
+
+// nextEvent returns the next event of type typ, whichever process it is for
+func nextEvent(t *testing.T, events <-chan ProcessEvent, typ EventType, timeout time.Duration) ProcessEvent {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type == typ {
+				return ev
+			}
+		case <-deadline:
+			t.Fatalf("no %s event within %v", typ, timeout)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	processUpDesc = prometheus.NewDesc("process_up",
+		"Whether the managed process is running (1) or not (0).", []string{"name"}, nil)
+	processRestartsDesc = prometheus.NewDesc("process_restarts_total",
+		"Number of times the managed process has been restarted.", []string{"name"}, nil)
+	processLastExitCodeDesc = prometheus.NewDesc("process_last_exit_code",
+		"Exit code of the managed process's most recent run, -1 if it has not exited or was killed by a signal.", []string{"name"}, nil)
+	processCPUSecondsDesc = prometheus.NewDesc("process_cpu_seconds_total",
+		"CPU time used by the managed process's current run, as of the latest usage sample.", []string{"name"}, nil)
+	processResidentMemoryDesc = prometheus.NewDesc("process_resident_memory_bytes",
+		"Resident memory of the managed process, as of the latest usage sample.", []string{"name"}, nil)
+	bundleReadyDesc = prometheus.NewDesc("bundle_ready_seconds",
+		"Time from startup until every critical process was ready, reported once the bundle has been ready.", nil, nil)
+)
+
+// processCollector exports the manager's process states as Prometheus
+// metrics, read fresh on every scrape
+type processCollector struct {
+	pm *ProcessManager
+}
+
+func (c processCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- processUpDesc
+	ch <- processRestartsDesc
+	ch <- processLastExitCodeDesc
+	ch <- processCPUSecondsDesc
+	ch <- processResidentMemoryDesc
+	ch <- bundleReadyDesc
+}
+
+func (c processCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, state := range c.pm.States() {
+		up := 0.0
+		if state.Running {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(processUpDesc, prometheus.GaugeValue, up, state.Name)
+		ch <- prometheus.MustNewConstMetric(processRestartsDesc, prometheus.CounterValue, float64(state.Restarts), state.Name)
+		ch <- prometheus.MustNewConstMetric(processLastExitCodeDesc, prometheus.GaugeValue, float64(state.LastExitCode), state.Name)
+	}
+
+	// Only sampled with a usage interval set
+	for name, sample := range c.pm.Usage() {
+		ch <- prometheus.MustNewConstMetric(processCPUSecondsDesc, prometheus.CounterValue, sample.CPUSeconds, name)
+		ch <- prometheus.MustNewConstMetric(processResidentMemoryDesc, prometheus.GaugeValue, float64(sample.RSS), name)
+	}
+
+	if bundle := c.pm.BundleReadiness(); bundle.Ready {
+		ch <- prometheus.MustNewConstMetric(bundleReadyDesc, prometheus.GaugeValue, bundle.ReadyAfter.Seconds())
+	}
+}
+
+// metricsHandler serves the process metrics in the Prometheus text format.
+// It uses its own registry, so only the manager's process metrics are exported.
+func (pm *ProcessManager) metricsHandler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(processCollector{pm: pm})
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	proc := &Process{Name: "sleeper", Command: "sleep", Args: []string{"30"}}
+	pm := NewProcessManager([]*Process{proc, {Name: "stopped"}})
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	pm.updateState("stopped", func(s *ProcessState) { s.Restarts = 2; s.LastExitCode = 3 })
+
+	srv := httptest.NewServer(newHTTPHandler(pm))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`process_up{name="sleeper"} 1`,
+		`process_up{name="stopped"} 0`,
+		`process_restarts_total{name="stopped"} 2`,
+		`process_last_exit_code{name="sleeper"} -1`,
+		`process_last_exit_code{name="stopped"} 3`,
+	} {
+		if !strings.Contains(string(body), want+"\n") {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+	// Not reported until the bundle has been ready
+	if strings.Contains(string(body), "bundle_ready_seconds ") {
+		t.Errorf("bundle_ready_seconds reported before the bundle was ready:\n%s", body)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Notifier pushes process events to an external system, such as a chat
+// channel, a paging service or a webhook
+type Notifier interface {
+	Notify(ProcessEvent) error
+}
+
+// notify hands event to every notifier without waiting for them. A failed
+// notification is logged and otherwise ignored.
+func (pm *ProcessManager) notify(event ProcessEvent) {
+	for _, notifier := range pm.Notifiers {
+		go func() {
+			if err := notifier.Notify(event); err != nil {
+				log.Printf("Warning: failed to send %s event of process %s to %T: %v", event.Type, event.Name, notifier, err)
+			}
+		}()
+	}
+}
+
+// webhookTimeout bounds each webhook request
+const webhookTimeout = 5 * time.Second
+
+// WebhookNotifier POSTs each event as JSON to a URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier returns a notifier posting to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// webhookEvent is the JSON body of a webhook notification
+type webhookEvent struct {
+	Process  string    `json:"process"`
+	Type     EventType `json:"type"`
+	PID      int       `json:"pid"`
+	ExitCode int       `json:"exitCode"`
+	Time     time.Time `json:"time"`
+}
+
+// Notify posts event, failing unless the webhook answers with a 2xx status
+func (w *WebhookNotifier) Notify(event ProcessEvent) error {
+	body, err := json.Marshal(webhookEvent{Process: event.Name, Type: event.Type, PID: event.PID, ExitCode: event.ExitCode, Time: event.Time})
+	if err != nil {
+		return err
+	}
+	resp, err := w.Client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", w.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeNotifier sends the events it is given to a channel, failing if err is set
+type fakeNotifier struct {
+	events chan ProcessEvent
+	err    error
+}
+
+func (n *fakeNotifier) Notify(event ProcessEvent) error {
+	n.events <- event
+	return n.err
+}
+
+func TestNotifiersReceiveEvents(t *testing.T) {
+	proc := &Process{Name: "worker", Command: "sleep", Args: []string{"30"}}
+	pm := NewProcessManager([]*Process{proc})
+	working := &fakeNotifier{events: make(chan ProcessEvent, 8)}
+	// A failing notifier does not stop the others or the process
+	failing := &fakeNotifier{events: make(chan ProcessEvent, 8), err: errors.New("unreachable")}
+	pm.Notifiers = []Notifier{failing, working}
+	defer pm.Shutdown()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	for _, notifier := range []*fakeNotifier{working, failing} {
+		select {
+		case event := <-notifier.events:
+			if event.Name != proc.Name || event.Type != EventStarted || event.PID == 0 {
+				t.Errorf("notified event = %+v, want %s started", event, proc.Name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event notified")
+		}
+	}
+	if state := pm.States()[0]; !state.Running {
+		t.Errorf("state = %+v, want the process running despite the failing notifier", state)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan webhookEvent, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("webhook request = %s with %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		var event webhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("webhook body: %v", err)
+		}
+		received <- event
+	}))
+	defer webhook.Close()
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := NewWebhookNotifier(webhook.URL).Notify(ProcessEvent{Name: "server", Type: EventExited, PID: 42, ExitCode: 1, Time: at}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	want := webhookEvent{Process: "server", Type: EventExited, PID: 42, ExitCode: 1, Time: at}
+	if got := <-received; got != want {
+		t.Errorf("webhook received %+v, want %+v", got, want)
+	}
+}
+
+func TestWebhookNotifierError(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer webhook.Close()
+
+	if err := NewWebhookNotifier(webhook.URL).Notify(ProcessEvent{Name: "server", Type: EventStarted}); err == nil {
+		t.Error("Notify succeeded although the webhook answered 503")
+	}
+}
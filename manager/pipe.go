@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// stdinPipe feeds the stdout of a process to the stdin of the process that
+// names it in StdinFrom. Every run of the consumer reads from a fresh pipe and
+// every run of the producer writes to whichever pipe is current, so a restart
+// on either side reconnects the two.
+type stdinPipe struct {
+	mu sync.Mutex
+	// Write end of the consumer's current stdin, nil until it first starts
+	w *os.File
+}
+
+// connect opens the pipe for a new run of the consumer, returning the read end
+// for its stdin. The previous run's pipe is closed.
+func (p *stdinPipe) connect() (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.w != nil {
+		p.w.Close()
+	}
+	p.w = w
+	return r, nil
+}
+
+// Write passes producer output on to the consumer. A slow consumer holds the
+// producer back, but output written while the consumer is not running is
+// discarded, so a restarting consumer never blocks the producer.
+func (p *stdinPipe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	w := p.w
+	p.mu.Unlock()
+	if w != nil {
+		// Fails once the consumer has exited, until its next run connects
+		w.Write(b)
+	}
+	return len(b), nil
+}
+
+// close closes the consumer's stdin
+func (p *stdinPipe) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.w != nil {
+		p.w.Close()
+		p.w = nil
+	}
+}
+
+// closeStdinPipe closes the manager's copy of the read end of proc's stdin
+// pipe once cmd has started, or failed to, so the process is its only reader
+// and the producer sees the pipe break when it exits
+func closeStdinPipe(proc *Process, cmd *exec.Cmd) {
+	if proc.StdinFrom == "" {
+		return
+	}
+	if r, ok := cmd.Stdin.(*os.File); ok {
+		r.Close()
+	}
+}
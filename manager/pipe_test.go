@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForLine waits for a line of the named process's output containing want
+func waitForLine(t *testing.T, pm *ProcessManager, name, want string) {
+	t.Helper()
+	lines, cancel := pm.logs[name].follow()
+	defer cancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line := <-lines:
+			if strings.Contains(line, want) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no %q in output of %s within 5s", want, name)
+		}
+	}
+}
+
+func TestStdinFrom(t *testing.T) {
+	producer := &Process{
+		Name:    "producer",
+		Command: "sh",
+		Args:    []string{"-c", `while true; do echo "line $$"; sleep 0.05; done`},
+	}
+	consumer := &Process{
+		Name:      "consumer",
+		Command:   "sh",
+		Args:      []string{"-c", `while read line; do echo "$$ got $line"; done`},
+		StdinFrom: "producer",
+	}
+	pm := NewProcessManager([]*Process{consumer, producer})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	for _, proc := range []*Process{consumer, producer} {
+		if err := pm.startProcess(proc, false); err != nil {
+			t.Fatalf("startProcess(%s): %v", proc.Name, err)
+		}
+	}
+	waitForLine(t, pm, "consumer", "got line")
+	if lines := pm.logs["producer"].tail(0); len(lines) > 0 {
+		t.Errorf("piped producer output was also logged: %q", lines)
+	}
+
+	// Restarting either side re-establishes the pipe
+	for _, name := range []string{"producer", "consumer"} {
+		if err := pm.Restart(name); err != nil {
+			t.Fatalf("Restart(%s): %v", name, err)
+		}
+		waitEvent(t, events, name, EventRestarting, 5*time.Second)
+		started := waitEvent(t, events, name, EventStarted, 5*time.Second)
+		want := fmt.Sprintf("%d got line", started.PID)
+		if name == "producer" {
+			want = fmt.Sprintf("got line %d", started.PID)
+		}
+		waitForLine(t, pm, "consumer", want)
+	}
+}
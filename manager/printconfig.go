@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// resolveConfig returns the config the manager would run for processes: the
+// merged config files with environment variables expanded and every default
+// the manager applies written out
+func resolveConfig(processes []*Process) (config, error) {
+	resolved := config{Processes: make([]processConfig, 0, len(processes))}
+	for _, proc := range processes {
+		pc := proc.toConfig()
+
+		var env []string
+		if proc.ExpandEnv {
+			var err error
+			if env, err = fileEnv(proc); err != nil {
+				return config{}, fmt.Errorf("process %q: %w", proc.Name, err)
+			}
+		}
+		command, args, err := expandCommand(proc, env)
+		if err != nil {
+			return config{}, fmt.Errorf("process %q: %w", proc.Name, err)
+		}
+		// Written out expanded, so they are not expanded again
+		pc.Command, pc.Args, pc.ExpandEnv = command, args, false
+
+		if pc.Kind == "" {
+			pc.Kind = KindDaemon
+			if proc.isTask() {
+				pc.Kind = KindTask
+			}
+		}
+		if pc.RestartDelay == 0 {
+			pc.RestartDelay = duration(defaultRestartDelay)
+		}
+		if pc.MinStableRun == 0 {
+			pc.MinStableRun = duration(defaultMinStableRun)
+		}
+		if pc.ReadyInterval == 0 && proc.ReadyCheck != nil {
+			pc.ReadyInterval = duration(defaultReadyInterval)
+		}
+		if pc.PostStopTimeout == 0 && len(pc.PostStop) > 0 {
+			pc.PostStopTimeout = duration(defaultPostStopTimeout)
+		}
+		if pc.RestartAlertWindow == 0 && pc.RestartAlertThreshold > 0 {
+			pc.RestartAlertWindow = duration(defaultRestartAlertWindow)
+		}
+		if pc.DrainTimeout == 0 && proc.isTask() {
+			pc.DrainTimeout = duration(defaultTaskDrainTimeout)
+		}
+		if len(pc.StopSignals) == 0 {
+			for _, step := range proc.stopSequence() {
+				pc.StopSignals = append(pc.StopSignals, stopStepConfig{Signal: namedSignal(step.Signal), Wait: duration(step.Wait)})
+			}
+		}
+		resolved.Processes = append(resolved.Processes, pc)
+	}
+	return resolved, nil
+}
+
+// runPrintConfig writes the resolved config to w as JSON and returns the process exit code
+func runPrintConfig(processes []*Process, w io.Writer) int {
+	resolved, err := resolveConfig(processes)
+	if err != nil {
+		fmt.Fprintf(w, "Config invalid: %v\n", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(resolved); err != nil {
+		fmt.Fprintf(w, "Failed to encode config: %v\n", err)
+		return 1
+	}
+	return 0
+}
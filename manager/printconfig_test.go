@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPrintConfig(t *testing.T) {
+	t.Setenv("PRINT_CONFIG_BIN", "/opt/bin")
+	dir := t.TempDir()
+	base := writeConfig(t, dir, "base.json", `{"processes": [
+		{"name": "server", "command": "${PRINT_CONFIG_BIN}/server", "expandEnv": true, "args": ["-socket", "/tmp/a.sock"], "critical": true, "grpcHealthSocket": "/tmp/a.sock"},
+		{"name": "client", "command": "/bin/client", "dependsOn": ["server"], "restartAlertThreshold": 3}
+	]}`)
+	override := writeConfig(t, dir, "override.json", `{"processes": [
+		{"name": "server", "args": ["-socket", "/tmp/b.sock"], "restartDelay": "1s"},
+		{"name": "client", "stopSignals": [{"signal": "SIGINT", "wait": "3s"}]}
+	]}`)
+	processes, err := LoadConfig(base, override)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	var out bytes.Buffer
+	if code := runPrintConfig(processes, &out); code != 0 {
+		t.Fatalf("runPrintConfig = %d: %s", code, out.String())
+	}
+	var got config
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("printed config is not valid JSON: %v\n%s", err, out.String())
+	}
+
+	want := config{Processes: []processConfig{
+		{
+			Name:             "server",
+			Command:          "/opt/bin/server",
+			Args:             []string{"-socket", "/tmp/b.sock"},
+			Critical:         true,
+			Kind:             KindDaemon,
+			RestartDelay:     duration(time.Second),
+			MinStableRun:     duration(defaultMinStableRun),
+			ReadyInterval:    duration(defaultReadyInterval),
+			GRPCHealthSocket: "/tmp/a.sock",
+			StopSignals:      []stopStepConfig{{Signal: namedSignal(syscall.SIGTERM), Wait: duration(defaultStopTimeout)}},
+		},
+		{
+			Name:                  "client",
+			Command:               "/bin/client",
+			DependsOn:             []string{"server"},
+			Kind:                  KindDaemon,
+			RestartDelay:          duration(defaultRestartDelay),
+			RestartAlertThreshold: 3,
+			RestartAlertWindow:    duration(defaultRestartAlertWindow),
+			MinStableRun:          duration(defaultMinStableRun),
+			StopSignals:           []stopStepConfig{{Signal: namedSignal(syscall.SIGINT), Wait: duration(3 * time.Second)}},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("printed config:\n%s\nwant %+v", out.String(), want)
+	}
+}
+
+func TestPrintConfigStrictEnv(t *testing.T) {
+	processes := []*Process{{Name: "server", Command: "${PRINT_CONFIG_UNSET}/server", ExpandEnv: true, StrictEnv: true}}
+	var out bytes.Buffer
+	if code := runPrintConfig(processes, &out); code != 1 {
+		t.Errorf("runPrintConfig = %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), "PRINT_CONFIG_UNSET") {
+		t.Errorf("output = %q, want the unset variable named", out.String())
+	}
+}
@@ -0,0 +1,322 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// handoffEnv names the file a re-executed manager resumes from
+const handoffEnv = "MANAGER_HANDOFF"
+
+// handoff is the state a manager passes on when it re-executes itself
+type handoff struct {
+	// Inherited descriptor of the HTTP API listener, 0 if there is none
+	HTTPListenerFD int              `json:"httpListenerFd,omitempty"`
+	Processes      []handoffProcess `json:"processes"`
+	// Run-to-completion processes that have already completed
+	Completed []string `json:"completed,omitempty"`
+}
+
+// handoffProcess is a running process for the new manager to adopt
+type handoffProcess struct {
+	Name     string `json:"name"`
+	PID      int    `json:"pid"`
+	Restarts int    `json:"restarts"`
+	// Inherited descriptors of the read ends of the output pipes, by stream
+	Outputs map[string]int `json:"outputs,omitempty"`
+	// Inherited descriptor of the write end of the stdin pipe from the
+	// process's StdinFrom, 0 if there is none
+	StdinPipe int `json:"stdinPipe,omitempty"`
+}
+
+// reexecOnSignal re-executes the manager's binary on SIGUSR2 until ctx is
+// done, handing over the running processes and the HTTP listener, if any.
+// Running processes are not restarted, so a new manager binary can be picked
+// up without downtime.
+func (pm *ProcessManager) reexecOnSignal(ctx context.Context, listener net.Listener) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(sigChan)
+		for {
+			select {
+			case <-sigChan:
+				log.Println("Received SIGUSR2, re-executing the manager")
+				if err := pm.reexec(listener); err != nil {
+					log.Printf("Re-exec failed, carrying on: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// reexec replaces the manager with a fresh run of its binary. Running
+// processes stay children of the same PID across the exec; they are recorded
+// in a handoff file along with inheritable copies of their output pipes and
+// of the HTTP listener. It only returns if the exec fails.
+func (pm *ProcessManager) reexec(listener net.Listener) error {
+	// The path the running binary was started from, whatever os.Args[0]
+	// says, so an upgrade dropped in at that path is what runs next
+	binary, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the manager binary: %w", err)
+	}
+
+	var h handoff
+	var fds []int
+	// Descriptors are only closed here if the exec does not happen
+	defer func() {
+		for _, fd := range fds {
+			syscall.Close(fd)
+		}
+	}()
+	inherit := func(conn syscall.Conn) (int, error) {
+		fd, err := dupInheritable(conn)
+		if err == nil {
+			fds = append(fds, fd)
+		}
+		return fd, err
+	}
+
+	if listener != nil {
+		conn, ok := listener.(syscall.Conn)
+		if !ok {
+			return fmt.Errorf("cannot hand over HTTP listener of type %T", listener)
+		}
+		if h.HTTPListenerFD, err = inherit(conn); err != nil {
+			return fmt.Errorf("failed to hand over HTTP listener: %w", err)
+		}
+	}
+
+	pm.mu.Lock()
+	for _, proc := range pm.processes {
+		state := pm.states[proc.Name]
+		handle, ok := pm.running[proc.Name]
+		if !ok {
+			if state.Completed {
+				h.Completed = append(h.Completed, proc.Name)
+			}
+			continue
+		}
+
+		hp := handoffProcess{Name: proc.Name, PID: handle.Pid(), Restarts: state.Restarts, Outputs: make(map[string]int)}
+		if outputs := handle.outputPipes(); outputs != nil {
+			for _, out := range outputs.pipes {
+				fd, err := inherit(out.r)
+				if err != nil {
+					pm.mu.Unlock()
+					return fmt.Errorf("failed to hand over %s of process %s: %w", out.stream, proc.Name, err)
+				}
+				hp.Outputs[out.stream] = fd
+			}
+		}
+		if pipe := pm.stdinPipes[proc.StdinFrom]; pipe != nil {
+			pipe.mu.Lock()
+			w := pipe.w
+			pipe.mu.Unlock()
+			if w != nil {
+				if hp.StdinPipe, err = inherit(w); err != nil {
+					pm.mu.Unlock()
+					return fmt.Errorf("failed to hand over stdin of process %s: %w", proc.Name, err)
+				}
+			}
+		}
+		h.Processes = append(h.Processes, hp)
+	}
+	pm.mu.Unlock()
+
+	file, err := os.CreateTemp("", "manager-handoff-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create handoff file: %w", err)
+	}
+	err = json.NewEncoder(file).Encode(h)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write handoff file: %w", err)
+	}
+
+	log.Printf("Handing over %d running processes to %s", len(h.Processes), binary)
+	env := append(os.Environ(), handoffEnv+"="+file.Name())
+	err = syscall.Exec(binary, os.Args, env)
+	os.Remove(file.Name())
+	return fmt.Errorf("exec %s: %w", binary, err)
+}
+
+// dupInheritable duplicates the descriptor of conn without close-on-exec, so
+// it survives an exec
+func dupInheritable(conn syscall.Conn) (int, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return -1, err
+	}
+	fd := -1
+	var dupErr error
+	if err := raw.Control(func(orig uintptr) {
+		fd, dupErr = syscall.Dup(int(orig))
+	}); err != nil {
+		return -1, err
+	}
+	return fd, dupErr
+}
+
+// resumeHandoff takes over from a manager that re-executed into this one. Its
+// running processes are handed to the restart loops, which adopt them on Start
+// in place of launching new instances, and its HTTP listener is returned. It
+// returns a nil listener when there is nothing to resume.
+func (pm *ProcessManager) resumeHandoff() (net.Listener, error) {
+	path := os.Getenv(handoffEnv)
+	if path == "" {
+		return nil, nil
+	}
+	// Processes started from now on must not see the handoff
+	os.Unsetenv(handoffEnv)
+	data, err := os.ReadFile(path)
+	os.Remove(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handoff file: %w", err)
+	}
+	var h handoff
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("invalid handoff file %s: %w", path, err)
+	}
+
+	for _, name := range h.Completed {
+		pm.mu.Lock()
+		pm.completed[name] = true
+		pm.mu.Unlock()
+		pm.updateState(name, func(s *ProcessState) { s.Completed = true })
+	}
+
+	for _, hp := range h.Processes {
+		proc := pm.process(hp.Name)
+		outputs := &outputPipes{}
+		for stream, fd := range hp.Outputs {
+			syscall.CloseOnExec(fd)
+			file := os.Stdout
+			if stream == "stderr" {
+				file = os.Stderr
+			}
+			var dest io.Writer = file
+			if proc != nil {
+				dest = pm.outputWriter(proc, stream, file)
+			}
+			outputs.pipes = append(outputs.pipes, outputPipe{stream: stream, r: os.NewFile(uintptr(fd), hp.Name+" "+stream), dest: dest})
+		}
+
+		var stdin *os.File
+		if hp.StdinPipe != 0 {
+			syscall.CloseOnExec(hp.StdinPipe)
+			stdin = os.NewFile(uintptr(hp.StdinPipe), hp.Name+" stdin")
+		}
+
+		handle, err := adoptProcess(hp.PID, outputs)
+		if err != nil {
+			log.Printf("Process %s: cannot adopt PID %d: %v", hp.Name, hp.PID, err)
+			outputs.close()
+			if stdin != nil {
+				stdin.Close()
+			}
+			continue
+		}
+		if stdin != nil {
+			// The producer's output goes on to the adopted consumer
+			var pipe *stdinPipe
+			if proc != nil {
+				pipe = pm.stdinPipes[proc.StdinFrom]
+			}
+			if pipe != nil {
+				pipe.mu.Lock()
+				pipe.w = stdin
+				pipe.mu.Unlock()
+			} else {
+				stdin.Close()
+			}
+		}
+		waited := newWaitedHandle(handle)
+		if proc == nil {
+			log.Printf("Process %s: no longer configured, stopping adopted PID %d", hp.Name, hp.PID)
+			go stopProcess(&Process{Name: hp.Name}, waited)
+			continue
+		}
+
+		log.Printf("Process %s: adopted PID %d from the previous manager", hp.Name, hp.PID)
+		pm.mu.Lock()
+		pm.replacements[hp.Name] = waited
+		pm.mu.Unlock()
+		pm.updateState(hp.Name, func(s *ProcessState) { s.Restarts = hp.Restarts })
+	}
+
+	if h.HTTPListenerFD == 0 {
+		return nil, nil
+	}
+	syscall.CloseOnExec(h.HTTPListenerFD)
+	file := os.NewFile(uintptr(h.HTTPListenerFD), "http listener")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume HTTP listener: %w", err)
+	}
+	return listener, nil
+}
+
+// adoptedHandle is a processHandle for a process started by the manager before
+// it re-executed. The process is still this PID's child, so it can be waited for.
+type adoptedHandle struct {
+	process *os.Process
+	state   *os.ProcessState
+	outputs *outputPipes
+}
+
+// adoptProcess takes over the running child pid, copying its output pipes
+func adoptProcess(pid int, outputs *outputPipes) (*adoptedHandle, error) {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	if err := process.Signal(syscall.Signal(0)); err != nil {
+		return nil, err
+	}
+	outputs.copy()
+	return &adoptedHandle{process: process, outputs: outputs}, nil
+}
+
+func (h *adoptedHandle) Pid() int { return h.process.Pid }
+
+func (h *adoptedHandle) Wait() error {
+	state, err := h.process.Wait()
+	h.outputs.wait()
+	if err != nil {
+		return err
+	}
+	h.state = state
+	if !state.Success() {
+		return &exec.ExitError{ProcessState: state}
+	}
+	return nil
+}
+
+func (h *adoptedHandle) ExitCode() int {
+	if h.state == nil {
+		return -1
+	}
+	return h.state.ExitCode()
+}
+
+func (h *adoptedHandle) Signal(sig os.Signal) error { return h.process.Signal(sig) }
+
+func (h *adoptedHandle) outputPipes() *outputPipes { return h.outputs }
@@ -0,0 +1,108 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// processPID returns the PID GET /status reports for the named process
+func processPID(t *testing.T, addr, name string) int {
+	t.Helper()
+	resp, err := http.Get("http://" + addr + "/status")
+	if err != nil {
+		t.Fatalf("GET /status: %v", err)
+	}
+	defer resp.Body.Close()
+	var status statusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("decoding /status: %v", err)
+	}
+	for _, state := range status.Processes {
+		if state.Name == name {
+			if !state.Running {
+				t.Fatalf("process %s not running: %+v", name, state)
+			}
+			return state.PID
+		}
+	}
+	t.Fatalf("process %s missing from /status", name)
+	return 0
+}
+
+func TestReexecKeepsProcesses(t *testing.T) {
+	dir := t.TempDir()
+	manager := buildBinary(t, dir, "./manager")
+	config := writeConfig(t, dir, "ticker.json", `{"processes": [
+		{"name": "ticker", "command": "sh", "args": ["-c", "while :; do echo tick; sleep 0.1; done"]}
+	]}`)
+
+	// A free port for the HTTP API, which has to survive the re-exec
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	var out lockedBuffer
+	cmd := exec.Command(manager, "-config", config, "-http", addr)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	defer syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+
+	waitForOutput(t, &out, "HTTP API listening", 10*time.Second)
+	waitForOutput(t, &out, "[ticker] tick", 10*time.Second)
+	pid := processPID(t, addr, "ticker")
+
+	if err := cmd.Process.Signal(syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, &out, fmt.Sprintf("Process ticker: adopted PID %d", pid), 10*time.Second)
+
+	// The child kept running and its output still reaches the new manager
+	adopted := len(out.String())
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String()[adopted:], "[ticker] tick") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no ticker output after the re-exec:\n%s", out.String())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if got := processPID(t, addr, "ticker"); got != pid {
+		t.Errorf("ticker PID after re-exec = %d, want %d", got, pid)
+	}
+
+	// The new manager tracks the child, so it stops it on shutdown
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-exited:
+		if err != nil {
+			t.Fatalf("manager exited with %v:\n%s", err, out.String())
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatalf("manager still running 30s after SIGTERM:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), fmt.Sprintf("Sending SIGTERM to process: ticker (PID: %d)", pid)) {
+		t.Errorf("adopted ticker was not stopped on shutdown:\n%s", out.String())
+	}
+	if err := syscall.Kill(pid, 0); err == nil {
+		t.Errorf("ticker PID %d still running after shutdown", pid)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"net"
+)
+
+// reexecOnSignal is a no-op: re-executing with running children is only supported on Linux
+func (pm *ProcessManager) reexecOnSignal(ctx context.Context, listener net.Listener) {}
+
+// resumeHandoff has nothing to resume where the manager cannot re-execute
+func (pm *ProcessManager) resumeHandoff() (net.Listener, error) {
+	return nil, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRestartChangesPID(t *testing.T) {
+	proc := &Process{Name: "server", Command: "sleep", Args: []string{"30"}, RestartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	first := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+
+	if err := pm.Restart(proc.Name); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	// Well within the hour-long restart delay, which an explicit restart skips
+	second := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	if second.PID == first.PID {
+		t.Errorf("PID after restart = %d, want a new process", second.PID)
+	}
+	if state := pm.States()[0]; state.PID != second.PID || !state.Running {
+		t.Errorf("state = %+v, want running as PID %d", state, second.PID)
+	}
+}
+
+func TestRestartErrors(t *testing.T) {
+	pm := NewProcessManager([]*Process{{Name: "server"}})
+	if err := pm.Restart("missing"); err == nil {
+		t.Error("Restart of an unknown process succeeded")
+	}
+	if err := pm.Restart("server"); err == nil {
+		t.Error("Restart of a process that is not running succeeded")
+	}
+}
+
+func TestHandleRestart(t *testing.T) {
+	proc := &Process{Name: "server", Command: "sleep", Args: []string{"30"}, RestartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{proc, {Name: "stopped"}})
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"server", http.StatusAccepted},
+		{"stopped", http.StatusConflict},
+		{"missing", http.StatusNotFound},
+	}
+	handler := newHTTPHandler(pm)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/processes/"+tt.name+"/restart", nil))
+			if rec.Code != tt.want {
+				t.Errorf("POST restart %s = %d, want %d", tt.name, rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	sharedconfig "multi-process-docker/internal/config"
+)
+
+const (
+	// How long a rolling restart waits for the replacement to accept connections
+	rollingReadyTimeout = 30 * time.Second
+	// Interval between connection attempts on the replacement's staging socket
+	rollingPollInterval = 100 * time.Millisecond
+)
+
+// rollingRestart starts a replacement for proc on a staging socket, renames the
+// staging socket over proc.Socket once the replacement accepts connections, and
+// only then stops the running instance. The restart loop adopts the replacement
+// when the old instance exits. If the replacement does not come up it is
+// stopped and the running instance is left serving.
+func (pm *ProcessManager) rollingRestart(proc *Process) error {
+	pm.mu.Lock()
+	old, ok := pm.running[proc.Name]
+	if !ok {
+		pm.mu.Unlock()
+		return fmt.Errorf("process %q is not running", proc.Name)
+	}
+	if pm.rolling[proc.Name] {
+		pm.mu.Unlock()
+		return fmt.Errorf("restart of process %q already in progress", proc.Name)
+	}
+	pm.rolling[proc.Name] = true
+	pm.mu.Unlock()
+
+	defer func() {
+		pm.mu.Lock()
+		delete(pm.rolling, proc.Name)
+		pm.mu.Unlock()
+	}()
+
+	log.Printf("Rolling restart requested for process: %s (PID: %d)", proc.Name, old.Pid())
+
+	// The replacement is a new start of the process, so it is prepared like
+	// one. The restart loop adopts it as it is, without running PreStart again.
+	if err := pm.runPreStart(proc); err != nil {
+		return fmt.Errorf("failed to start replacement for process %q: %w", proc.Name, err)
+	}
+
+	staging := sharedconfig.StagingSocketPath(proc.Socket)
+	cmd, err := pm.command(proc)
+	if err != nil {
+		return fmt.Errorf("failed to start replacement for process %q: %w", proc.Name, err)
+	}
+	cmd.Env = append(cmd.Environ(), sharedconfig.StagingSocketEnv+"="+staging)
+	started, err := pm.runner.Start(cmd)
+	closeStdinPipe(proc, cmd)
+	if err != nil {
+		return fmt.Errorf("failed to start replacement for process %q: %w", proc.Name, err)
+	}
+	replacement := newWaitedHandle(started)
+	log.Printf("Process %s: replacement started with PID: %d, waiting for %s", proc.Name, replacement.Pid(), staging)
+
+	if err := pm.waitForSocket(staging, replacement); err != nil {
+		stopProcess(proc, replacement)
+		return fmt.Errorf("replacement for process %q not ready: %w", proc.Name, err)
+	}
+
+	if err := pm.swapIn(proc, old, replacement, staging); err != nil {
+		stopProcess(proc, replacement)
+		return err
+	}
+	return nil
+}
+
+// swapIn atomically renames the replacement's staging socket over proc.Socket,
+// hands the replacement to the restart loop and stops the old instance
+func (pm *ProcessManager) swapIn(proc *Process, old, replacement *waitedHandle, staging string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// Once shutdown has begun the restart loop may already be gone
+	if pm.ctx.Err() != nil {
+		return fmt.Errorf("shutdown requested during rolling restart of process %q", proc.Name)
+	}
+	if pm.running[proc.Name] != old {
+		return fmt.Errorf("process %q exited during rolling restart", proc.Name)
+	}
+	if err := os.Rename(staging, proc.Socket); err != nil {
+		return fmt.Errorf("failed to swap in socket of process %q: %w", proc.Name, err)
+	}
+
+	pm.replacements[proc.Name] = replacement
+	pm.restartRequested[proc.Name] = true
+	log.Printf("Process %s: replacement now serving %s, stopping PID: %d", proc.Name, proc.Socket, old.Pid())
+	if err := old.Signal(syscall.SIGTERM); err != nil {
+		// The replacement still takes over whenever the old instance exits
+		log.Printf("Failed to send SIGTERM to %s: %v", proc.Name, err)
+	}
+	return nil
+}
+
+// waitForSocket polls until something accepts connections on the Unix socket at
+// path, failing if the replacement exits, the timeout passes or the manager shuts down
+func (pm *ProcessManager) waitForSocket(path string, replacement *waitedHandle) error {
+	deadline := time.After(rollingReadyTimeout)
+	ticker := time.NewTicker(rollingPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil
+		}
+
+		select {
+		case <-replacement.done:
+			return fmt.Errorf("exited before accepting connections: %v", replacement.err)
+		case <-deadline:
+			return fmt.Errorf("no connections accepted on %s within %v", path, rollingReadyTimeout)
+		case <-pm.ctx.Done():
+			return errors.New("shutdown requested")
+		case <-ticker.C:
+		}
+	}
+}
+
+// takeReplacement hands a replacement waiting to be adopted to the restart loop,
+// returning nil if there is none
+func (pm *ProcessManager) takeReplacement(name string) *waitedHandle {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	replacement, ok := pm.replacements[name]
+	if !ok {
+		return nil
+	}
+	delete(pm.replacements, name)
+	return replacement
+}
+
+// discardReplacement stops a replacement that the exiting restart loop will never adopt
+func (pm *ProcessManager) discardReplacement(proc *Process) {
+	pm.mu.Lock()
+	replacement, ok := pm.replacements[proc.Name]
+	delete(pm.replacements, proc.Name)
+	pm.mu.Unlock()
+
+	if ok {
+		stopProcess(proc, replacement)
+	}
+}
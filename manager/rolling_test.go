@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sharedconfig "multi-process-docker/internal/config"
+)
+
+// waitForFile polls until path exists
+func waitForFile(t *testing.T, path string, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s not created within %v", path, timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRollingRestartKeepsSocket(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the server binary")
+	}
+	bin := buildBinary(t, t.TempDir(), "multi-process-docker/server")
+	socket := filepath.Join(t.TempDir(), "grpc.sock")
+	proc := &Process{
+		Name:           "grpc-server",
+		Command:        bin,
+		Args:           []string{"-socket", socket},
+		RestartDelay:   time.Hour,
+		Socket:         socket,
+		RollingRestart: true,
+	}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	first := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	waitForFile(t, socket, 5*time.Second)
+
+	// Watch the socket path for the whole restart
+	var missing atomic.Int32
+	stop := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if _, err := os.Stat(socket); err != nil {
+				missing.Add(1)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	if err := pm.Restart(proc.Name); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventExited, 10*time.Second)
+	second := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	close(stop)
+	<-watched
+
+	if n := missing.Load(); n > 0 {
+		t.Errorf("socket missing %d times during the rolling restart", n)
+	}
+	if second.PID == first.PID {
+		t.Errorf("PID after rolling restart = %d, want the replacement", second.PID)
+	}
+	if _, err := os.Stat(sharedconfig.StagingSocketPath(socket)); !os.IsNotExist(err) {
+		t.Errorf("staging socket left behind (stat error: %v)", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := GRPCHealthCheck(socket)(ctx); err != nil {
+		t.Errorf("replacement not serving on %s: %v", socket, err)
+	}
+}
+
+func TestRollingRestartReplacementFails(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "grpc.sock")
+	proc := &Process{Name: "server", Command: "sleep", Args: []string{"30"}, RestartDelay: time.Hour, Socket: socket, RollingRestart: true}
+	pm := NewProcessManager([]*Process{proc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	first := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+
+	// The replacement exits without ever listening on the staging socket
+	proc.Args = []string{"0"}
+	if err := pm.Restart(proc.Name); err == nil {
+		t.Fatal("Restart succeeded although the replacement never accepted connections")
+	}
+	if state := pm.States()[0]; state.PID != first.PID || !state.Running {
+		t.Errorf("state = %+v, want still running as PID %d", state, first.PID)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// runAsync calls pm.Run(ctx) in the background and returns its result channel
+func runAsync(pm *ProcessManager, ctx context.Context) <-chan error {
+	result := make(chan error, 1)
+	go func() { result <- pm.Run(ctx) }()
+	return result
+}
+
+// waitRun waits for the result of runAsync
+func waitRun(t *testing.T, result <-chan error, timeout time.Duration) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		t.Fatalf("Run did not return within %v", timeout)
+		return nil
+	}
+}
+
+func TestRunStopsOnContextCancel(t *testing.T) {
+	procs := []*Process{
+		{Name: "server", Command: "sleep", Args: []string{"30"}},
+		{Name: "client", Command: "sleep", Args: []string{"30"}, DependsOn: []string{"server"}},
+	}
+	pm := NewProcessManager(procs)
+	events := pm.Events()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result := runAsync(pm, ctx)
+	waitEvent(t, events, "client", EventStarted, 10*time.Second)
+	cancel()
+
+	if err := waitRun(t, result, 10*time.Second); err != nil {
+		t.Errorf("Run() = %v, want nil after cancellation", err)
+	}
+	for _, state := range pm.States() {
+		if state.Running {
+			t.Errorf("process %s still running after Run returned", state.Name)
+		}
+	}
+}
+
+func TestRunCancelledDuringStart(t *testing.T) {
+	proc := &Process{Name: "late", Command: "sleep", Args: []string{"30"}, StartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{proc})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result := runAsync(pm, ctx)
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	if err := waitRun(t, result, 5*time.Second); err != nil {
+		t.Errorf("Run() = %v, want nil after cancellation", err)
+	}
+}
+
+func TestRunCriticalStartFailure(t *testing.T) {
+	proc := &Process{Name: "server", Command: "no-such-command-here", Critical: true}
+	pm := NewProcessManager([]*Process{proc})
+
+	if err := waitRun(t, runAsync(pm, context.Background()), 10*time.Second); err == nil {
+		t.Error("Run() = nil, want the critical start failure")
+	}
+}
+
+func TestRunCriticalGaveUp(t *testing.T) {
+	pm := NewProcessManager([]*Process{{Name: "server", Command: "sleep", Args: []string{"30"}}})
+	events := pm.Events()
+	result := runAsync(pm, context.Background())
+	waitEvent(t, events, "server", EventStarted, 5*time.Second)
+
+	pm.giveUp(&Process{Name: "migrate", Critical: true}, 0, 1, context.DeadlineExceeded)
+	if err := waitRun(t, result, 10*time.Second); err == nil {
+		t.Error("Run() = nil, want the error of the critical process that gave up")
+	}
+}
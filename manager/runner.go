@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// commandRunner creates and launches OS processes. The manager goes through it
+// rather than os/exec directly so process execution can be faked in tests.
+type commandRunner interface {
+	// Command builds the command for a process run
+	Command(ctx context.Context, name string, args ...string) *exec.Cmd
+	// Start launches cmd and returns a handle to the running process
+	Start(cmd *exec.Cmd) (processHandle, error)
+}
+
+// processHandle controls a started process
+type processHandle interface {
+	Pid() int
+	// Wait blocks until the process exits, returning a non-nil error for a non-zero exit
+	Wait() error
+	// ExitCode returns the exit code after Wait, or -1 if the process was killed by a signal
+	ExitCode() int
+	Signal(sig os.Signal) error
+}
+
+// execRunner is the default commandRunner backed by os/exec
+type execRunner struct{}
+
+func (execRunner) Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, name, args...)
+}
+
+func (execRunner) Start(cmd *exec.Cmd) (processHandle, error) {
+	handle := &execHandle{cmd: cmd}
+	writes, err := handle.outputs.pipe(cmd)
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	for _, w := range writes {
+		w.Close()
+	}
+	if err != nil {
+		handle.outputs.close()
+		return nil, err
+	}
+	handle.outputs.copy()
+	return handle, nil
+}
+
+// execHandle is a processHandle for a command started by execRunner
+type execHandle struct {
+	cmd     *exec.Cmd
+	outputs outputPipes
+}
+
+func (h *execHandle) Pid() int { return h.cmd.Process.Pid }
+
+func (h *execHandle) Wait() error {
+	err := h.cmd.Wait()
+	h.outputs.wait()
+	return err
+}
+
+func (h *execHandle) ExitCode() int { return h.cmd.ProcessState.ExitCode() }
+
+func (h *execHandle) Signal(sig os.Signal) error { return h.cmd.Process.Signal(sig) }
+
+func (h *execHandle) outputPipes() *outputPipes { return &h.outputs }
+
+// outputPipe is an output stream of a process, read by the manager from a pipe
+type outputPipe struct {
+	stream string
+	r      *os.File
+	dest   io.Writer
+}
+
+// outputPipes are the output streams of a process. The manager owns the pipes
+// rather than leaving them to os/exec so a re-exec can hand the read ends over.
+type outputPipes struct {
+	pipes   []outputPipe
+	copying sync.WaitGroup
+}
+
+// pipe replaces the command's stdout and stderr writers with pipes, returning
+// the write ends to close once the command has started
+func (o *outputPipes) pipe(cmd *exec.Cmd) ([]*os.File, error) {
+	var writes []*os.File
+	for _, out := range []struct {
+		stream string
+		w      *io.Writer
+	}{{"stdout", &cmd.Stdout}, {"stderr", &cmd.Stderr}} {
+		if *out.w == nil {
+			continue
+		}
+		if _, ok := (*out.w).(*os.File); ok {
+			continue
+		}
+		r, w, err := os.Pipe()
+		if err != nil {
+			for _, w := range writes {
+				w.Close()
+			}
+			o.close()
+			return nil, err
+		}
+		o.pipes = append(o.pipes, outputPipe{stream: out.stream, r: r, dest: *out.w})
+		*out.w = w
+		writes = append(writes, w)
+	}
+	return writes, nil
+}
+
+// copy copies each pipe to its writer in the background
+func (o *outputPipes) copy() {
+	for _, out := range o.pipes {
+		o.copying.Add(1)
+		go func() {
+			defer o.copying.Done()
+			io.Copy(out.dest, out.r)
+		}()
+	}
+}
+
+// wait blocks until the process and any children sharing its pipes have
+// closed them and everything has been copied, then closes the read ends
+func (o *outputPipes) wait() {
+	o.copying.Wait()
+	o.close()
+}
+
+func (o *outputPipes) close() {
+	for _, out := range o.pipes {
+		out.r.Close()
+	}
+}
+
+// waitedHandle is a processHandle whose Wait already runs in the background, so
+// its exit can be observed while the restart loop is blocked in Wait
+type waitedHandle struct {
+	processHandle
+	// Closed once the process has exited
+	done chan struct{}
+	err  error
+}
+
+func newWaitedHandle(handle processHandle) *waitedHandle {
+	waited := &waitedHandle{processHandle: handle, done: make(chan struct{})}
+	go func() {
+		waited.err = handle.Wait()
+		close(waited.done)
+	}()
+	return waited
+}
+
+func (h *waitedHandle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// outputPipes returns the process's output pipes, or nil if the manager does not own them
+func (h *waitedHandle) outputPipes() *outputPipes {
+	if piped, ok := h.processHandle.(interface{ outputPipes() *outputPipes }); ok {
+		return piped.outputPipes()
+	}
+	return nil
+}
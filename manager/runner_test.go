@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRunner is a commandRunner that never starts an OS process. Each run
+// exits with the next code from exits, the last one repeating; a negative
+// code makes the run last until it is signalled.
+type fakeRunner struct {
+	mu     sync.Mutex
+	exits  []int
+	starts []time.Time
+}
+
+func (r *fakeRunner) Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, name, args...)
+}
+
+func (r *fakeRunner) Start(cmd *exec.Cmd) (processHandle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run := len(r.starts)
+	r.starts = append(r.starts, time.Now())
+	code := r.exits[min(run, len(r.exits)-1)]
+	return &fakeHandle{pid: 1000 + run, code: code, signalled: make(chan struct{})}, nil
+}
+
+// startTimes returns when each run was started
+func (r *fakeRunner) startTimes() []time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Time(nil), r.starts...)
+}
+
+// fakeHandle is the processHandle of a fakeRunner run
+type fakeHandle struct {
+	pid       int
+	mu        sync.Mutex
+	code      int
+	signalled chan struct{}
+	once      sync.Once
+}
+
+func (h *fakeHandle) Pid() int { return h.pid }
+
+func (h *fakeHandle) Wait() error {
+	h.mu.Lock()
+	code := h.code
+	h.mu.Unlock()
+	if code < 0 {
+		<-h.signalled
+		return fmt.Errorf("signal: terminated")
+	}
+	if code != 0 {
+		return fmt.Errorf("exit status %d", code)
+	}
+	return nil
+}
+
+func (h *fakeHandle) ExitCode() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.code
+}
+
+func (h *fakeHandle) Signal(sig os.Signal) error {
+	h.mu.Lock()
+	exited := h.code >= 0
+	h.mu.Unlock()
+	// Like a real process, one that has exited can no longer be signalled
+	if exited {
+		return os.ErrProcessDone
+	}
+	h.once.Do(func() { close(h.signalled) })
+	return nil
+}
+
+func TestRestartOnFailure(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	proc := &Process{Name: "flaky", Command: "flaky", RestartDelay: delay}
+	pm := NewProcessManager([]*Process{proc})
+	runner := &fakeRunner{exits: []int{1}}
+	pm.runner = runner
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	for range 3 {
+		waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	}
+	pm.Shutdown()
+
+	starts := runner.startTimes()
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < delay {
+			t.Errorf("run %d started %v after the previous one, want at least %v", i, gap, delay)
+		}
+	}
+	state := pm.States()[0]
+	if state.Restarts < 2 || state.LastExitCode != 1 {
+		t.Errorf("state = %+v, want at least 2 restarts and last exit code 1", state)
+	}
+}
+
+func TestShutdownSignalsRunningProcess(t *testing.T) {
+	proc := &Process{Name: "server", Command: "server"}
+	pm := NewProcessManager([]*Process{proc})
+	runner := &fakeRunner{exits: []int{-1}}
+	pm.runner = runner
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	pm.Shutdown()
+
+	if n := len(runner.startTimes()); n != 1 {
+		t.Errorf("process started %d times, want 1", n)
+	}
+	if state := pm.States()[0]; state.Running {
+		t.Error("process still running after Shutdown")
+	}
+}
+
+func TestRestartDelayAppliedOnce(t *testing.T) {
+	const delay = 300 * time.Millisecond
+	proc := &Process{Name: "server", Command: "server", RestartDelay: delay}
+	pm := NewProcessManager([]*Process{proc})
+	// Runs until restarted, then crashes once, then runs until shutdown
+	runner := &fakeRunner{exits: []int{-1, 1, -1}}
+	pm.runner = runner
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	if err := pm.Restart(proc.Name); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	pm.Shutdown()
+
+	starts := runner.startTimes()
+	if len(starts) != 3 {
+		t.Fatalf("got %d starts, want 3", len(starts))
+	}
+	if gap := starts[1].Sub(starts[0]); gap >= delay {
+		t.Errorf("manual restart took %v, want it to skip the %v delay", gap, delay)
+	}
+	if gap := starts[2].Sub(starts[1]); gap < delay || gap >= 2*delay {
+		t.Errorf("restart after a crash took %v, want one %v delay", gap, delay)
+	}
+}
+
+// gatedRunner is a fakeRunner whose starts after the first are held until
+// release is closed, with starting closed once the second one is waiting
+type gatedRunner struct {
+	fakeRunner
+	starting chan struct{}
+	release  chan struct{}
+	handles  []*fakeHandle
+}
+
+func (r *gatedRunner) Start(cmd *exec.Cmd) (processHandle, error) {
+	if len(r.startTimes()) > 0 {
+		close(r.starting)
+		<-r.release
+	}
+	handle, err := r.fakeRunner.Start(cmd)
+	r.handles = append(r.handles, handle.(*fakeHandle))
+	return handle, err
+}
+
+func TestShutdownStopsRunStartedDuringShutdown(t *testing.T) {
+	proc := &Process{Name: "server", Command: "server"}
+	pm := NewProcessManager([]*Process{proc})
+	runner := &gatedRunner{fakeRunner: fakeRunner{exits: []int{-1}}, starting: make(chan struct{}), release: make(chan struct{})}
+	pm.runner = runner
+	events := pm.Events()
+
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	if err := pm.Restart(proc.Name); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	<-runner.starting
+
+	// The restarted run only starts once shutdown has found nothing running
+	shutDown := make(chan struct{})
+	go func() {
+		pm.Shutdown()
+		close(shutDown)
+	}()
+	for pm.ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(runner.release)
+
+	select {
+	case <-shutDown:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not return with a run started during it still running")
+	}
+	select {
+	case <-runner.handles[1].signalled:
+	default:
+		t.Error("run started during shutdown was never signalled")
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentShutdown(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	proc := &Process{Name: "sleeper", Command: "sleep", Args: []string{"30"}}
+	pm := NewProcessManager([]*Process{proc})
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pm.Shutdown()
+			// Every caller returns only once the teardown has finished
+			if pm.States()[0].Running {
+				t.Error("Shutdown returned while the process was still running")
+			}
+		}()
+	}
+	wg.Wait()
+	pm.Shutdown()
+
+	log.SetOutput(os.Stderr)
+	for _, line := range []string{"initiating graceful shutdown", "Sending SIGTERM to process: sleeper", "shutdown complete"} {
+		if n := strings.Count(logs.String(), line); n != 1 {
+			t.Errorf("%q logged %d times, want once", line, n)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartStagger(t *testing.T) {
+	const stagger = 700 * time.Millisecond
+	const startDelay = 300 * time.Millisecond
+	procs := []*Process{
+		{Name: "first", Command: "sleep", Args: []string{"5"}, StartDelay: startDelay},
+		{Name: "second", Command: "sleep", Args: []string{"5"}},
+	}
+	pm := NewProcessManager(procs)
+	pm.Stagger = stagger
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	begin := time.Now()
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	first := waitEvent(t, events, "first", EventStarted, 5*time.Second)
+	second := waitEvent(t, events, "second", EventStarted, 5*time.Second)
+	if gap := first.Time.Sub(begin); gap < startDelay {
+		t.Errorf("first process launched %v after Start, want at least its StartDelay %v", gap, startDelay)
+	}
+	if gap := second.Time.Sub(first.Time); gap < stagger {
+		t.Errorf("launches spaced by %v, want at least the stagger %v", gap, stagger)
+	}
+}
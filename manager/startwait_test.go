@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStartWait(t *testing.T) {
+	procs := []*Process{
+		{Name: "default", Command: "sleep", Args: []string{"5"}},
+		{Name: "override", Command: "sleep", Args: []string{"5"}, StartWait: 100 * time.Millisecond},
+	}
+	pm := NewProcessManager(procs)
+	pm.StartWait = 800 * time.Millisecond
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	begin := time.Now()
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	elapsed := time.Since(begin)
+
+	second := waitEvent(t, events, "override", EventStarted, 5*time.Second)
+	if gap := second.Time.Sub(begin); gap < pm.StartWait {
+		t.Errorf("second process launched %v after Start, want at least the manager's StartWait %v", gap, pm.StartWait)
+	}
+	if wait := elapsed - second.Time.Sub(begin); wait >= pm.StartWait {
+		t.Errorf("Start returned %v after the last launch, want its StartWait override of %v", wait, procs[1].StartWait)
+	}
+}
+
+func TestCriticalWait(t *testing.T) {
+	const criticalWait = 700 * time.Millisecond
+	procs := []*Process{
+		{Name: "critical", Command: "sleep", Args: []string{"5"}, Critical: true, MinStableRun: 100 * time.Millisecond, CriticalWait: criticalWait},
+		{Name: "next", Command: "sleep", Args: []string{"5"}},
+	}
+	pm := NewProcessManager(procs)
+	pm.CriticalWait = time.Hour
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	begin := time.Now()
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	second := waitEvent(t, events, "next", EventStarted, 5*time.Second)
+	if gap, want := second.Time.Sub(begin), procs[0].MinStableRun+criticalWait; gap < want {
+		t.Errorf("next process launched %v after Start, want at least %v", gap, want)
+	}
+}
+
+func TestCriticalWaitForReadiness(t *testing.T) {
+	var ready atomic.Bool
+	procs := []*Process{
+		{
+			Name:         "critical",
+			Command:      "sleep",
+			Args:         []string{"5"},
+			Critical:     true,
+			MinStableRun: 100 * time.Millisecond,
+			ReadyCheck: func(context.Context) error {
+				if !ready.Load() {
+					return errors.New("not ready")
+				}
+				return nil
+			},
+			ReadyInterval: 50 * time.Millisecond,
+		},
+		{Name: "next", Command: "sleep", Args: []string{"5"}},
+	}
+	pm := NewProcessManager(procs)
+	// Superseded by the readiness check
+	pm.CriticalWait = time.Hour
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	started := make(chan error, 1)
+	go func() { started <- pm.Start() }()
+
+	first := waitEvent(t, events, "critical", EventStarted, 5*time.Second)
+	const readyAfter = 500 * time.Millisecond
+	time.AfterFunc(readyAfter, func() { ready.Store(true) })
+	second := waitEvent(t, events, "next", EventStarted, 5*time.Second)
+	if err := <-started; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if gap := second.Time.Sub(first.Time); gap < readyAfter {
+		t.Errorf("next process launched %v after the critical one, before it was ready", gap)
+	}
+}
+
+func TestCriticalExitsBeforeReady(t *testing.T) {
+	procs := []*Process{
+		{
+			Name:          "critical",
+			Command:       "sh",
+			Args:          []string{"-c", "sleep 0.3; exit 1"},
+			Critical:      true,
+			MinStableRun:  100 * time.Millisecond,
+			ReadyCheck:    func(context.Context) error { return errors.New("not ready") },
+			ReadyInterval: 50 * time.Millisecond,
+		},
+		{Name: "next", Command: "sleep", Args: []string{"5"}},
+	}
+	pm := NewProcessManager(procs)
+	pm.readyTimeout = time.Minute
+	defer pm.Shutdown()
+
+	begin := time.Now()
+	err := pm.Start()
+	if err == nil || !strings.Contains(err.Error(), "exited before becoming ready") {
+		t.Fatalf("Start() error = %v, want the critical process to have exited before becoming ready", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Errorf("Start failed after %v, want it to fail soon after the crash rather than after the ready timeout", elapsed)
+	}
+	if state, _ := stateOf(pm, "next"); state.PID != 0 {
+		t.Errorf("next process started with PID %d after the critical one crashed", state.PID)
+	}
+}
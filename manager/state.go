@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// ExitReason classifies how a run of a process ended
+type ExitReason string
+
+const (
+	// The process exited with code 0
+	ExitNormal ExitReason = "normal"
+	// The process exited with a non-zero code
+	ExitFailure ExitReason = "failure"
+	// The process was killed by a signal it did not handle
+	ExitSignaled ExitReason = "signaled"
+	// The process was terminated for exceeding its MaxRuntime
+	ExitTimedOut ExitReason = "timed-out"
+)
+
+// ProcessState is a snapshot of a managed process's runtime status
+type ProcessState struct {
+	Name    string `json:"name"`
+	PID     int    `json:"pid"`
+	Running bool   `json:"running"`
+	// Result of the last ReadyCheck probe (always false until the first probe passes)
+	Ready     bool      `json:"ready"`
+	Restarts  int       `json:"restarts"`
+	StartedAt time.Time `json:"startedAt"`
+	// Total run time of completed runs
+	Uptime time.Duration `json:"uptimeNs"`
+	// Exit code of the most recent run (-1 if it has not exited or was killed by a signal)
+	LastExitCode int `json:"lastExitCode"`
+	// Whether the most recent run was terminated for exceeding its MaxRuntime
+	TimedOut bool `json:"timedOut"`
+	// How the most recent run ended (empty until it has exited)
+	ExitReason ExitReason `json:"exitReason,omitempty"`
+	// Signal that killed the most recent run, if any
+	ExitSignal syscall.Signal `json:"exitSignal,omitempty"`
+	// Whether a task has run to completion
+	Completed bool `json:"completed,omitempty"`
+}
+
+// classifyExit derives the exit reason of a run from the error returned by
+// Wait, along with the signal that killed the process, if any
+func classifyExit(err error, timedOut bool) (ExitReason, syscall.Signal) {
+	var signal syscall.Signal
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			signal = status.Signal()
+		}
+	}
+
+	switch {
+	case timedOut:
+		return ExitTimedOut, signal
+	case signal != 0:
+		return ExitSignaled, signal
+	case err != nil:
+		return ExitFailure, 0
+	default:
+		return ExitNormal, 0
+	}
+}
+
+// updateState applies fn to the state of the named process under the manager lock
+func (pm *ProcessManager) updateState(name string, fn func(*ProcessState)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	state, ok := pm.states[name]
+	if !ok {
+		state = &ProcessState{Name: name, LastExitCode: -1}
+		pm.states[name] = state
+	}
+	fn(state)
+}
+
+// States returns a copy of the current state of every managed process, in configuration order
+func (pm *ProcessManager) States() []ProcessState {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	states := make([]ProcessState, 0, len(pm.processes))
+	for _, proc := range pm.processes {
+		states = append(states, *pm.states[proc.Name])
+	}
+	return states
+}
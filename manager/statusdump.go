@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// dumpStatusOnSignal writes the process table to w each time the manager
+// receives SIGUSR1, until ctx is done. Managed processes are not signalled.
+func (pm *ProcessManager) dumpStatusOnSignal(ctx context.Context, w io.Writer) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR1)
+	go func() {
+		defer signal.Stop(sigChan)
+		for {
+			select {
+			case <-sigChan:
+				pm.dumpStatus(w)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// dumpStatus writes the state of every process as a table, or as one JSON object in JSON log mode
+func (pm *ProcessManager) dumpStatus(w io.Writer) {
+	states := pm.States()
+
+	if pm.LogFormat == logFormatJSON {
+		data, err := json.Marshal(struct {
+			Status []ProcessState `json:"status"`
+		}{states})
+		if err != nil {
+			log.Printf("Failed to encode status: %v", err)
+			return
+		}
+		w.Write(append(data, '\n'))
+		return
+	}
+
+	// Written in one go so the table is not interleaved with other output
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROCESS\tPID\tSTATE\tRESTARTS\tUPTIME\tLAST EXIT")
+	for _, state := range states {
+		pid, uptime := "-", time.Duration(0)
+		if state.Running {
+			pid = fmt.Sprint(state.PID)
+			uptime = time.Since(state.StartedAt).Round(time.Second)
+		}
+		lastExit := "-"
+		if state.ExitReason != "" {
+			lastExit = fmt.Sprintf("%d (%s)", state.LastExitCode, state.ExitReason)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%v\t%s\n", state.Name, pid, processStatus(state), state.Restarts, uptime, lastExit)
+	}
+	tw.Flush()
+	w.Write(buf.Bytes())
+}
+
+// processStatus describes the state of a process in one word
+func processStatus(state ProcessState) string {
+	switch {
+	case state.Running && state.Ready:
+		return "ready"
+	case state.Running:
+		return "running"
+	case state.ExitReason != "":
+		return "exited"
+	default:
+		return "stopped"
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDumpStatusOnSignal(t *testing.T) {
+	sleeper := &Process{Name: "sleeper", Command: "sleep", Args: []string{"30"}}
+	failed := &Process{Name: "failed", Command: "sh", Args: []string{"-c", "exit 3"}, RestartDelay: time.Hour}
+	pm := NewProcessManager([]*Process{sleeper, failed})
+	defer pm.Shutdown()
+	events := pm.Events()
+	// Started one at a time, so waiting for the events of one does not skip
+	// those of the other
+	if err := pm.startProcess(sleeper, false); err != nil {
+		t.Fatalf("startProcess %s: %v", sleeper.Name, err)
+	}
+	started := waitEvent(t, events, sleeper.Name, EventStarted, 5*time.Second)
+	if err := pm.startProcess(failed, false); err != nil {
+		t.Fatalf("startProcess %s: %v", failed.Name, err)
+	}
+	// Restarting follows the restart count being recorded
+	waitEvent(t, events, failed.Name, EventRestarting, 5*time.Second)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pm.dumpStatusOnSignal(ctx, w)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	r.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4096)
+	n, err := r.Read(buf)
+	if err != nil {
+		t.Fatalf("reading status dump: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(buf[:n])), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("status dump = %q, want a header and one line per process", lines)
+	}
+	if fields := strings.Fields(lines[0]); fields[0] != "PROCESS" || fields[1] != "PID" || fields[2] != "STATE" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if want := []string{"sleeper", fmt.Sprint(started.PID), "running", "0"}; !reflect.DeepEqual(strings.Fields(lines[1])[:4], want) {
+		t.Errorf("sleeper line = %q, want it to start with %q", lines[1], want)
+	}
+	if want := []string{"failed", "-", "exited", "1"}; !reflect.DeepEqual(strings.Fields(lines[2])[:4], want) {
+		t.Errorf("failed line = %q, want it to start with %q", lines[2], want)
+	}
+	if !strings.HasSuffix(lines[2], "3 (failure)") {
+		t.Errorf("failed line = %q, want the last exit 3 (failure)", lines[2])
+	}
+
+	// The managed process was left alone
+	if state := pm.States()[0]; !state.Running || state.PID != started.PID {
+		t.Errorf("sleeper state after SIGUSR1 = %+v, want it still running", state)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// Time a process is given to exit after SIGTERM when it has no StopSignals
+const defaultStopTimeout = 30 * time.Second
+
+// Time a running task is left to complete on shutdown, when it has no
+// DrainTimeout, before its stop sequence begins. It is taken out of the stop
+// sequence's own time, so it must stay well short of defaultStopTimeout.
+const defaultTaskDrainTimeout = 10 * time.Second
+
+// StopStep is one step of a process's stop sequence
+type StopStep struct {
+	Signal syscall.Signal
+	// How long to wait for the process to exit before the next step
+	Wait time.Duration
+}
+
+// signalsByName are the signals a stop sequence may be configured with
+var signalsByName = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// parseSignal resolves a signal name such as "SIGINT" or "INT"
+func parseSignal(name string) (syscall.Signal, error) {
+	if sig, ok := signalsByName[name]; ok {
+		return sig, nil
+	}
+	if sig, ok := signalsByName["SIG"+name]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unsupported signal %q", name)
+}
+
+// signalName returns the conventional name of sig, such as "SIGTERM"
+func signalName(sig syscall.Signal) string {
+	for name, s := range signalsByName {
+		if s == sig {
+			return name
+		}
+	}
+	return sig.String()
+}
+
+// stopSequence returns the configured stop sequence, or the default one
+func (proc *Process) stopSequence() []StopStep {
+	if len(proc.StopSignals) == 0 {
+		return []StopStep{{Signal: syscall.SIGTERM, Wait: defaultStopTimeout}}
+	}
+	return proc.StopSignals
+}
+
+// stopBudget returns the longest proc's stop sequence waits before it kills
+func (proc *Process) stopBudget() time.Duration {
+	var total time.Duration
+	for _, step := range proc.stopSequence() {
+		total += step.Wait
+	}
+	return total
+}
+
+// drainTimeout returns how long proc is left to exit on its own on shutdown
+// before it is stopped. Tasks are drained, as they hold work that is lost if
+// they are cut short; daemons are stopped straight away. The drain is taken
+// out of the stop sequence, so by default it leaves at least half of it.
+func (proc *Process) drainTimeout() time.Duration {
+	if !proc.isTask() {
+		return 0
+	}
+	if proc.DrainTimeout > 0 {
+		return min(proc.DrainTimeout, proc.stopBudget())
+	}
+	return min(defaultTaskDrainTimeout, proc.stopBudget()/2)
+}
+
+// drainProcess waits up to proc's drain timeout for handle to exit by itself,
+// then takes it through what is left of its stop sequence. A drained task is
+// killed no later than its stop sequence alone would have killed it.
+func drainProcess(proc *Process, handle *waitedHandle) {
+	deadline := time.Now().Add(proc.stopBudget())
+	if drain := proc.drainTimeout(); drain > 0 {
+		log.Printf("Waiting up to %v for task %s (PID: %d) to complete before stopping it", drain, proc.Name, handle.Pid())
+		select {
+		case <-handle.done:
+			return
+		case <-time.After(drain):
+			log.Printf("Task %s did not complete within %v", proc.Name, drain)
+		}
+	}
+	stopProcessBy(proc, handle, deadline)
+}
+
+// stopProcess sends each signal of proc's stop sequence to handle until it
+// exits, killing it once the sequence is exhausted, and waits for the exit
+func stopProcess(proc *Process, handle *waitedHandle) {
+	stopProcessBy(proc, handle, time.Now().Add(proc.stopBudget()))
+}
+
+// stopProcessBy is stopProcess with each wait of the stop sequence cut short
+// so that handle is killed by deadline at the latest
+func stopProcessBy(proc *Process, handle *waitedHandle, deadline time.Time) {
+	for _, step := range proc.stopSequence() {
+		select {
+		case <-handle.done:
+			return
+		default:
+		}
+
+		log.Printf("Sending %s to process: %s (PID: %d)", signalName(step.Signal), proc.Name, handle.Pid())
+		if err := handle.Signal(step.Signal); err != nil {
+			log.Printf("Failed to send %s to %s: %v", signalName(step.Signal), proc.Name, err)
+		}
+
+		select {
+		case <-handle.done:
+			return
+		case <-time.After(min(step.Wait, time.Until(deadline))):
+		}
+	}
+
+	log.Printf("Force killing process: %s (PID: %d)", proc.Name, handle.Pid())
+	handle.Signal(os.Kill)
+	<-handle.done
+}
+
+// shutdownTiers groups processes into the order they are stopped in. Higher
+// ShutdownPriority tiers go first; within a priority, processes deeper in the
+// dependency graph go first, so dependents stop before what they depend on.
+// The processes of one tier are stopped together.
+func shutdownTiers(processes []*Process) [][]*Process {
+	byName := make(map[string]*Process, len(processes))
+	for _, proc := range processes {
+		byName[proc.Name] = proc
+	}
+
+	// depth is the length of the longest dependency chain below a process
+	depths := make(map[string]int, len(processes))
+	visiting := make(map[string]bool)
+	var depth func(proc *Process) int
+	depth = func(proc *Process) int {
+		if d, ok := depths[proc.Name]; ok {
+			return d
+		}
+		if visiting[proc.Name] {
+			// Cycles are rejected by Start, but never loop on one here
+			return 0
+		}
+		visiting[proc.Name] = true
+		d := 0
+		for _, dep := range proc.DependsOn {
+			if depProc, ok := byName[dep]; ok {
+				d = max(d, depth(depProc)+1)
+			}
+		}
+		visiting[proc.Name] = false
+		depths[proc.Name] = d
+		return d
+	}
+
+	type tierKey struct{ priority, depth int }
+	tiers := make(map[tierKey][]*Process)
+	var keys []tierKey
+	for _, proc := range processes {
+		key := tierKey{proc.ShutdownPriority, depth(proc)}
+		if _, ok := tiers[key]; !ok {
+			keys = append(keys, key)
+		}
+		tiers[key] = append(tiers[key], proc)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].priority != keys[j].priority {
+			return keys[i].priority > keys[j].priority
+		}
+		return keys[i].depth > keys[j].depth
+	})
+
+	ordered := make([][]*Process, 0, len(keys))
+	for _, key := range keys {
+		ordered = append(ordered, tiers[key])
+	}
+	return ordered
+}
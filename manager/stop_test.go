@@ -0,0 +1,250 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// onlyInterrupt is a shell script that ignores SIGTERM and exits cleanly on SIGINT
+const onlyInterrupt = `trap '' TERM; trap 'exit 0' INT; while :; do sleep 0.05; done`
+
+func TestStopSignalsEscalate(t *testing.T) {
+	const termWait = 300 * time.Millisecond
+	proc := &Process{
+		Name:    "stubborn",
+		Command: "sh",
+		Args:    []string{"-c", onlyInterrupt},
+		StopSignals: []StopStep{
+			{Signal: syscall.SIGTERM, Wait: termWait},
+			{Signal: syscall.SIGINT, Wait: 5 * time.Second},
+		},
+	}
+	pm := NewProcessManager([]*Process{proc})
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	// Let the shell install its traps
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	pm.Shutdown()
+	took := time.Since(start)
+
+	if took < termWait {
+		t.Errorf("shutdown took %v, want the process to outlast the %v SIGTERM step", took, termWait)
+	}
+	// A clean exit means SIGINT stopped it before SIGKILL was needed
+	if state := pm.States()[0]; state.ExitReason != ExitNormal {
+		t.Errorf("exit = %s/%v, want a clean exit on SIGINT", state.ExitReason, state.ExitSignal)
+	}
+}
+
+func TestStopSignalsEndInKill(t *testing.T) {
+	proc := &Process{
+		Name:        "immortal",
+		Command:     "sh",
+		Args:        []string{"-c", `trap '' TERM INT; while :; do sleep 0.05; done`},
+		StopSignals: []StopStep{{Signal: syscall.SIGINT, Wait: 200 * time.Millisecond}},
+	}
+	pm := NewProcessManager([]*Process{proc})
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	time.Sleep(200 * time.Millisecond)
+
+	pm.Shutdown()
+	if state := pm.States()[0]; state.ExitReason != ExitSignaled || state.ExitSignal != syscall.SIGKILL {
+		t.Errorf("exit = %s/%v, want %s/%v", state.ExitReason, state.ExitSignal, ExitSignaled, syscall.SIGKILL)
+	}
+}
+
+func TestShutdownStopsDependentsFirst(t *testing.T) {
+	// The client is slow to exit on SIGTERM and the server is quick, so the
+	// server would exit first if both were signalled together
+	server := &Process{Name: "server", Command: "sh", Args: []string{"-c", "trap 'exit 0' TERM; while :; do sleep 0.05; done"}}
+	client := &Process{Name: "client", Command: "sh", Args: []string{"-c", "trap 'sleep 0.3; exit 0' TERM; while :; do sleep 0.05; done"}, DependsOn: []string{"server"}}
+	pm := NewProcessManager([]*Process{server, client})
+	events := pm.Events()
+	for _, proc := range []*Process{server, client} {
+		if err := pm.startProcess(proc, false); err != nil {
+			t.Fatalf("startProcess %s: %v", proc.Name, err)
+		}
+		waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	pm.Shutdown()
+
+	var exited []string
+	for len(exited) < 2 {
+		select {
+		case ev := <-events:
+			if ev.Type == EventExited {
+				exited = append(exited, ev.Name)
+			}
+		default:
+			t.Fatalf("exit events = %q, want both processes", exited)
+		}
+	}
+	if want := []string{"client", "server"}; !reflect.DeepEqual(exited, want) {
+		t.Errorf("exit order = %q, want %q", exited, want)
+	}
+}
+
+func TestShutdownTiers(t *testing.T) {
+	tests := []struct {
+		name      string
+		processes []*Process
+		want      [][]string
+	}{
+		{"independent", procs("a", "b"), [][]string{{"a", "b"}}},
+		{"reverse dependency order", procs("server", "client:server", "sidecar:client"), [][]string{{"sidecar"}, {"client"}, {"server"}}},
+		{"shared depth", procs("server", "a:server", "b:server"), [][]string{{"a", "b"}, {"server"}}},
+		{"priority first", []*Process{{Name: "server"}, {Name: "client", DependsOn: []string{"server"}}, {Name: "db", ShutdownPriority: 1}}, [][]string{{"db"}, {"client"}, {"server"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			for _, tier := range shutdownTiers(tt.processes) {
+				got = append(got, names(tier))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shutdownTiers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShutdownLetsTaskComplete(t *testing.T) {
+	// The task is near done when shutdown begins, and would fail if stopped
+	task := &Process{Name: "migrate", Command: "sh", Args: []string{"-c", "trap 'exit 1' TERM; sleep 0.4; exit 0"}, Kind: KindTask}
+	daemon := &Process{Name: "server", Command: "sleep", Args: []string{"30"}}
+	pm := NewProcessManager([]*Process{task, daemon})
+	events := pm.Events()
+	for _, proc := range []*Process{task, daemon} {
+		if err := pm.startProcess(proc, false); err != nil {
+			t.Fatalf("startProcess %s: %v", proc.Name, err)
+		}
+		waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	}
+
+	start := time.Now()
+	pm.Shutdown()
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("shutdown took %v, want it to end once the task completed", took)
+	}
+
+	if state, _ := stateOf(pm, "migrate"); !state.Completed || state.ExitReason != ExitNormal {
+		t.Errorf("task exit = %s/%v, completed %v, want it to complete during shutdown", state.ExitReason, state.ExitSignal, state.Completed)
+	}
+	// The daemon is stopped without waiting for it
+	if state, _ := stateOf(pm, "server"); state.ExitReason != ExitSignaled || state.ExitSignal != syscall.SIGTERM {
+		t.Errorf("daemon exit = %s/%v, want %s/%v", state.ExitReason, state.ExitSignal, ExitSignaled, syscall.SIGTERM)
+	}
+}
+
+func TestShutdownStopsTaskAfterDrainTimeout(t *testing.T) {
+	const drain = 200 * time.Millisecond
+	task := &Process{Name: "backfill", Command: "sleep", Args: []string{"30"}, Kind: KindTask, DrainTimeout: drain}
+	pm := NewProcessManager([]*Process{task})
+	events := pm.Events()
+	if err := pm.startProcess(task, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, task.Name, EventStarted, 5*time.Second)
+
+	start := time.Now()
+	pm.Shutdown()
+	if took := time.Since(start); took < drain || took > 5*time.Second {
+		t.Errorf("shutdown took %v, want the task stopped once its %v drain timeout passed", took, drain)
+	}
+	if state, _ := stateOf(pm, task.Name); state.Completed || state.ExitSignal != syscall.SIGTERM {
+		t.Errorf("task exit = %s/%v, completed %v, want it stopped with SIGTERM", state.ExitReason, state.ExitSignal, state.Completed)
+	}
+}
+
+func TestShutdownDrainIsTakenFromStopSequence(t *testing.T) {
+	// The task ignores SIGTERM, so it is only stopped by the kill at the end
+	// of its stop sequence
+	const stopWait = 1500 * time.Millisecond
+	task := &Process{
+		Name:         "backfill",
+		Command:      "sh",
+		Args:         []string{"-c", "trap '' TERM; while :; do sleep 0.1; done"},
+		Kind:         KindTask,
+		DrainTimeout: time.Second,
+		StopSignals:  []StopStep{{Signal: syscall.SIGTERM, Wait: stopWait}},
+	}
+	pm := NewProcessManager([]*Process{task})
+	events := pm.Events()
+	if err := pm.startProcess(task, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitEvent(t, events, task.Name, EventStarted, 5*time.Second)
+
+	// Draining for 1s leaves 500ms of the stop sequence's 1.5s, rather than
+	// adding 1s to it
+	start := time.Now()
+	pm.Shutdown()
+	if took := time.Since(start); took < stopWait || took > stopWait+700*time.Millisecond {
+		t.Errorf("shutdown took %v, want about the %v of the stop sequence", took, stopWait)
+	}
+	if state, _ := stateOf(pm, task.Name); state.ExitSignal != syscall.SIGKILL {
+		t.Errorf("task exit = %s/%v, want it killed", state.ExitReason, state.ExitSignal)
+	}
+}
+
+func TestShutdownDrainsDefaultTaskBeforeSignalling(t *testing.T) {
+	// With a 2s stop sequence the default drain is 1s. The task exits as soon
+	// as it is sent SIGTERM, so shutdown lasts as long as it was left to drain.
+	task := &Process{
+		Name:        "backfill",
+		Command:     "sh",
+		Args:        []string{"-c", "trap 'echo terminated; exit 0' TERM; echo ready; while :; do sleep 0.05; done"},
+		Kind:        KindTask,
+		StopSignals: []StopStep{{Signal: syscall.SIGTERM, Wait: 2 * time.Second}},
+	}
+	drain := task.drainTimeout()
+	if drain != time.Second {
+		t.Fatalf("drainTimeout() = %v, want 1s", drain)
+	}
+	pm := NewProcessManager([]*Process{task})
+	if err := pm.startProcess(task, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitForTail(t, pm, task.Name, "ready")
+
+	start := time.Now()
+	pm.Shutdown()
+	if took := time.Since(start); took < drain || took > drain+700*time.Millisecond {
+		t.Errorf("shutdown took %v, want SIGTERM sent once the full %v drain passed", took, drain)
+	}
+	if lines := pm.logBuffer(task.Name).tail(10); !slices.Contains(lines, "terminated") {
+		t.Errorf("task output = %q, want it sent SIGTERM", lines)
+	}
+}
+
+func TestDrainTimeoutDefault(t *testing.T) {
+	tests := []struct {
+		name string
+		proc *Process
+		want time.Duration
+	}{
+		{"daemon", &Process{}, 0},
+		{"task", &Process{Kind: KindTask}, defaultTaskDrainTimeout},
+		{"task with a short stop sequence", &Process{Kind: KindTask, StopSignals: []StopStep{{Signal: syscall.SIGTERM, Wait: 4 * time.Second}}}, 2 * time.Second},
+		{"configured", &Process{Kind: KindTask, DrainTimeout: 20 * time.Second}, 20 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := tt.proc.drainTimeout(); got != tt.want {
+			t.Errorf("%s: drainTimeout() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
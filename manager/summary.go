@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// processSummary is the per-process entry of the shutdown report
+type processSummary struct {
+	Name         string   `json:"name"`
+	Restarts     int      `json:"restarts"`
+	Uptime       duration `json:"uptime"`
+	LastExitCode int      `json:"lastExitCode"`
+}
+
+// Summary returns restart, uptime, and exit statistics for every managed process
+func (pm *ProcessManager) Summary() []processSummary {
+	states := pm.States()
+	summary := make([]processSummary, 0, len(states))
+	for _, state := range states {
+		uptime := state.Uptime
+		if state.Running {
+			uptime += time.Since(state.StartedAt)
+		}
+		summary = append(summary, processSummary{
+			Name:         state.Name,
+			Restarts:     state.Restarts,
+			Uptime:       duration(uptime.Round(time.Millisecond)),
+			LastExitCode: state.LastExitCode,
+		})
+	}
+	return summary
+}
+
+// printSummary writes the shutdown report as a table, or as one JSON object in JSON log mode
+func (pm *ProcessManager) printSummary() {
+	summary := pm.Summary()
+
+	if pm.LogFormat == logFormatJSON {
+		data, err := json.Marshal(struct {
+			Summary []processSummary `json:"summary"`
+		}{summary})
+		if err != nil {
+			log.Printf("Failed to encode shutdown summary: %v", err)
+			return
+		}
+		pm.console(os.Stderr).Write(append(data, '\n'))
+		return
+	}
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROCESS\tRESTARTS\tUPTIME\tLAST EXIT")
+	for _, s := range summary {
+		lastExit := "-"
+		if s.LastExitCode >= 0 {
+			lastExit = fmt.Sprint(s.LastExitCode)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%s\n", s.Name, s.Restarts, time.Duration(s.Uptime).Round(time.Second), lastExit)
+	}
+	tw.Flush()
+
+	log.Println("Process summary:")
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		log.Println("  " + line)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummaryCountsRestarts(t *testing.T) {
+	crasher := &Process{Name: "crasher", Command: "sh", Args: []string{"-c", "exit 2"}, RestartDelay: 50 * time.Millisecond}
+	steady := &Process{Name: "steady", Command: "sleep", Args: []string{"5"}}
+	pm := NewProcessManager([]*Process{crasher, steady})
+	events := pm.Events()
+
+	for _, proc := range []*Process{crasher, steady} {
+		if err := pm.startProcess(proc, false); err != nil {
+			t.Fatalf("startProcess(%s): %v", proc.Name, err)
+		}
+	}
+	waitEvent(t, events, steady.Name, EventStarted, 5*time.Second)
+	for range 3 {
+		waitEvent(t, events, crasher.Name, EventRestarting, 5*time.Second)
+	}
+	pm.Shutdown()
+
+	summary := make(map[string]processSummary)
+	for _, s := range pm.Summary() {
+		summary[s.Name] = s
+	}
+	if got := summary["crasher"]; got.Restarts < 3 || got.LastExitCode != 2 {
+		t.Errorf("crasher summary = %+v, want at least 3 restarts and last exit code 2", got)
+	}
+	if got := summary["steady"]; got.Restarts != 0 || got.Uptime <= 0 {
+		t.Errorf("steady summary = %+v, want no restarts and some uptime", got)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResourceUsage is a sample of a running process's CPU and memory use
+type ResourceUsage struct {
+	PID int
+	// CPU time, user and system, used by the process since it started
+	CPUSeconds float64
+	// Share of one CPU used since the previous sample of the same process
+	// (0 for its first sample)
+	CPUPercent float64
+	// Resident set size in bytes
+	RSS uint64
+	// When the sample was taken
+	Time time.Time
+}
+
+// sampleUsage samples the resource usage of every running process each
+// UsageInterval until shutdown, logging a line per sample and keeping the
+// latest for the metrics endpoint
+func (pm *ProcessManager) sampleUsage() {
+	ticker := time.NewTicker(pm.UsageInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-pm.ctx.Done():
+			return
+		}
+
+		pm.mu.Lock()
+		pids := make(map[string]int, len(pm.running))
+		for name, handle := range pm.running {
+			pids[name] = handle.Pid()
+		}
+		previous := pm.usage
+		pm.mu.Unlock()
+
+		samples := make(map[string]ResourceUsage, len(pids))
+		for name, pid := range pids {
+			sample, err := readProcessUsage(pid)
+			if err != nil {
+				// The process may have just exited
+				continue
+			}
+			if last, ok := previous[name]; ok && last.PID == pid {
+				if elapsed := sample.Time.Sub(last.Time).Seconds(); elapsed > 0 {
+					sample.CPUPercent = (sample.CPUSeconds - last.CPUSeconds) / elapsed * 100
+				}
+			}
+			samples[name] = sample
+		}
+
+		pm.mu.Lock()
+		pm.usage = samples
+		pm.mu.Unlock()
+
+		if len(samples) > 0 {
+			log.Printf("Resource usage: %s", formatUsage(samples))
+		}
+	}
+}
+
+// Usage returns the latest resource usage sample of each running process,
+// empty unless UsageInterval is set
+func (pm *ProcessManager) Usage() map[string]ResourceUsage {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return maps.Clone(pm.usage)
+}
+
+// formatUsage formats samples by process name, e.g. "grpc-server cpu 1.5% rss 12.3MiB"
+func formatUsage(samples map[string]ResourceUsage) string {
+	var parts []string
+	for _, name := range slices.Sorted(maps.Keys(samples)) {
+		sample := samples[name]
+		parts = append(parts, fmt.Sprintf("%s cpu %.1f%% rss %s", name, sample.CPUPercent, formatMiB(sample.RSS)))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,61 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	usageSupported = true
+	// Units of the CPU times in /proc/<pid>/stat (USER_HZ, 100 on every
+	// architecture Linux exposes it for)
+	clockTicksPerSecond = 100
+)
+
+// readProcessUsage samples the CPU time and resident memory of pid from /proc/<pid>/stat
+func readProcessUsage(pid int) (ResourceUsage, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+	sample, err := parseProcStat(data)
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+	sample.PID = pid
+	sample.Time = time.Now()
+	return sample, nil
+}
+
+// parseProcStat reads the CPU times and resident size from the contents of a
+// /proc/<pid>/stat file
+func parseProcStat(data []byte) (ResourceUsage, error) {
+	// The command name in field 2 is in parentheses and may itself contain
+	// spaces and parentheses, so the fields are counted from the last ')'
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return ResourceUsage{}, fmt.Errorf("unexpected stat format: %q", data)
+	}
+	// Starting at field 3 (state): utime is field 14, stime 15 and rss 24
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 22 {
+		return ResourceUsage{}, fmt.Errorf("unexpected stat format: %q", data)
+	}
+	var values [3]uint64
+	for i, index := range []int{11, 12, 21} {
+		value, err := strconv.ParseUint(string(fields[index]), 10, 64)
+		if err != nil {
+			return ResourceUsage{}, fmt.Errorf("unexpected stat format: %q", data)
+		}
+		values[i] = value
+	}
+	return ResourceUsage{
+		CPUSeconds: float64(values[0]+values[1]) / clockTicksPerSecond,
+		RSS:        values[2] * uint64(os.Getpagesize()),
+	}, nil
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampleUsage(t *testing.T) {
+	proc := &Process{Name: "busy", Command: "sh", Args: []string{"-c", "while :; do :; done"}}
+	pm := NewProcessManager([]*Process{proc})
+	pm.UsageInterval = 50 * time.Millisecond
+	pm.StartWait = 10 * time.Millisecond
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	started := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+
+	// The second sample of a run also has a CPU share
+	deadline := time.Now().Add(5 * time.Second)
+	var sample ResourceUsage
+	for {
+		sample = pm.Usage()[proc.Name]
+		if sample.CPUPercent > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if sample.PID != started.PID {
+		t.Fatalf("usage sample = %+v, want one for PID %d", sample, started.PID)
+	}
+	if sample.RSS == 0 || sample.CPUSeconds <= 0 || sample.CPUPercent <= 0 {
+		t.Errorf("usage sample = %+v, want resident memory and CPU use for a busy loop", sample)
+	}
+
+	srv := httptest.NewServer(newHTTPHandler(pm))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`process_cpu_seconds_total{name="busy"} `, `process_resident_memory_bytes{name="busy"} `} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestParseProcStat(t *testing.T) {
+	// A command name with spaces and a ')' in it
+	stat := "1234 (my (odd) cmd) S 1 1234 1234 0 -1 4194560 100 0 0 0 250 50 0 0 20 0 1 0 500 10000000 300 18446744073709551615"
+	got, err := parseProcStat([]byte(stat))
+	if err != nil {
+		t.Fatalf("parseProcStat: %v", err)
+	}
+	if got.CPUSeconds != 3 {
+		t.Errorf("CPU seconds = %v, want 3 from 250+50 ticks", got.CPUSeconds)
+	}
+	if want := uint64(300 * os.Getpagesize()); got.RSS != want {
+		t.Errorf("RSS = %d, want %d", got.RSS, want)
+	}
+
+	if _, err := parseProcStat([]byte("1234 (short) S 1")); err == nil {
+		t.Error("parseProcStat of a truncated stat line succeeded")
+	}
+}
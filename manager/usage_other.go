@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+const usageSupported = false
+
+func readProcessUsage(int) (ResourceUsage, error) {
+	return ResourceUsage{}, errors.New("resource usage sampling is only supported on Linux")
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// validateProcesses checks process definitions without starting anything,
+// returning every problem found
+func validateProcesses(processes []*Process) []error {
+	var problems []error
+
+	seen := make(map[string]bool, len(processes))
+	for _, proc := range processes {
+		if proc.Name == "" {
+			problems = append(problems, errors.New("process with empty name"))
+			continue
+		}
+		if seen[proc.Name] {
+			problems = append(problems, fmt.Errorf("duplicate process name %q", proc.Name))
+		}
+		seen[proc.Name] = true
+	}
+
+	// Consumer of each process's stdout, by producer
+	consumers := make(map[string]string)
+	for _, proc := range processes {
+		var env []string
+		if proc.ExpandEnv {
+			// An unreadable file is reported by checkEnvFromFile
+			env, _ = fileEnv(proc)
+		}
+		command, _, err := expandCommand(proc, env)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("process %q: %v", proc.Name, err))
+		} else if command == "" {
+			problems = append(problems, fmt.Errorf("process %q: no command", proc.Name))
+		} else if proc.enabled() {
+			// A disabled process, such as a debug sidecar, may not be installed
+			if resolved, err := resolveCommand(command); err != nil {
+				problems = append(problems, fmt.Errorf("process %q: %v", proc.Name, err))
+			} else if _, err := exec.LookPath(resolved); err != nil {
+				problems = append(problems, fmt.Errorf("process %q: command %q not found or not executable", proc.Name, command))
+			}
+		}
+
+		if len(proc.PreStart) > 0 && proc.PreStart[0] == "" {
+			problems = append(problems, fmt.Errorf("process %q: preStart has no command", proc.Name))
+		}
+		if len(proc.PostStop) > 0 && proc.PostStop[0] == "" {
+			problems = append(problems, fmt.Errorf("process %q: postStop has no command", proc.Name))
+		}
+
+		if proc.EnabledIf != "" {
+			if err := checkEnabledIf(proc.EnabledIf); err != nil {
+				problems = append(problems, fmt.Errorf("process %q: %v", proc.Name, err))
+			}
+		}
+
+		switch proc.Kind {
+		case "", KindTask:
+		case KindDaemon:
+			if proc.WaitForExit {
+				problems = append(problems, fmt.Errorf("process %q: waitForExit makes it a task, not a %s", proc.Name, proc.Kind))
+			}
+		default:
+			problems = append(problems, fmt.Errorf("process %q: unknown kind %q (expected %s or %s)", proc.Name, proc.Kind, KindDaemon, KindTask))
+		}
+
+		if proc.DrainTimeout != 0 && !proc.isTask() {
+			problems = append(problems, fmt.Errorf("process %q: drainTimeout only applies to tasks, and daemons are stopped straight away", proc.Name))
+		}
+		if proc.DrainTimeout > 0 && proc.isTask() && proc.DrainTimeout >= proc.stopBudget() {
+			problems = append(problems, fmt.Errorf("process %q: drainTimeout %v leaves no time for the stop sequence of %v", proc.Name, proc.DrainTimeout, proc.stopBudget()))
+		}
+
+		for i, step := range proc.StopSignals {
+			if step.Signal == 0 {
+				problems = append(problems, fmt.Errorf("process %q: stop step %d has no signal", proc.Name, i+1))
+			}
+		}
+
+		if err := checkCredential(proc); err != nil {
+			problems = append(problems, fmt.Errorf("process %q: %v", proc.Name, err))
+		}
+
+		if proc.LogFilter != "" {
+			if _, err := regexp.Compile(proc.LogFilter); err != nil {
+				problems = append(problems, fmt.Errorf("process %q: invalid log filter: %v", proc.Name, err))
+			}
+		}
+
+		problems = append(problems, checkEnvFromFile(proc)...)
+		problems = append(problems, checkWatch(proc)...)
+
+		for _, key := range slices.Sorted(maps.Keys(proc.LogLabels)) {
+			if reservedLogFields[key] {
+				problems = append(problems, fmt.Errorf("process %q: log label %q would replace a standard log field", proc.Name, key))
+			}
+		}
+
+		if proc.RestartBackoffMax > 0 {
+			delay := proc.RestartDelay
+			if delay == 0 {
+				delay = defaultRestartDelay
+			}
+			if proc.RestartBackoffMax < delay {
+				problems = append(problems, fmt.Errorf("process %q: restartBackoffMax %v is shorter than the restart delay %v", proc.Name, proc.RestartBackoffMax, delay))
+			}
+		}
+
+		if proc.GRPCHealthSocket != "" && proc.ReadyFile != "" {
+			problems = append(problems, fmt.Errorf("process %q: grpcHealthSocket and readyFile are mutually exclusive", proc.Name))
+		}
+
+		if proc.RollingRestart && proc.Socket == "" {
+			problems = append(problems, fmt.Errorf("process %q: rolling restart requires a socket", proc.Name))
+		}
+
+		if proc.StdinFrom != "" {
+			switch {
+			case proc.StdinFrom == proc.Name:
+				problems = append(problems, fmt.Errorf("process %q: stdinFrom names the process itself", proc.Name))
+			case !seen[proc.StdinFrom]:
+				problems = append(problems, fmt.Errorf("process %q: stdinFrom names unknown process %q", proc.Name, proc.StdinFrom))
+			case consumers[proc.StdinFrom] != "":
+				problems = append(problems, fmt.Errorf("process %q: stdout of %q already feeds %q", proc.Name, proc.StdinFrom, consumers[proc.StdinFrom]))
+			default:
+				consumers[proc.StdinFrom] = proc.Name
+			}
+			if proc.StdinData != nil {
+				problems = append(problems, fmt.Errorf("process %q: stdin and stdinFrom are mutually exclusive", proc.Name))
+			}
+		}
+
+		for _, dep := range proc.DependsOn {
+			if !seen[dep] {
+				problems = append(problems, fmt.Errorf("process %q: depends on unknown process %q", proc.Name, dep))
+			}
+		}
+	}
+
+	problems = append(problems, criticalDependencyProblems(processes)...)
+
+	if _, err := startOrder(processes); err != nil {
+		problems = append(problems, err)
+	}
+
+	return problems
+}
+
+// criticalDependencyProblems returns a problem for each critical process that
+// depends on a noncritical one. The dependency may never become ready, or give
+// up, without that ending the run, leaving the critical process waiting on
+// it. Checked by both validation and Start.
+func criticalDependencyProblems(processes []*Process) []error {
+	byName := make(map[string]*Process, len(processes))
+	for _, proc := range processes {
+		byName[proc.Name] = proc
+	}
+
+	var problems []error
+	for _, proc := range processes {
+		if !proc.Critical {
+			continue
+		}
+		for _, dep := range proc.DependsOn {
+			if d := byName[dep]; d != nil && !d.Critical {
+				problems = append(problems, fmt.Errorf("process %q: critical process depends on noncritical process %q; make %q critical too", proc.Name, dep, dep))
+			}
+		}
+	}
+	return problems
+}
+
+// ungatedWarnings returns a warning for each noncritical process without a
+// ReadyCheck that others rely on starting first: one they depend on, or the
+// first process when the rest rely on config order alone. Start only waits
+// for such a process for its StartWait, so the ones after it may start
+// before it is serving. processes must be in start order.
+func ungatedWarnings(processes []*Process) []error {
+	dependents := make(map[string][]string)
+	ordered := len(processes) > 1
+	for _, proc := range processes {
+		for _, dep := range proc.DependsOn {
+			dependents[dep] = append(dependents[dep], proc.Name)
+			ordered = false
+		}
+	}
+
+	var warnings []error
+	for i, proc := range processes {
+		if proc.Critical || proc.ReadyCheck != nil {
+			continue
+		}
+		switch {
+		case len(dependents[proc.Name]) > 0:
+			warnings = append(warnings, fmt.Errorf("process %q is not critical and has no readiness check, so %s may start before it is serving; make it critical or give it a grpcHealthSocket or readyFile", proc.Name, strings.Join(dependents[proc.Name], ", ")))
+		case i == 0 && ordered:
+			warnings = append(warnings, fmt.Errorf("process %q starts first but is not critical and has no readiness check, so the processes after it may start before it is serving; make it critical or give it a grpcHealthSocket or readyFile", proc.Name))
+		}
+	}
+	return warnings
+}
+
+// runValidate prints a validation report and returns the process exit code
+func runValidate(processes []*Process) int {
+	problems := validateProcesses(processes)
+	if order, err := startOrder(processes); err == nil {
+		for _, warning := range ungatedWarnings(order) {
+			fmt.Printf("Warning: %v\n", warning)
+		}
+	}
+	if len(problems) == 0 {
+		fmt.Printf("Config OK: %d processes\n", len(processes))
+		return 0
+	}
+
+	fmt.Printf("Config invalid: %d problems\n", len(problems))
+	for _, problem := range problems {
+		fmt.Printf("  - %v\n", problem)
+	}
+	return 1
+}
+
+// startOrder sorts processes so each one comes after its dependencies,
+// otherwise keeping configuration order
+func startOrder(processes []*Process) ([]*Process, error) {
+	byName := make(map[string]*Process, len(processes))
+	for _, proc := range processes {
+		byName[proc.Name] = proc
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	marks := make(map[string]int, len(processes))
+	ordered := make([]*Process, 0, len(processes))
+
+	var visit func(proc *Process, path []string) error
+	visit = func(proc *Process, path []string) error {
+		switch marks[proc.Name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, proc.Name), " -> "))
+		}
+
+		marks[proc.Name] = visiting
+		for _, dep := range proc.DependsOn {
+			depProc, ok := byName[dep]
+			if !ok {
+				// Reported separately by validation
+				continue
+			}
+			if err := visit(depProc, append(path, proc.Name)); err != nil {
+				return err
+			}
+		}
+		marks[proc.Name] = done
+		ordered = append(ordered, proc)
+		return nil
+	}
+
+	for _, proc := range processes {
+		if err := visit(proc, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
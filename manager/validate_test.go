@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// procs builds processes from "name:dep1,dep2" specs
+func procs(specs ...string) []*Process {
+	processes := make([]*Process, 0, len(specs))
+	for _, spec := range specs {
+		name, deps, _ := strings.Cut(spec, ":")
+		proc := &Process{Name: name, Command: "sh"}
+		if deps != "" {
+			proc.DependsOn = strings.Split(deps, ",")
+		}
+		processes = append(processes, proc)
+	}
+	return processes
+}
+
+func names(processes []*Process) []string {
+	result := make([]string, len(processes))
+	for i, proc := range processes {
+		result[i] = proc.Name
+	}
+	return result
+}
+
+func TestStartOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []string
+		wantErr string
+	}{
+		{"config order", []string{"a", "b", "c"}, []string{"a", "b", "c"}, ""},
+		{"dependency first", []string{"client:server", "server"}, []string{"server", "client"}, ""},
+		{"chain", []string{"c:b", "b:a", "a"}, []string{"a", "b", "c"}, ""},
+		{"shared dependency", []string{"x:db", "y:db", "db"}, []string{"db", "x", "y"}, ""},
+		{"unknown dependency ignored", []string{"a:missing", "b"}, []string{"a", "b"}, ""},
+		{"self cycle", []string{"a:a"}, nil, "dependency cycle: a -> a"},
+		{"two cycle", []string{"a:b", "b:a"}, nil, "dependency cycle: a -> b -> a"},
+		{"cycle behind a chain", []string{"top:a", "a:b", "b:c", "c:a"}, nil, "dependency cycle: top -> a -> b -> c -> a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := startOrder(procs(tt.specs...))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("startOrder() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("startOrder() error = %v", err)
+			}
+			if got := names(order); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("startOrder() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateProcesses(t *testing.T) {
+	tests := []struct {
+		name      string
+		processes []*Process
+		want      []string
+	}{
+		{"valid", procs("server", "client:server"), nil},
+		{"cycle", procs("a:b", "b:a"), []string{"dependency cycle: a -> b -> a"}},
+		{"unknown dependency", procs("a:missing"), []string{`process "a": depends on unknown process "missing"`}},
+		{"duplicate name", procs("a", "a"), []string{`duplicate process name "a"`}},
+		{"empty name", procs(":"), []string{"process with empty name"}},
+		{"missing command", []*Process{{Name: "a", Command: "no-such-command-here"}}, []string{`process "a": command "no-such-command-here" not found or not executable`}},
+		{"stop step without signal", []*Process{{Name: "a", Command: "sh", StopSignals: []StopStep{{Wait: time.Second}}}}, []string{`process "a": stop step 1 has no signal`}},
+		{"unknown user", []*Process{{Name: "a", Command: "sh", User: "no-such-user-here"}}, []string{`process "a": unknown user "no-such-user-here": user: unknown user no-such-user-here`}},
+		{"invalid log filter", []*Process{{Name: "a", Command: "sh", LogFilter: "("}}, []string{"process \"a\": invalid log filter: error parsing regexp: missing closing ): `(`"}},
+		{"reserved log label", []*Process{{Name: "a", Command: "sh", LogLabels: map[string]string{"service": "a", "time": "now"}}}, []string{`process "a": log label "time" would replace a standard log field`}},
+		{"invalid enabledIf", []*Process{{Name: "a", Command: "sh", EnabledIf: "=1"}}, []string{`process "a": invalid enabledIf "=1": want VAR or VAR=value`}},
+		{"disabled process not installed", []*Process{{Name: "a", Command: "/no/such/debugger", EnabledIf: "MPD_VALIDATE_UNSET"}}, nil},
+		{"stdinFrom unknown process", []*Process{{Name: "a", Command: "sh", StdinFrom: "missing"}}, []string{`process "a": stdinFrom names unknown process "missing"`}},
+		{"stdinFrom shared", []*Process{{Name: "a", Command: "sh"}, {Name: "b", Command: "sh", StdinFrom: "a"}, {Name: "c", Command: "sh", StdinFrom: "a"}}, []string{`process "c": stdout of "a" already feeds "b"`}},
+		{"stdin and stdinFrom", []*Process{{Name: "a", Command: "sh"}, {Name: "b", Command: "sh", StdinFrom: "a", StdinData: []byte("x")}}, []string{`process "b": stdin and stdinFrom are mutually exclusive`}},
+		{"backoff below restart delay", []*Process{{Name: "a", Command: "sh", RestartBackoffMax: time.Second}}, []string{`process "a": restartBackoffMax 1s is shorter than the restart delay 5s`}},
+		{"unknown kind", []*Process{{Name: "a", Command: "sh", Kind: "job"}}, []string{`process "a": unknown kind "job" (expected daemon or task)`}},
+		{"daemon waiting for exit", []*Process{{Name: "a", Command: "sh", Kind: KindDaemon, WaitForExit: true}}, []string{`process "a": waitForExit makes it a task, not a daemon`}},
+		{"daemon with drain timeout", []*Process{{Name: "a", Command: "sh", DrainTimeout: time.Minute}}, []string{`process "a": drainTimeout only applies to tasks, and daemons are stopped straight away`}},
+		{"drain timeout past stop sequence", []*Process{{Name: "a", Command: "sh", Kind: KindTask, DrainTimeout: time.Minute}}, []string{`process "a": drainTimeout 1m0s leaves no time for the stop sequence of 30s`}},
+		{"two ready checks", []*Process{{Name: "a", Command: "sh", GRPCHealthSocket: "/tmp/a.sock", ReadyFile: "/tmp/a.ready"}}, []string{`process "a": grpcHealthSocket and readyFile are mutually exclusive`}},
+		{"critical depends on noncritical", []*Process{{Name: "db", Command: "sh"}, {Name: "api", Command: "sh", Critical: true, DependsOn: []string{"db"}}}, []string{`process "api": critical process depends on noncritical process "db"; make "db" critical too`}},
+		{"critical depends on critical", []*Process{{Name: "db", Command: "sh", Critical: true}, {Name: "api", Command: "sh", Critical: true, DependsOn: []string{"db"}}}, nil},
+		{"noncritical depends on noncritical", []*Process{{Name: "db", Command: "sh"}, {Name: "api", Command: "sh", DependsOn: []string{"db"}}}, nil},
+		{"empty preStart", []*Process{{Name: "a", Command: "sh", PreStart: []string{""}}}, []string{`process "a": preStart has no command`}},
+		{"empty postStop", []*Process{{Name: "a", Command: "sh", PostStop: []string{""}}}, []string{`process "a": postStop has no command`}},
+		{"rolling restart without socket", []*Process{{Name: "a", Command: "sh", RollingRestart: true}}, []string{`process "a": rolling restart requires a socket`}},
+		{"empty watch path", []*Process{{Name: "a", Command: "sh", WatchPaths: []string{""}}}, []string{`process "a": empty watchPaths entry`}},
+		{"watch path in missing directory", []*Process{{Name: "a", Command: "sh", WatchPaths: []string{"/nonexistent/a.conf"}}}, []string{`process "a": cannot watch /nonexistent/a.conf: stat /nonexistent: no such file or directory`}},
+		{"task with watch paths", []*Process{{Name: "a", Command: "sh", Kind: KindTask, WatchPaths: []string{"/etc/a.conf"}}}, []string{`process "a": watchPaths only applies to daemons, and a task is not restarted`}},
+		{"watch signal without paths", []*Process{{Name: "a", Command: "sh", WatchSignal: syscall.SIGHUP}}, []string{`process "a": watchSignal requires watchPaths`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, problem := range validateProcesses(tt.processes) {
+				got = append(got, problem.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateProcesses() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartRejectsCriticalDependingOnNoncritical(t *testing.T) {
+	pm := NewProcessManager([]*Process{
+		{Name: "db", Command: "sleep", Args: []string{"5"}},
+		{Name: "api", Command: "sleep", Args: []string{"5"}, Critical: true, DependsOn: []string{"db"}},
+	})
+	defer pm.Shutdown()
+
+	err := pm.Start()
+	if err == nil || !strings.Contains(err.Error(), `critical process depends on noncritical process "db"`) {
+		t.Fatalf("Start() error = %v, want the noncritical dependency reported", err)
+	}
+	if state, _ := stateOf(pm, "db"); state.PID != 0 {
+		t.Errorf("db started with PID %d, want nothing started", state.PID)
+	}
+}
+
+func TestUngatedWarnings(t *testing.T) {
+	gated := func(processes []*Process, names ...string) []*Process {
+		for _, proc := range processes {
+			for _, name := range names {
+				if proc.Name == name {
+					proc.ReadyCheck = func(context.Context) error { return nil }
+				}
+			}
+		}
+		return processes
+	}
+	critical := func(processes []*Process) []*Process {
+		processes[0].Critical = true
+		return processes
+	}
+
+	tests := []struct {
+		name      string
+		processes []*Process
+		want      []string
+	}{
+		{"noncritical dependency without readiness check", procs("server", "client:server", "worker:server"), []string{
+			`process "server" is not critical and has no readiness check, so client, worker may start before it is serving; make it critical or give it a grpcHealthSocket or readyFile`,
+		}},
+		{"first in config order", procs("server", "client"), []string{
+			`process "server" starts first but is not critical and has no readiness check, so the processes after it may start before it is serving; make it critical or give it a grpcHealthSocket or readyFile`,
+		}},
+		{"critical dependency", critical(procs("server", "client:server")), nil},
+		{"dependency with readiness check", gated(procs("server", "client:server"), "server"), nil},
+		{"first with readiness check", gated(procs("server", "client"), "server"), nil},
+		{"single process", procs("server"), nil},
+		{"independent processes with dependencies elsewhere", procs("a", "b", "c:b"), []string{
+			`process "b" is not critical and has no readiness check, so c may start before it is serving; make it critical or give it a grpcHealthSocket or readyFile`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, warning := range ungatedWarnings(tt.processes) {
+				got = append(got, warning.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ungatedWarnings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartWarnsAboutUngatedDependency(t *testing.T) {
+	newManager := func() *ProcessManager {
+		pm := NewProcessManager([]*Process{
+			{Name: "server", Command: "sleep", Args: []string{"5"}},
+			{Name: "client", Command: "sleep", Args: []string{"5"}, DependsOn: []string{"server"}},
+		})
+		pm.StartWait = 10 * time.Millisecond
+		return pm
+	}
+	const warning = `process "server" is not critical and has no readiness check, so client may start before it is serving`
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	pm := newManager()
+	err := pm.Start()
+	pm.Shutdown()
+	log.SetOutput(os.Stderr)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !strings.Contains(logs.String(), "Warning: "+warning) {
+		t.Errorf("logs = %q, want the warning %q", logs.String(), warning)
+	}
+
+	strict := newManager()
+	strict.Strict = true
+	defer strict.Shutdown()
+	if err := strict.Start(); err == nil || !strings.Contains(err.Error(), warning) {
+		t.Errorf("Start() under Strict = %v, want the warning as an error", err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitForExitGatesDependents(t *testing.T) {
+	initProc := &Process{Name: "init", Command: "sleep", Args: []string{"0.3"}, WaitForExit: true}
+	server := &Process{Name: "server", Command: "sleep", Args: []string{"5"}, DependsOn: []string{"init"}}
+	pm := NewProcessManager([]*Process{server, initProc})
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	exited := waitEvent(t, events, initProc.Name, EventExited, 5*time.Second)
+	started := waitEvent(t, events, server.Name, EventStarted, 5*time.Second)
+	if exited.ExitCode != 0 {
+		t.Errorf("init exit code = %d, want 0", exited.ExitCode)
+	}
+	if started.Time.Before(exited.Time) {
+		t.Error("server launched before init completed")
+	}
+}
+
+func TestWaitForExitFailure(t *testing.T) {
+	initProc := &Process{Name: "init", Command: "sh", Args: []string{"-c", "sleep 0.1; exit 1"}, Critical: true, WaitForExit: true}
+	server := &Process{Name: "server", Command: "sleep", Args: []string{"5"}, DependsOn: []string{"init"}}
+	pm := NewProcessManager([]*Process{initProc, server})
+	defer pm.Shutdown()
+
+	if err := pm.Start(); err == nil {
+		t.Fatal("Start succeeded although init exited with code 1")
+	}
+	if pm.States()[1].PID != 0 {
+		t.Error("server was launched after init failed")
+	}
+}
+
+func TestTaskCompletesWithoutRestart(t *testing.T) {
+	task := &Process{Name: "migrate", Command: "true", Kind: KindTask, RestartDelay: 10 * time.Millisecond}
+	daemon := &Process{Name: "server", Command: "true", Kind: KindDaemon, RestartDelay: 10 * time.Millisecond}
+	pm := NewProcessManager([]*Process{task, daemon})
+	pm.StartWait = 10 * time.Millisecond
+	defer pm.Shutdown()
+	events := pm.Events()
+
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// The daemon exits cleanly too, but is brought back
+	waitEvent(t, events, daemon.Name, EventRestarting, 5*time.Second)
+	waitEvent(t, events, daemon.Name, EventStarted, 5*time.Second)
+
+	states := pm.States()
+	if got := states[0]; !got.Completed || got.Restarts != 0 || got.Running {
+		t.Errorf("task state = %+v, want completed without restarts", got)
+	}
+	if got := states[1]; got.Completed || got.Restarts == 0 {
+		t.Errorf("daemon state = %+v, want restarted and never completed", got)
+	}
+	if !pm.HealthDetail()[task.Name] {
+		t.Error("completed task reported unhealthy")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Default time the watched files must stay unchanged before the process is
+// restarted, so a burst of writes restarts it once
+const defaultWatchDebounce = 500 * time.Millisecond
+
+// fileStamp is what a change to a watched path is detected by, the zero value
+// for a path that does not exist
+type fileStamp struct {
+	exists  bool
+	modTime int64
+	size    int64
+}
+
+// statPaths returns the current stamp of every path. Symlinks are followed,
+// so swapping the target of a mounted ConfigMap counts as a change.
+func statPaths(paths []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fileStamp{exists: true, modTime: info.ModTime().UnixNano(), size: info.Size()}
+		} else {
+			stamps[path] = fileStamp{}
+		}
+	}
+	return stamps
+}
+
+// watchDirs returns the directories holding paths, each once
+func watchDirs(paths []string) []string {
+	var dirs []string
+	for _, path := range paths {
+		if dir := filepath.Dir(filepath.Clean(path)); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// watchPaths watches proc's WatchPaths with fsnotify until ctx is done. Once a
+// change has been followed by watchDebounce without further changes, the
+// process is restarted, or sent its WatchSignal if it has one. The directories
+// holding the paths are watched rather than the files themselves, so a file
+// replaced by a rename, as editors and ConfigMap updates do, stays watched,
+// and creating or removing a watched file is a change too.
+func (pm *ProcessManager) watchPaths(ctx context.Context, proc *Process) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Process %s: cannot watch files: %v", proc.Name, err)
+		return
+	}
+	defer watcher.Close()
+
+	stamps := statPaths(proc.WatchPaths)
+	for _, dir := range watchDirs(proc.WatchPaths) {
+		if err := watcher.Add(dir); err != nil {
+			log.Printf("Process %s: cannot watch %s: %v", proc.Name, dir, err)
+		}
+	}
+
+	watched := make([]string, len(proc.WatchPaths))
+	for i, path := range proc.WatchPaths {
+		watched[i] = filepath.Clean(path)
+	}
+	// Fires once the latest change has settled, nil when none is pending
+	var settled <-chan time.Time
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			// Other files in the directories are ignored, unless they are
+			// what a watched symlink points to
+			current := statPaths(proc.WatchPaths)
+			if slices.Contains(watched, filepath.Clean(event.Name)) || !maps.Equal(current, stamps) {
+				stamps = current
+				settled = time.After(pm.watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Process %s: error watching files: %v", proc.Name, err)
+		case <-settled:
+			settled = nil
+			pm.reloadOnChange(proc)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reloadOnChange restarts proc after its watched files changed, or sends it
+// its WatchSignal so it can reload them in place
+func (pm *ProcessManager) reloadOnChange(proc *Process) {
+	if proc.WatchSignal == 0 {
+		log.Printf("Process %s: watched files changed, restarting", proc.Name)
+		if err := pm.Restart(proc.Name); err != nil {
+			log.Printf("Process %s: failed to restart after watched files changed: %v", proc.Name, err)
+		}
+		return
+	}
+
+	pm.mu.Lock()
+	handle, ok := pm.running[proc.Name]
+	pm.mu.Unlock()
+	if !ok {
+		log.Printf("Process %s: watched files changed while not running, the next start picks them up", proc.Name)
+		return
+	}
+	log.Printf("Process %s: watched files changed, sending %s (PID: %d)", proc.Name, signalName(proc.WatchSignal), handle.Pid())
+	if err := handle.Signal(proc.WatchSignal); err != nil {
+		log.Printf("Process %s: failed to send %s: %v", proc.Name, signalName(proc.WatchSignal), err)
+	}
+}
+
+// checkWatch returns the problems with proc's WatchPaths and WatchSignal
+func checkWatch(proc *Process) []error {
+	var problems []error
+	for _, path := range proc.WatchPaths {
+		if path == "" {
+			problems = append(problems, fmt.Errorf("process %q: empty watchPaths entry", proc.Name))
+			continue
+		}
+		// The file itself may come and go, but its directory is what is watched
+		if _, err := os.Stat(filepath.Dir(filepath.Clean(path))); err != nil {
+			problems = append(problems, fmt.Errorf("process %q: cannot watch %s: %v", proc.Name, path, err))
+		}
+	}
+	if len(proc.WatchPaths) > 0 && proc.isTask() {
+		problems = append(problems, fmt.Errorf("process %q: watchPaths only applies to daemons, and a task is not restarted", proc.Name))
+	}
+	if proc.WatchSignal != 0 && len(proc.WatchPaths) == 0 {
+		problems = append(problems, fmt.Errorf("process %q: watchSignal requires watchPaths", proc.Name))
+	}
+	return problems
+}
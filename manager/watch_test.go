@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatchPathsRestartsOnChange(t *testing.T) {
+	conf := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(conf, []byte("v1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	proc := &Process{
+		Name:         "app",
+		Command:      "sleep",
+		Args:         []string{"30"},
+		RestartDelay: time.Hour,
+		WatchPaths:   []string{conf},
+	}
+	pm := NewProcessManager([]*Process{proc})
+	pm.watchDebounce = 200 * time.Millisecond
+	defer pm.Shutdown()
+	events := pm.Events()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	first := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+
+	// A burst of writes, like an editor saving in steps and finally renaming
+	// its copy into place, restarts it once
+	for i := range 3 {
+		if err := os.WriteFile(conf, []byte("v2"+strings.Repeat("!", i)+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(30 * time.Millisecond)
+	}
+	saved := conf + ".tmp"
+	if err := os.WriteFile(saved, []byte("v3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(saved, conf); err != nil {
+		t.Fatal(err)
+	}
+	restarted := waitEvent(t, events, proc.Name, EventStarted, 5*time.Second)
+	if restarted.PID == first.PID {
+		t.Errorf("restarted with the same PID %d", first.PID)
+	}
+	select {
+	case ev := <-events:
+		if ev.Type == EventStarted {
+			t.Errorf("started again (PID %d) after a single burst of changes", ev.PID)
+		}
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestWatchPathsSendsSignal(t *testing.T) {
+	conf := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(conf, []byte("v1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	proc := &Process{
+		Name:         "app",
+		Command:      "sh",
+		Args:         []string{"-c", `trap 'echo reloaded' HUP; echo ready; while :; do sleep 0.05; done`},
+		RestartDelay: time.Hour,
+		WatchPaths:   []string{conf},
+		WatchSignal:  syscall.SIGHUP,
+	}
+	pm := NewProcessManager([]*Process{proc})
+	pm.watchDebounce = 50 * time.Millisecond
+	defer pm.Shutdown()
+	if err := pm.startProcess(proc, false); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+	waitForTail(t, pm, proc.Name, "ready")
+	state, _ := stateOf(pm, proc.Name)
+	pid := state.PID
+
+	// Removing a watched file is a change too
+	if err := os.Remove(conf); err != nil {
+		t.Fatal(err)
+	}
+	waitForTail(t, pm, proc.Name, "reloaded")
+	if state, _ := stateOf(pm, proc.Name); state.PID != pid || !state.Running {
+		t.Errorf("state = PID %d, running %v, want PID %d still running", state.PID, state.Running, pid)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// listenFDsStart is the first descriptor passed by systemd socket activation.
+// Tests replace it to pass descriptors of their own.
+var listenFDsStart = 3
+
+// activatedListeners returns listeners for the sockets passed in by systemd
+// socket activation, or nil if the server was not socket-activated. The
+// sockets belong to systemd, so they are not chmodded or removed on exit.
+func activatedListeners() ([]*serverListener, error) {
+	// LISTEN_PID guards against variables inherited from a socket-activated parent
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", os.Getenv("LISTEN_FDS"))
+	}
+	// Processes the server starts must not take the sockets for their own
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	var listeners []*serverListener
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		syscall.CloseOnExec(fd)
+		file := os.NewFile(uintptr(fd), "listen fd "+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("failed to listen on socket-activated descriptor %d: %w", fd, err)
+		}
+		listeners = append(listeners, &serverListener{Listener: listener})
+	}
+	return listeners, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// inheritFD returns a duplicate of the listener's descriptor, as systemd would
+// pass it in
+func inheritFD(t *testing.T, listener net.Listener) int {
+	t.Helper()
+	raw, err := listener.(syscall.Conn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := -1
+	var dupErr error
+	if err := raw.Control(func(orig uintptr) { fd, dupErr = syscall.Dup(int(orig)) }); err != nil {
+		t.Fatal(err)
+	}
+	if dupErr != nil {
+		t.Fatal(dupErr)
+	}
+	return fd
+}
+
+func TestActivatedListeners(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	socket, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer socket.Close()
+
+	listenFDsStart = inheritFD(t, socket)
+	defer func() { listenFDsStart = 3 }()
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+
+	listeners, err := activatedListeners()
+	if err != nil {
+		t.Fatalf("activatedListeners: %v", err)
+	}
+	if len(listeners) != 1 {
+		t.Fatalf("activatedListeners returned %d listeners, want 1", len(listeners))
+	}
+	if listeners[0].socketPath != "" {
+		t.Errorf("socket path = %q, want none so the socket is left to systemd", listeners[0].socketPath)
+	}
+	if v, ok := os.LookupEnv("LISTEN_FDS"); ok {
+		t.Errorf("LISTEN_FDS = %q after activation, want it unset", v)
+	}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	go serveAll(grpcServer, listeners)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := pb.NewGreeterClient(conn).SayHello(ctx, &pb.HelloRequest{Name: "test"}); err != nil {
+		t.Errorf("SayHello on the inherited socket: %v", err)
+	}
+}
+
+func TestActivatedListenersNotActivated(t *testing.T) {
+	tests := []struct {
+		name string
+		pid  string
+	}{
+		{"unset", ""},
+		// Inherited from a socket-activated parent
+		{"other process", strconv.Itoa(os.Getppid())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LISTEN_PID", tt.pid)
+			t.Setenv("LISTEN_FDS", "1")
+			listeners, err := activatedListeners()
+			if listeners != nil || err != nil {
+				t.Errorf("activatedListeners() = %v, %v, want nil, nil", listeners, err)
+			}
+		})
+	}
+}
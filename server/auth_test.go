@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenAuth(t *testing.T) {
+	auth := &tokenAuth{token: "s3cret"}
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(auth.unaryInterceptor),
+		grpc.ChainStreamInterceptor(auth.streamInterceptor),
+	)
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	conn := serveInMemory(t, grpcServer)
+	client := pb.NewGreeterClient(conn)
+
+	tests := []struct {
+		name     string
+		header   []string
+		wantCode codes.Code
+	}{
+		{"valid token", []string{authMetadataKey, "Bearer s3cret"}, codes.OK},
+		{"no token", nil, codes.Unauthenticated},
+		{"wrong token", []string{authMetadataKey, "Bearer guess"}, codes.Unauthenticated},
+		{"token without scheme", []string{authMetadataKey, "s3cret"}, codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if tt.header != nil {
+				ctx = metadata.AppendToOutgoingContext(ctx, tt.header...)
+			}
+
+			_, err := client.SayHello(ctx, &pb.HelloRequest{Name: "test"})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("SayHello code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+
+			stream, err := client.StreamMessages(ctx, &pb.StreamRequest{Count: 1})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("StreamMessages code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+		})
+	}
+
+	// Health checks stay open for the process manager's readiness probe
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("health Check without token: %v", err)
+	}
+}
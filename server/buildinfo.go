@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+
+	"multi-process-docker/internal/buildinfo"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Response header keys carrying the server's build info
+const (
+	versionHeader   = "x-build-version"
+	commitHeader    = "x-build-commit"
+	buildDateHeader = "x-build-date"
+)
+
+// buildInfoHeader is sent with every response, so health checks also report which build is serving
+func buildInfoHeader() metadata.MD {
+	info := buildinfo.Get()
+	return metadata.Pairs(versionHeader, info.Version, commitHeader, info.Commit, buildDateHeader, info.Date)
+}
+
+func buildInfoUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := grpc.SetHeader(ctx, buildInfoHeader()); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func buildInfoStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := ss.SetHeader(buildInfoHeader()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"multi-process-docker/internal/buildinfo"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHealthCheckReportsBuildInfo(t *testing.T) {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(buildInfoUnaryInterceptor))
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	conn := serveInMemory(t, grpcServer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var header metadata.MD
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header)); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	info := buildinfo.Get()
+	for key, want := range map[string]string{versionHeader: info.Version, commitHeader: info.Commit, buildDateHeader: info.Date} {
+		if got := header.Get(key); len(got) != 1 || got[0] != want {
+			t.Errorf("header %s = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/stats"
+)
+
+// compressionRecorder records the compression of every RPC the server receives
+type compressionRecorder struct {
+	mu          sync.Mutex
+	compression []string
+}
+
+func (r *compressionRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if header, ok := s.(*stats.InHeader); ok {
+		r.mu.Lock()
+		r.compression = append(r.compression, header.Compression)
+		r.mu.Unlock()
+	}
+}
+
+func (r *compressionRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestGzipCompressedRPCs(t *testing.T) {
+	recorder := &compressionRecorder{}
+	grpcServer := grpc.NewServer(grpc.StatsHandler(recorder))
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	conn := serveInMemory(t, grpcServer, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	client := pb.NewGreeterClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	reply, err := client.SayHello(ctx, &pb.HelloRequest{Name: "gzip"})
+	if err != nil {
+		t.Fatalf("SayHello: %v", err)
+	}
+	if want := "Hello, gzip! Welcome to gRPC over UDS."; reply.Message != want {
+		t.Errorf("SayHello message = %q, want %q", reply.Message, want)
+	}
+
+	stream, err := client.StreamMessages(ctx, &pb.StreamRequest{Count: 2})
+	if err != nil {
+		t.Fatalf("StreamMessages: %v", err)
+	}
+	var indexes []int32
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("stream Recv: %v", err)
+		}
+		indexes = append(indexes, msg.Index)
+	}
+	if len(indexes) != 2 || indexes[0] != 1 || indexes[1] != 2 {
+		t.Errorf("streamed indexes = %v, want [1 2]", indexes)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.compression) != 2 {
+		t.Fatalf("server saw %d RPCs, want 2", len(recorder.compression))
+	}
+	for i, compression := range recorder.compression {
+		if compression != gzip.Name {
+			t.Errorf("RPC %d compression = %q, want %q", i, compression, gzip.Name)
+		}
+	}
+}
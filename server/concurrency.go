@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// concurrencyLimiter bounds the calls in flight per RPC method. Calls over the
+// limit wait in a bounded queue for a free slot, and are rejected once that
+// queue is full too.
+type concurrencyLimiter struct {
+	limits map[string]*methodLimit
+}
+
+type methodLimit struct {
+	// A call holds a slot for as long as it runs
+	slots chan struct{}
+	// Calls waiting for a slot, nil when calls over the limit are rejected outright
+	queue chan struct{}
+}
+
+// parseConcurrencyLimits parses a spec such as "StreamMessages=10:20,SayHello=100"
+// into per-method limits on calls in flight. The optional number after the
+// colon is how many more calls may queue for a slot; without it they are
+// rejected as soon as the limit is reached.
+func parseConcurrencyLimits(spec string) (*concurrencyLimiter, error) {
+	cl := &concurrencyLimiter{limits: make(map[string]*methodLimit)}
+	if spec == "" {
+		return cl, nil
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		method, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || method == "" {
+			return nil, fmt.Errorf("invalid concurrency limit %q, expected Method=max or Method=max:queue", entry)
+		}
+		maxValue, queueValue, queued := strings.Cut(value, ":")
+		limit, err := strconv.Atoi(maxValue)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid concurrency limit for %s: %q", method, value)
+		}
+		ml := &methodLimit{slots: make(chan struct{}, limit)}
+		if queued {
+			depth, err := strconv.Atoi(queueValue)
+			if err != nil || depth < 0 {
+				return nil, fmt.Errorf("invalid queue bound for %s: %q", method, value)
+			}
+			if depth > 0 {
+				ml.queue = make(chan struct{}, depth)
+			}
+		}
+		cl.limits[method] = ml
+	}
+	return cl, nil
+}
+
+// acquire takes a slot for a call to fullMethod (e.g. "/hello.Greeter/StreamMessages"),
+// queueing for one if the method is at its limit, and returns the function that
+// gives the slot back
+func (cl *concurrencyLimiter) acquire(ctx context.Context, fullMethod string) (func(), error) {
+	ml, ok := cl.limits[path.Base(fullMethod)]
+	if !ok {
+		return func() {}, nil
+	}
+	release := func() { <-ml.slots }
+
+	select {
+	case ml.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	select {
+	case ml.queue <- struct{}{}:
+	default:
+		return nil, status.Errorf(codes.ResourceExhausted, "too many concurrent %s calls", path.Base(fullMethod))
+	}
+	defer func() { <-ml.queue }()
+
+	select {
+	case ml.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func (cl *concurrencyLimiter) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	release, err := cl.acquire(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return handler(ctx, req)
+}
+
+func (cl *concurrencyLimiter) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	release, err := cl.acquire(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return handler(srv, ss)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseConcurrencyLimits(t *testing.T) {
+	tests := []struct {
+		spec    string
+		methods map[string][2]int // method: limit, queue bound
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"StreamMessages=10", map[string][2]int{"StreamMessages": {10, 0}}, false},
+		{"StreamMessages=10:20, SayHello=100:0", map[string][2]int{"StreamMessages": {10, 20}, "SayHello": {100, 0}}, false},
+		{"StreamMessages", nil, true},
+		{"=5", nil, true},
+		{"StreamMessages=0", nil, true},
+		{"StreamMessages=many", nil, true},
+		{"StreamMessages=10:-1", nil, true},
+		{"StreamMessages=10:lots", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			cl, err := parseConcurrencyLimits(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseConcurrencyLimits(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(cl.limits) != len(tt.methods) {
+				t.Errorf("got %d limited methods, want %d", len(cl.limits), len(tt.methods))
+			}
+			for method, want := range tt.methods {
+				ml := cl.limits[method]
+				if ml == nil {
+					t.Errorf("no limit for %s", method)
+					continue
+				}
+				if got := [2]int{cap(ml.slots), cap(ml.queue)}; got != want {
+					t.Errorf("%s limit, queue = %v, want %v", method, got, want)
+				}
+			}
+		})
+	}
+}
+
+// serveLimited serves a greeter behind a concurrency limiter parsed from spec
+func serveLimited(t *testing.T, spec string) (pb.GreeterClient, *concurrencyLimiter) {
+	t.Helper()
+	limiter, err := parseConcurrencyLimits(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(limiter.unaryInterceptor),
+		grpc.ChainStreamInterceptor(limiter.streamInterceptor),
+	)
+	pb.RegisterGreeterServer(grpcServer, &server{})
+	return pb.NewGreeterClient(serveInMemory(t, grpcServer)), limiter
+}
+
+// openStream starts a long stream and waits for its first message, so it is
+// known to hold a slot. Cancelling ctx ends it.
+func openStream(t *testing.T, ctx context.Context, client pb.GreeterClient) {
+	t.Helper()
+	stream, err := client.StreamMessages(ctx, &pb.StreamRequest{Count: 100})
+	if err != nil {
+		t.Fatalf("StreamMessages: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("StreamMessages Recv: %v", err)
+	}
+}
+
+func TestConcurrencyLimitRejectsExcessStreams(t *testing.T) {
+	client, _ := serveLimited(t, "StreamMessages=2")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	openStream(t, ctx, client)
+	openStream(t, ctx, client)
+
+	stream, err := client.StreamMessages(ctx, &pb.StreamRequest{Count: 1})
+	if err != nil {
+		t.Fatalf("StreamMessages: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("third stream Recv error = %v, want ResourceExhausted", err)
+	}
+
+	// Methods without a limit are unaffected
+	if _, err := client.SayHello(ctx, &pb.HelloRequest{Name: "test"}); err != nil {
+		t.Errorf("SayHello: %v", err)
+	}
+}
+
+func TestConcurrencyLimitQueuesStreams(t *testing.T) {
+	client, limiter := serveLimited(t, "StreamMessages=1:1")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	firstCtx, endFirst := context.WithCancel(ctx)
+	defer endFirst()
+	openStream(t, firstCtx, client)
+
+	// The second stream waits in the queue behind the first
+	queued, err := client.StreamMessages(ctx, &pb.StreamRequest{Count: 1})
+	if err != nil {
+		t.Fatalf("StreamMessages: %v", err)
+	}
+	queue := limiter.limits["StreamMessages"].queue
+	for len(queue) == 0 {
+		if ctx.Err() != nil {
+			t.Fatal("second stream never queued")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// With the queue full as well, a third is rejected
+	rejected, err := client.StreamMessages(ctx, &pb.StreamRequest{Count: 1})
+	if err != nil {
+		t.Fatalf("StreamMessages: %v", err)
+	}
+	if _, err := rejected.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("third stream Recv error = %v, want ResourceExhausted", err)
+	}
+
+	// Ending the first stream frees its slot for the queued one
+	endFirst()
+	if _, err := queued.Recv(); err != nil {
+		t.Errorf("queued stream Recv: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// loadRequestCount reads a request count saved by saveRequestCount. A missing
+// file is a count of 0, as on the very first start.
+func loadRequestCount(path string) (int32, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read request count: %w", err)
+	}
+	count, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid request count in %s: %q", path, strings.TrimSpace(string(data)))
+	}
+	return int32(count), nil
+}
+
+// saveRequestCount writes count to path, atomically so a crash mid-write
+// leaves the previous count rather than a torn file
+func saveRequestCount(path string, count int32) error {
+	if err := writeFileAtomic(path, []byte(fmt.Sprintf("%d\n", count))); err != nil {
+		return fmt.Errorf("failed to save request count: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file in path's directory, syncs
+// it and renames it over path, so readers see either the old file or the
+// complete new one
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
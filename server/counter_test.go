@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+)
+
+// runServer serves a server resumed from path, makes n SayHello requests and
+// saves the count, as a server run from start to shutdown does. It returns
+// the count of the last reply.
+func runServer(t *testing.T, path string, n int) int32 {
+	t.Helper()
+	greeter := newServer("test")
+	count, err := loadRequestCount(path)
+	if err != nil {
+		t.Fatalf("loadRequestCount: %v", err)
+	}
+	greeter.requestCount.Store(count)
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, greeter)
+	client := pb.NewGreeterClient(serveInMemory(t, grpcServer))
+	var last int32
+	for range n {
+		resp, err := client.SayHello(context.Background(), &pb.HelloRequest{Name: "test"})
+		if err != nil {
+			t.Fatalf("SayHello: %v", err)
+		}
+		last = resp.Count
+	}
+	grpcServer.Stop()
+
+	if err := saveRequestCount(path, greeter.requestCount.Load()); err != nil {
+		t.Fatalf("saveRequestCount: %v", err)
+	}
+	return last
+}
+
+func TestRequestCountPersistsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "count")
+
+	if got := runServer(t, path, 3); got != 3 {
+		t.Fatalf("count on the first run = %d, want 3", got)
+	}
+	if got := runServer(t, path, 2); got != 5 {
+		t.Errorf("count after a restart = %d, want 5, continuing from the saved 3", got)
+	}
+
+	// Nothing but the count file is left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the count file", len(entries))
+	}
+}
+
+func TestLoadRequestCountInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "count")
+	if err := os.WriteFile(path, []byte("lots\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRequestCount(path); err == nil {
+		t.Error("loadRequestCount of a corrupt file succeeded")
+	}
+}
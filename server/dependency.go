@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// dependencyTimeout bounds each reachability check of a dependency
+const dependencyTimeout = 2 * time.Second
+
+// dependencyCheck reports whether a downstream service the server relies on is reachable
+type dependencyCheck func(ctx context.Context) error
+
+// dialCheck returns a dependencyCheck that connects to addr, a socket path or
+// TCP address in the form accepted by -listen
+func dialCheck(addr string) dependencyCheck {
+	network, address := parseListenAddr(addr)
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// watchDependency reports the server as NOT_SERVING until the dependency is
+// reachable, then polls check every interval in the background until ctx is
+// done, switching to NOT_SERVING again whenever the dependency is lost. The
+// process manager's readiness gate therefore waits for the dependency too.
+func watchDependency(ctx context.Context, healthServer *health.Server, name string, check dependencyCheck, interval time.Duration) {
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	go func() {
+		reachable, checked := false, false
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			checkCtx, cancel := context.WithTimeout(ctx, dependencyTimeout)
+			err := check(checkCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+
+			switch {
+			case err == nil && !reachable:
+				log.Printf("Dependency %s reachable, serving", name)
+				healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+			case err != nil && reachable:
+				log.Printf("Dependency %s unreachable, not serving: %v", name, err)
+				healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+			case err != nil && !checked:
+				log.Printf("Waiting for dependency %s: %v", name, err)
+			}
+			reachable, checked = err == nil, true
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestWatchDependencyServesOnceReachable(t *testing.T) {
+	// An address with nothing listening yet
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	grpcServer := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	conn := serveInMemory(t, grpcServer)
+	healthClient := healthpb.NewHealthClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchDependency(ctx, healthServer, addr, dialCheck(addr), 50*time.Millisecond)
+
+	status := func() healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("health Check: %v", err)
+		}
+		return resp.Status
+	}
+
+	// Unreachable for a few checks: not serving
+	for range 5 {
+		if got := status(); got != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Fatalf("status before the dependency is up = %v, want NOT_SERVING", got)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// The downstream comes up
+	downstream, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer downstream.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for status() != healthpb.HealthCheckResponse_SERVING {
+		if time.Now().After(deadline) {
+			t.Fatal("still NOT_SERVING after the dependency became reachable")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Losing it again takes the server out of service
+	downstream.Close()
+	deadline = time.Now().Add(5 * time.Second)
+	for status() != healthpb.HealthCheckResponse_NOT_SERVING {
+		if time.Now().After(deadline) {
+			t.Fatal("still SERVING after the dependency went away")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
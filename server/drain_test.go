@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestDrainAndStopReportsNotServingWhileDraining(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	conn := serveInMemory(t, grpcServer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	healthClient := healthpb.NewHealthClient(conn)
+	greeter := pb.NewGreeterClient(conn)
+
+	t.Cleanup(func() { shuttingDown.Store(false) })
+	stopped := make(chan struct{})
+	go func() {
+		drainAndStop(grpcServer, healthServer, time.Second, 0)
+		close(stopped)
+	}()
+
+	// Health flips to NOT_SERVING as soon as the drain starts
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("health Check while draining: %v", err)
+		}
+		if resp.Status == healthpb.HealthCheckResponse_NOT_SERVING {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("health status = %v, want NOT_SERVING while draining", resp.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// RPCs are still served until the drain period is over
+	if _, err := greeter.SayHello(ctx, &pb.HelloRequest{Name: "test"}); err != nil {
+		t.Errorf("SayHello while draining: %v", err)
+	}
+	select {
+	case <-stopped:
+		t.Fatal("server stopped before the drain period was over")
+	default:
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after the drain period")
+	}
+}
+
+func TestDrainAndStopBoundsOpenStreams(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	conn := serveInMemory(t, grpcServer)
+	t.Cleanup(func() { shuttingDown.Store(false) })
+
+	// A stream of 100 messages would take 50s to finish
+	stream, err := pb.NewGreeterClient(conn).StreamMessages(context.Background(), &pb.StreamRequest{Count: 100})
+	if err != nil {
+		t.Fatalf("StreamMessages: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("first stream message: %v", err)
+	}
+
+	start := time.Now()
+	stopped := make(chan struct{})
+	go func() {
+		drainAndStop(grpcServer, health.NewServer(), 0, 200*time.Millisecond)
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("drainAndStop waited for the stream to finish")
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("stopped after %v, want the stream given the 200ms stop timeout", elapsed)
+	}
+
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err == io.EOF {
+				t.Error("stream completed, want it cut off by the stop")
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	pb "multi-process-docker/proto/v1"
+	pbv2 "multi-process-docker/proto/v2"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// serverV2 serves version 2 of the Greeter API from a version 1 server, so
+// both versions share one request count
+type serverV2 struct {
+	pbv2.UnimplementedGreeterServer
+	v1 *server
+}
+
+func (s *serverV2) SayHello(ctx context.Context, req *pbv2.HelloRequest) (*pbv2.HelloReply, error) {
+	reply, err := s.v1.SayHello(ctx, &pb.HelloRequest{Name: req.Name})
+	if err != nil {
+		return nil, err
+	}
+	return &pbv2.HelloReply{
+		Message:    reply.Message,
+		Count:      reply.Count,
+		InstanceId: reply.InstanceId,
+		ServedAt:   timestamppb.Now(),
+	}, nil
+}
+
+func (s *serverV2) StreamMessages(req *pbv2.StreamRequest, stream pbv2.Greeter_StreamMessagesServer) error {
+	return streamMessages(req.Count, func(message string, index int32) error {
+		return stream.Send(&pbv2.MessageResponse{Message: message, Index: index})
+	})
+}
+
+func (s *serverV2) GetStats(ctx context.Context, req *emptypb.Empty) (*pbv2.StatsReply, error) {
+	stats, err := s.v1.GetStats(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &pbv2.StatsReply{RequestCount: stats.RequestCount, Uptime: stats.Uptime}, nil
+}
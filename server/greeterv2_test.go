@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+	pbv2 "multi-process-docker/proto/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestBothAPIVersionsOnOneServer(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	greeter := newServer("test")
+	pb.RegisterGreeterServer(grpcServer, greeter)
+	pbv2.RegisterGreeterServer(grpcServer, &serverV2{v1: greeter})
+	conn := serveInMemory(t, grpcServer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	v1Reply, err := pb.NewGreeterClient(conn).SayHello(ctx, &pb.HelloRequest{Name: "v1"})
+	if err != nil {
+		t.Fatalf("v1 SayHello: %v", err)
+	}
+	if v1Reply.Message != "Hello, v1! Welcome to gRPC over UDS." || v1Reply.Count != 1 || v1Reply.InstanceId != "test" {
+		t.Errorf("v1 reply = %v, want the greeting for v1 as request 1 of instance test", v1Reply)
+	}
+
+	before := time.Now()
+	v2Client := pbv2.NewGreeterClient(conn)
+	v2Reply, err := v2Client.SayHello(ctx, &pbv2.HelloRequest{Name: "v2"})
+	if err != nil {
+		t.Fatalf("v2 SayHello: %v", err)
+	}
+	// The count carries on from the v1 call
+	if v2Reply.Message != "Hello, v2! Welcome to gRPC over UDS." || v2Reply.Count != 2 || v2Reply.InstanceId != "test" {
+		t.Errorf("v2 reply = %v, want the greeting for v2 as request 2 of instance test", v2Reply)
+	}
+	if servedAt := v2Reply.ServedAt.AsTime(); servedAt.Before(before.Truncate(time.Second)) || servedAt.After(time.Now()) {
+		t.Errorf("v2 served_at = %v, want the time of the call", servedAt)
+	}
+
+	stream, err := v2Client.StreamMessages(ctx, &pbv2.StreamRequest{Count: 1})
+	if err != nil {
+		t.Fatalf("v2 StreamMessages: %v", err)
+	}
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("v2 stream Recv: %v", err)
+	}
+	if msg.Message != "Stream message number 1" || msg.Index != 1 {
+		t.Errorf("v2 stream message = %v, want message number 1", msg)
+	}
+
+	stats, err := v2Client.GetStats(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("v2 GetStats: %v", err)
+	}
+	if stats.RequestCount != 2 {
+		t.Errorf("v2 request count = %d, want 2 across both versions", stats.RequestCount)
+	}
+}
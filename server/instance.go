@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// instanceHeader is the response header key carrying the serving instance's ID
+const instanceHeader = "x-instance-id"
+
+// newInstanceID returns an ID for this server instance: the hostname with a
+// random suffix, so instances sharing a host or a container image still differ
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "grpc-server"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return host + "-" + hex.EncodeToString(suffix)
+}
+
+// instanceTagger sends the instance ID with every response, so a client can
+// tell which of several instances answered any RPC
+type instanceTagger struct {
+	id string
+}
+
+func (t instanceTagger) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := grpc.SetHeader(ctx, metadata.Pairs(instanceHeader, t.id)); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (t instanceTagger) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := ss.SetHeader(metadata.Pairs(instanceHeader, t.id)); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestInstancesReturnDistinctIDs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	seen := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		instance := instanceTagger{id: newInstanceID()}
+		grpcServer := grpc.NewServer(
+			grpc.UnaryInterceptor(instance.unaryInterceptor),
+			grpc.StreamInterceptor(instance.streamInterceptor),
+		)
+		pb.RegisterGreeterServer(grpcServer, newServer(instance.id))
+		client := pb.NewGreeterClient(serveInMemory(t, grpcServer))
+
+		var header metadata.MD
+		reply, err := client.SayHello(ctx, &pb.HelloRequest{Name: "test"}, grpc.Header(&header))
+		if err != nil {
+			t.Fatalf("SayHello: %v", err)
+		}
+		if reply.InstanceId != instance.id {
+			t.Errorf("HelloReply.InstanceId = %q, want %q", reply.InstanceId, instance.id)
+		}
+		if got := header.Get(instanceHeader); len(got) != 1 || got[0] != instance.id {
+			t.Errorf("header %s = %q, want %q", instanceHeader, got, instance.id)
+		}
+
+		stream, err := client.StreamMessages(ctx, &pb.StreamRequest{Count: 1})
+		if err != nil {
+			t.Fatalf("StreamMessages: %v", err)
+		}
+		header, err = stream.Header()
+		if err != nil {
+			t.Fatalf("stream header: %v", err)
+		}
+		if got := header.Get(instanceHeader); len(got) != 1 || got[0] != instance.id {
+			t.Errorf("stream header %s = %q, want %q", instanceHeader, got, instance.id)
+		}
+
+		if seen[reply.InstanceId] {
+			t.Errorf("instance ID %q returned by two instances", reply.InstanceId)
+		}
+		seen[reply.InstanceId] = true
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+// listenFlags collects the addresses of repeated -listen flags
+type listenFlags []string
+
+func (f *listenFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *listenFlags) Set(value string) error {
+	if value == "" {
+		return errors.New("empty listen address")
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// parseListenAddr splits a -listen value into a network and address.
+// unix:// and tcp:// prefixes are explicit; otherwise anything containing a
+// slash is a socket path and the rest are TCP host:port addresses.
+func parseListenAddr(value string) (network, address string) {
+	switch {
+	case strings.HasPrefix(value, "unix://"):
+		return "unix", strings.TrimPrefix(value, "unix://")
+	case strings.HasPrefix(value, "tcp://"):
+		return "tcp", strings.TrimPrefix(value, "tcp://")
+	case strings.Contains(value, "/"):
+		return "unix", value
+	default:
+		return "tcp", value
+	}
+}
+
+// serverListener is a listener the server serves on, with what is needed to
+// clean up after it
+type serverListener struct {
+	net.Listener
+	// Socket file of a UDS listener, empty for TCP
+	socketPath string
+	socketInfo os.FileInfo
+}
+
+// listen opens a listener for a -listen value. Socket files are created with
+// listenSocket and given mode and group. If that fails the socket keeps its
+// default permissions with a warning, or with strictPerms the listen fails.
+func listen(value string, mode os.FileMode, group string, strictPerms bool) (*serverListener, error) {
+	network, address := parseListenAddr(value)
+	if network == "tcp" {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on TCP: %w", err)
+		}
+		return &serverListener{Listener: listener}, nil
+	}
+
+	listener, info, err := listenSocket(address)
+	if err != nil {
+		return nil, err
+	}
+	if err := setSocketPermissions(address, mode, group); err != nil {
+		if strictPerms {
+			listener.Close()
+			return nil, err
+		}
+		log.Printf("Warning: %v; serving %s with its default permissions", err, address)
+	}
+	return &serverListener{Listener: listener, socketPath: address, socketInfo: info}, nil
+}
+
+// shuttingDown is set once the server starts shutting down, after which a
+// listener closing under Serve is part of the shutdown rather than a failure
+var shuttingDown atomic.Bool
+
+// expectedServeError reports whether an error from Serve is a normal part of
+// stopping: ErrServerStopped when another listener's failure stopped the
+// server before this one started, or a closed listener during shutdown
+func expectedServeError(err error) bool {
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return true
+	}
+	return shuttingDown.Load() && errors.Is(err, net.ErrClosed)
+}
+
+// serveAll serves grpcServer on every listener concurrently. If serving on one
+// fails, the server is stopped so the others shut down with it. It returns
+// once all have stopped, with the errors of those that failed.
+func serveAll(grpcServer *grpc.Server, listeners []*serverListener) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(listeners))
+	for i, listener := range listeners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := grpcServer.Serve(listener)
+			if err == nil {
+				return
+			}
+			if expectedServeError(err) {
+				logf(levelDebug, "Stopped serving on %s: %v", listener.Addr(), err)
+				return
+			}
+			errs[i] = fmt.Errorf("%s: %w", listener.Addr(), err)
+			grpcServer.Stop()
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
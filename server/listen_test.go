@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+)
+
+func TestParseListenAddr(t *testing.T) {
+	tests := []struct {
+		value, network, address string
+	}{
+		{"/tmp/grpc.sock", "unix", "/tmp/grpc.sock"},
+		{"unix:///tmp/grpc.sock", "unix", "/tmp/grpc.sock"},
+		{"./grpc.sock", "unix", "./grpc.sock"},
+		{"127.0.0.1:50051", "tcp", "127.0.0.1:50051"},
+		{":50051", "tcp", ":50051"},
+		{"tcp://localhost:50051", "tcp", "localhost:50051"},
+	}
+	for _, tt := range tests {
+		network, address := parseListenAddr(tt.value)
+		if network != tt.network || address != tt.address {
+			t.Errorf("parseListenAddr(%q) = %q, %q, want %q, %q", tt.value, network, address, tt.network, tt.address)
+		}
+	}
+}
+
+func TestServeAllOnUnixAndTCP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	var listeners []*serverListener
+	for _, addr := range []string{path, "127.0.0.1:0"} {
+		listener, err := listen(addr, 0660, "", true)
+		if err != nil {
+			t.Fatalf("listen(%q): %v", addr, err)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	served := make(chan error, 1)
+	go func() { served <- serveAll(grpcServer, listeners) }()
+
+	for _, target := range []string{"unix://" + path, listeners[1].Addr().String()} {
+		conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err = pb.NewGreeterClient(conn).SayHello(ctx, &pb.HelloRequest{Name: "test"})
+		cancel()
+		conn.Close()
+		if err != nil {
+			t.Errorf("SayHello via %s: %v", target, err)
+		}
+	}
+
+	// Stopping the server stops serving on every listener
+	grpcServer.GracefulStop()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serveAll() = %v, want nil after a graceful stop", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveAll did not return after the server stopped")
+	}
+}
+
+func TestServeAllListenerClosedDuringShutdown(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	t.Cleanup(func() { shuttingDown.Store(false) })
+
+	listener, err := listen("127.0.0.1:0", 0, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	served := make(chan error, 1)
+	go func() { served <- serveAll(grpcServer, []*serverListener{listener}) }()
+
+	// The listener closes under Serve while the server drains, as a deferred
+	// Close can when shutdown races with the serving goroutine
+	stopped := make(chan struct{})
+	go func() {
+		drainAndStop(grpcServer, health.NewServer(), 100*time.Millisecond, 0)
+		close(stopped)
+	}()
+	for !shuttingDown.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	listener.Close()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serveAll() = %v, want nil for a listener closed during shutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveAll did not return after the listener closed")
+	}
+	<-stopped
+	if strings.Contains(logs.String(), "closed") {
+		t.Errorf("shutdown logged %q, want no error for the closed listener", logs.String())
+	}
+}
+
+func TestServeAllListenerClosedWhileServing(t *testing.T) {
+	listener, err := listen("127.0.0.1:0", 0, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	served := make(chan error, 1)
+	go func() { served <- serveAll(grpcServer, []*serverListener{listener}) }()
+
+	// Without a shutdown in progress a closed listener is a real failure
+	time.Sleep(50 * time.Millisecond)
+	listener.Close()
+	select {
+	case err := <-served:
+		if err == nil {
+			t.Error("serveAll() = nil, want an error for a listener closed while serving")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveAll did not return after the listener closed")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// logLevel is the verbosity of the server's logs. Lines logged at a level are
+// written only while the current level is at least as verbose.
+type logLevel int32
+
+const (
+	levelError logLevel = iota - 1
+	levelInfo
+	levelDebug
+)
+
+func (l logLevel) String() string {
+	switch l {
+	case levelError:
+		return "error"
+	case levelInfo:
+		return "info"
+	case levelDebug:
+		return "debug"
+	default:
+		return fmt.Sprintf("logLevel(%d)", int32(l))
+	}
+}
+
+// parseLogLevel parses a -log-level value
+func parseLogLevel(value string) (logLevel, error) {
+	for _, level := range []logLevel{levelError, levelInfo, levelDebug} {
+		if value == level.String() {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q (expected error, info or debug)", value)
+}
+
+// currentLevel holds the server's log level, info until it is changed
+var currentLevel atomic.Int32
+
+func setLogLevel(level logLevel) {
+	currentLevel.Store(int32(level))
+}
+
+func getLogLevel() logLevel {
+	return logLevel(currentLevel.Load())
+}
+
+// cycleLogLevel moves to the next more verbose level, wrapping from debug
+// back to error, and returns the new level
+func cycleLogLevel() logLevel {
+	next := getLogLevel() + 1
+	if next > levelDebug {
+		next = levelError
+	}
+	setLogLevel(next)
+	return next
+}
+
+// logf logs a line at level, if the current level includes it
+func logf(level logLevel, format string, args ...any) {
+	if level <= getLogLevel() {
+		log.Printf(format, args...)
+	}
+}
+
+// cycleLogLevelOnSignal cycles the log level on every SIGUSR1, so verbosity can
+// be raised and lowered for live debugging without a restart
+func cycleLogLevelOnSignal() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR1)
+	go func() {
+		for range sigChan {
+			// Logged whatever the level, so the change is always visible
+			log.Printf("Received SIGUSR1, log level is now %s", cycleLogLevel())
+		}
+	}()
+}
+
+// requestLogUnaryInterceptor logs every RPC at debug level, and RPCs that
+// fail at info level
+func requestLogUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logRequest(info.FullMethod, time.Since(start), err)
+	return resp, err
+}
+
+func requestLogStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logRequest(info.FullMethod, time.Since(start), err)
+	return err
+}
+
+func logRequest(method string, elapsed time.Duration, err error) {
+	if err != nil {
+		logf(levelInfo, "RPC %s failed after %v: %s", method, elapsed.Round(time.Microsecond), status.Code(err))
+		return
+	}
+	logf(levelDebug, "RPC %s completed in %v", method, elapsed.Round(time.Microsecond))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+)
+
+func TestRequestLogHonorsLevel(t *testing.T) {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(requestLogUnaryInterceptor))
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	client := pb.NewGreeterClient(serveInMemory(t, grpcServer))
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	defer setLogLevel(levelInfo)
+
+	const debugLine = "RPC /hello.Greeter/SayHello completed in"
+	tests := []struct {
+		level     logLevel
+		wantDebug bool
+		wantInfo  bool
+	}{
+		{levelError, false, false},
+		{levelInfo, false, true},
+		{levelDebug, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			setLogLevel(tt.level)
+			logs.Reset()
+			if _, err := client.SayHello(context.Background(), &pb.HelloRequest{Name: "test"}); err != nil {
+				t.Fatalf("SayHello: %v", err)
+			}
+			if got := strings.Contains(logs.String(), debugLine); got != tt.wantDebug {
+				t.Errorf("debug request line logged = %v, want %v:\n%s", got, tt.wantDebug, logs.String())
+			}
+			if got := strings.Contains(logs.String(), "Received SayHello request"); got != tt.wantInfo {
+				t.Errorf("info line logged = %v, want %v:\n%s", got, tt.wantInfo, logs.String())
+			}
+		})
+	}
+}
+
+func TestCycleLogLevel(t *testing.T) {
+	defer setLogLevel(levelInfo)
+	setLogLevel(levelInfo)
+	for _, want := range []logLevel{levelDebug, levelError, levelInfo} {
+		if got := cycleLogLevel(); got != want {
+			t.Errorf("cycleLogLevel() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for _, level := range []logLevel{levelError, levelInfo, levelDebug} {
+		if got, err := parseLogLevel(level.String()); err != nil || got != level {
+			t.Errorf("parseLogLevel(%q) = %v, %v", level, got, err)
+		}
+	}
+	if _, err := parseLogLevel("verbose"); err == nil {
+		t.Error("parseLogLevel(verbose) succeeded")
+	}
+}
@@ -2,79 +2,380 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strconv"
 	"sync/atomic"
 	"syscall"
 	"time"
 
-	pb "multi-process-docker/proto"
+	"multi-process-docker/internal/buildinfo"
+	"multi-process-docker/internal/config"
+	"multi-process-docker/internal/tracing"
+	pb "multi-process-docker/proto/v1"
+	pbv2 "multi-process-docker/proto/v2"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	// Registers the gzip compressor, so compressed requests are always decoded
+	// and answered with compressed responses
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
-const socketPath = "/tmp/grpc.sock"
+// streamInterval is the pause after each message sent by StreamMessages.
+// The client sizes its stream deadline from it.
+const streamInterval = 500 * time.Millisecond
 
 type server struct {
 	pb.UnimplementedGreeterServer
 	requestCount atomic.Int32
+	startedAt    time.Time
+	// Returned in every HelloReply
+	instanceID string
+}
+
+func newServer(instanceID string) *server {
+	return &server{startedAt: time.Now(), instanceID: instanceID}
 }
 
 func (s *server) SayHello(ctx context.Context, req *pb.HelloRequest) (*pb.HelloReply, error) {
+	// A caller that has given up, or whose deadline passed while the call
+	// waited, gets DeadlineExceeded or Canceled and is not counted. Checks like
+	// this belong before and between any expensive steps the handler grows.
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
 	count := s.requestCount.Add(1)
-	log.Printf("Received SayHello request from: %s (request #%d)", req.Name, count)
+	logf(levelInfo, "Received SayHello request from: %s (request #%d)", req.Name, count)
 
 	return &pb.HelloReply{
-		Message: fmt.Sprintf("Hello, %s! Welcome to gRPC over UDS.", req.Name),
-		Count:   count,
+		Message:    fmt.Sprintf("Hello, %s! Welcome to gRPC over UDS.", req.Name),
+		Count:      count,
+		InstanceId: s.instanceID,
 	}, nil
 }
 
 func (s *server) StreamMessages(req *pb.StreamRequest, stream pb.Greeter_StreamMessagesServer) error {
-	log.Printf("Received StreamMessages request for %d messages", req.Count)
+	return streamMessages(req.Count, func(message string, index int32) error {
+		return stream.Send(&pb.MessageResponse{Message: message, Index: index})
+	})
+}
+
+// streamMessages sends count numbered messages through send, pausing
+// streamInterval after each, for the StreamMessages of every API version
+func streamMessages(count int32, send func(message string, index int32) error) error {
+	logf(levelInfo, "Received StreamMessages request for %d messages", count)
 
-	for i := int32(0); i < req.Count; i++ {
-		if err := stream.Send(&pb.MessageResponse{
-			Message: fmt.Sprintf("Stream message number %d", i+1),
-			Index:   i + 1,
-		}); err != nil {
+	for i := int32(0); i < count; i++ {
+		if err := send(fmt.Sprintf("Stream message number %d", i+1), i+1); err != nil {
 			return err
 		}
-		time.Sleep(500 * time.Millisecond)
+		time.Sleep(streamInterval)
 	}
 
-	log.Printf("Completed streaming %d messages", req.Count)
+	logf(levelInfo, "Completed streaming %d messages", count)
 	return nil
 }
 
-func main() {
-	log.Println("Starting gRPC Server...")
+func (s *server) GetStats(ctx context.Context, _ *emptypb.Empty) (*pb.StatsReply, error) {
+	return &pb.StatsReply{
+		RequestCount: s.requestCount.Load(),
+		Uptime:       durationpb.New(time.Since(s.startedAt)),
+	}, nil
+}
+
+// removeSocket deletes the socket file at path if it is still the one described by created
+func removeSocket(path string, created os.FileInfo) {
+	current, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to stat socket for cleanup: %v", err)
+		}
+		return
+	}
 
-	// Remove existing socket if it exists
-	if err := os.RemoveAll(socketPath); err != nil {
-		log.Fatalf("Failed to remove existing socket: %v", err)
+	if !os.SameFile(created, current) {
+		log.Printf("Socket %s was replaced by another instance, leaving it in place", path)
+		return
+	}
+
+	if err := os.Remove(path); err != nil {
+		log.Printf("Failed to remove socket: %v", err)
+		return
+	}
+	log.Printf("Removed socket %s", path)
+}
+
+// lookupGroupID resolves a group name or numeric GID
+func lookupGroupID(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// socketDirMode is the permission of socket directories created by listenSocket.
+// Clients need to traverse the directory; access to the socket itself is
+// governed by -socket-mode.
+const socketDirMode = 0755
+
+// staleSocketDialTimeout bounds the check for a server still listening on an
+// existing socket file
+const staleSocketDialTimeout = time.Second
+
+// errSocketInUse is returned by listenSocket when another server still accepts
+// connections on the socket
+var errSocketInUse = errors.New("socket in use by another process")
+
+// listenSocket creates the Unix socket at path, replacing any stale socket and
+// creating missing parent directories, and returns it with its file info. A
+// socket another server still listens on is left alone and errSocketInUse
+// returned.
+func listenSocket(path string) (*net.UnixListener, os.FileInfo, error) {
+	if err := os.MkdirAll(filepath.Dir(path), socketDirMode); err != nil {
+		return nil, nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	// A socket that still answers belongs to a live server; only one nobody
+	// listens on any more is stale and safe to replace
+	if conn, err := net.DialTimeout("unix", path, staleSocketDialTimeout); err == nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("%w: %s", errSocketInUse, path)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return nil, nil, fmt.Errorf("failed to remove existing socket: %w", err)
 	}
 
 	// Create Unix Domain Socket listener
-	listener, err := net.Listen("unix", socketPath)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen on UDS: %w", err)
+	}
+	unixListener := listener.(*net.UnixListener)
+
+	// The socket file is removed explicitly on shutdown, and only if it is still
+	// ours, so a stopping server never deletes the socket of its replacement
+	unixListener.SetUnlinkOnClose(false)
+	info, err := os.Stat(path)
 	if err != nil {
-		log.Fatalf("Failed to listen on UDS: %v", err)
+		unixListener.Close()
+		return nil, nil, fmt.Errorf("failed to stat socket: %w", err)
+	}
+	return unixListener, info, nil
+}
+
+// chmod changes the mode of a socket file. Tests replace it to simulate a
+// filesystem that refuses permission changes.
+var chmod = os.Chmod
+
+// setSocketPermissions applies mode to the socket file at path and, when group
+// is not empty, makes that group its owner
+func setSocketPermissions(path string, mode os.FileMode, group string) error {
+	if err := chmod(path, mode); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %w", err)
 	}
-	defer listener.Close()
 
-	// Set socket permissions
-	if err := os.Chmod(socketPath, 0666); err != nil {
-		log.Fatalf("Failed to set socket permissions: %v", err)
+	if group != "" {
+		gid, err := lookupGroupID(group)
+		if err != nil {
+			return fmt.Errorf("failed to resolve socket group: %w", err)
+		}
+		if err := os.Chown(path, -1, gid); err != nil {
+			return fmt.Errorf("failed to set socket group: %w", err)
+		}
+	}
+	return nil
+}
+
+// drainAndStop reports NOT_SERVING on every health service, keeps serving for
+// drain so load balancers notice and move traffic away, then stops gracefully.
+// Calls still running stopTimeout after that are cut off, so a long stream
+// cannot hold up shutdown; a stopTimeout of 0 waits for every call to finish.
+func drainAndStop(grpcServer *grpc.Server, healthServer *health.Server, drain, stopTimeout time.Duration) {
+	shuttingDown.Store(true)
+	healthServer.Shutdown()
+	if drain > 0 {
+		log.Printf("Health set to NOT_SERVING, draining for %v before stopping", drain)
+		time.Sleep(drain)
 	}
 
-	log.Printf("gRPC Server listening on Unix Domain Socket: %s", socketPath)
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	if stopTimeout <= 0 {
+		<-stopped
+		return
+	}
+	select {
+	case <-stopped:
+	case <-time.After(stopTimeout):
+		log.Printf("Calls still running %v after stopping gracefully, cancelling them", stopTimeout)
+		grpcServer.Stop()
+		<-stopped
+	}
+}
+
+func main() {
+	socketFlag := flag.String("socket", "", "Unix socket path to listen on (default $GRPC_SOCKET_PATH or "+config.DefaultSocketPath+")")
+	var listenAddrs listenFlags
+	flag.Var(&listenAddrs, "listen", "Address to serve on, repeatable: a socket path or unix://path, or a TCP host:port or tcp://host:port (default the -socket path)")
+	rateLimits := flag.String("rate-limit", "", "Per-method rate limits in requests per second, e.g. SayHello=100,StreamMessages=5")
+	maxInFlight := flag.String("max-in-flight", "", "Per-method limits on concurrent calls, optionally with how many more may queue, e.g. StreamMessages=10:20,SayHello=100")
+	socketModeFlag := flag.String("socket-mode", "0666", "Permission bits for the socket file (octal)")
+	socketGroup := flag.String("socket-group", "", "Group name or GID to own the socket file")
+	strictPerms := flag.Bool("strict-perms", false, "Exit if -socket-mode or -socket-group cannot be applied, instead of serving with the socket's default permissions")
+	dependency := flag.String("dependency", "", "Address of a downstream service (socket path or host:port) that must be reachable for health to report SERVING")
+	dependencyInterval := flag.Duration("dependency-interval", 2*time.Second, "How often the -dependency address is checked")
+	drain := flag.Duration("drain", 0, "How long to keep serving with health NOT_SERVING on shutdown, so load balancers stop sending traffic before the server stops")
+	stopTimeout := flag.Duration("stop-timeout", 5*time.Second, "How long calls still running after -drain are given to finish before they are cancelled (0 waits for them all)")
+	authToken := flag.String("auth-token", "", "Shared secret required on every RPC except health checks (default $"+config.AuthTokenEnv+", disabled when empty)")
+	clientVersions := flag.String("client-versions", "", "Range of client build versions accepted, as min..max with max excluded, e.g. v1.2.0..v2.0.0; clients outside it fail with FailedPrecondition (disabled when empty)")
+	readyFile := flag.String("ready-file", "", "File created once the server is listening and removed on shutdown, for file-based readiness probes (disabled when empty)")
+	countFile := flag.String("count-file", "", "File the request count is saved to on shutdown and resumed from on startup, so it carries across restarts (disabled when empty)")
+	logLevelFlag := flag.String("log-level", levelInfo.String(), "Log verbosity: error, info or debug, which also logs every RPC. SIGUSR1 cycles through them at runtime")
+	version := flag.Bool("version", false, "Print the version and exit")
+	flag.Parse()
+
+	if *version {
+		fmt.Println("grpc-server", buildinfo.Get())
+		return
+	}
+
+	socketPath := config.SocketPath(*socketFlag)
+
+	socketMode, err := strconv.ParseUint(*socketModeFlag, 8, 32)
+	if err != nil || socketMode > 0777 {
+		log.Fatalf("Invalid -socket-mode %q: must be octal permission bits such as 0660", *socketModeFlag)
+	}
+
+	level, err := parseLogLevel(*logLevelFlag)
+	if err != nil {
+		log.Fatalf("Invalid -log-level: %v", err)
+	}
+	setLogLevel(level)
+	cycleLogLevelOnSignal()
+
+	instance := instanceTagger{id: newInstanceID()}
+	log.Printf("Starting gRPC Server %s as instance %s...", buildinfo.Get(), instance.id)
+
+	limiter, err := parseRateLimits(*rateLimits)
+	if err != nil {
+		log.Fatalf("Invalid -rate-limit: %v", err)
+	}
+	concurrency, err := parseConcurrencyLimits(*maxInFlight)
+	if err != nil {
+		log.Fatalf("Invalid -max-in-flight: %v", err)
+	}
+
+	// Set up tracing (exports only when an OTLP endpoint is configured)
+	shutdownTracing, err := tracing.Setup(context.Background(), "grpc-server")
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Printf("Failed to flush traces: %v", err)
+		}
+	}()
+
+	if len(listenAddrs) == 0 {
+		listenAddrs = listenFlags{socketPath}
+	}
+
+	// A replacement started for a rolling restart listens on a staging path,
+	// which the process manager renames over socketPath once we accept connections
+	stagingPath := os.Getenv(config.StagingSocketEnv)
+
+	// Under systemd socket activation the sockets are passed in, and the
+	// -socket and -listen addresses are not used
+	listeners, err := activatedListeners()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	for _, listener := range listeners {
+		defer listener.Close()
+		log.Printf("gRPC Server listening on socket-activated %s: %s", listener.Addr().Network(), listener.Addr())
+	}
+	if listeners == nil {
+		for _, addr := range listenAddrs {
+			if network, address := parseListenAddr(addr); network == "unix" && address == socketPath && stagingPath != "" {
+				addr = stagingPath
+			}
+			listener, err := listen(addr, os.FileMode(socketMode), *socketGroup, *strictPerms)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			defer listener.Close()
+			listeners = append(listeners, listener)
+
+			if listener.socketPath != "" {
+				log.Printf("gRPC Server listening on Unix Domain Socket: %s", listener.socketPath)
+			} else {
+				log.Printf("gRPC Server listening on TCP: %s", listener.Addr())
+			}
+		}
+	}
+
+	auth := &tokenAuth{token: config.AuthToken(*authToken)}
+	if auth.token == "" {
+		log.Println("RPC authentication disabled: no auth token configured")
+	}
+
+	versionCheck := &clientVersionCheck{}
+	if *clientVersions != "" {
+		accepted, err := buildinfo.ParseVersionRange(*clientVersions)
+		if err != nil {
+			log.Fatalf("Invalid -client-versions: %v", err)
+		}
+		versionCheck.accepted = &accepted
+		log.Printf("Accepting clients %v", accepted)
+	}
 
 	// Create gRPC server
-	grpcServer := grpc.NewServer()
-	pb.RegisterGreeterServer(grpcServer, &server{})
+	grpcServer := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(requestLogUnaryInterceptor, buildInfoUnaryInterceptor, instance.unaryInterceptor, auth.unaryInterceptor, versionCheck.unaryInterceptor, limiter.unaryInterceptor, concurrency.unaryInterceptor),
+		grpc.ChainStreamInterceptor(requestLogStreamInterceptor, buildInfoStreamInterceptor, instance.streamInterceptor, auth.streamInterceptor, versionCheck.streamInterceptor, limiter.streamInterceptor, concurrency.streamInterceptor),
+	)
+	greeter := newServer(instance.id)
+	if *countFile != "" {
+		count, err := loadRequestCount(*countFile)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		greeter.requestCount.Store(count)
+		log.Printf("Resuming from request count %d", count)
+	}
+	// Both API versions are served, sharing the request count
+	pb.RegisterGreeterServer(grpcServer, greeter)
+	pbv2.RegisterGreeterServer(grpcServer, &serverV2{v1: greeter})
+
+	// Health service used by the process manager as a readiness gate
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	if *dependency != "" {
+		watchDependency(context.Background(), healthServer, *dependency, dialCheck(*dependency), *dependencyInterval)
+	}
 
 	// Handle graceful shutdown
 	go func() {
@@ -82,14 +383,34 @@ func main() {
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		sig := <-sigChan
 		log.Printf("Received signal: %v. Shutting down gracefully...", sig)
-		grpcServer.GracefulStop()
+		if *readyFile != "" {
+			// Not ready any more while draining, like the health status
+			removeReadyFile(*readyFile)
+		}
+		drainAndStop(grpcServer, healthServer, *drain, *stopTimeout)
 	}()
 
 	// Start serving
 	log.Println("gRPC Server is ready to accept connections")
-	if err := grpcServer.Serve(listener); err != nil {
+	if err := serveReady(grpcServer, listeners, *readyFile); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
 
+	if *countFile != "" {
+		if err := saveRequestCount(*countFile, greeter.requestCount.Load()); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	for _, listener := range listeners {
+		if listener.socketPath == "" {
+			continue
+		}
+		removeSocket(listener.socketPath, listener.socketInfo)
+		if listener.socketPath == stagingPath {
+			// Our staging socket may have been renamed into place since
+			removeSocket(socketPath, listener.socketInfo)
+		}
+	}
 	log.Println("gRPC Server stopped")
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serveInMemory serves grpcServer on an in-memory listener until the test
+// ends and returns a client connection to it. opts are added to the dial options.
+func serveInMemory(t *testing.T, grpcServer *grpc.Server, opts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		append([]grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSayHelloHonorsContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"cancelled", cancelled, codes.Canceled},
+		{"deadline passed", expired, codes.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer("test")
+			start := time.Now()
+			resp, err := s.SayHello(tt.ctx, &pb.HelloRequest{Name: "test"})
+			if status.Code(err) != tt.want || resp != nil {
+				t.Errorf("SayHello() = %v, %v, want %v", resp, err, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+				t.Errorf("SayHello took %v to give up", elapsed)
+			}
+			if count := s.requestCount.Load(); count != 0 {
+				t.Errorf("request count = %d after an abandoned call, want 0", count)
+			}
+		})
+	}
+}
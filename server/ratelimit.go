@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rateLimiter enforces a token-bucket request rate per RPC method
+type rateLimiter struct {
+	limiters map[string]*rate.Limiter
+}
+
+// parseRateLimits parses a spec such as "SayHello=100,StreamMessages=5" into
+// per-method limits in requests per second. Bursts equal one second's worth of requests.
+func parseRateLimits(spec string) (*rateLimiter, error) {
+	rl := &rateLimiter{limiters: make(map[string]*rate.Limiter)}
+	if spec == "" {
+		return rl, nil
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		method, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || method == "" {
+			return nil, fmt.Errorf("invalid rate limit %q, expected Method=rps", entry)
+		}
+		rps, err := strconv.ParseFloat(value, 64)
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %q", method, value)
+		}
+		rl.limiters[method] = rate.NewLimiter(rate.Limit(rps), int(math.Ceil(rps)))
+	}
+	return rl, nil
+}
+
+// allow reports whether a call to fullMethod (e.g. "/hello.Greeter/SayHello") may proceed
+func (rl *rateLimiter) allow(fullMethod string) error {
+	limiter, ok := rl.limiters[path.Base(fullMethod)]
+	if !ok || limiter.Allow() {
+		return nil
+	}
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s", path.Base(fullMethod))
+}
+
+func (rl *rateLimiter) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := rl.allow(info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (rl *rateLimiter) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := rl.allow(info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	tests := []struct {
+		spec    string
+		methods []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"SayHello=100", []string{"SayHello"}, false},
+		{"SayHello=100, StreamMessages=0.5", []string{"SayHello", "StreamMessages"}, false},
+		{"SayHello", nil, true},
+		{"=5", nil, true},
+		{"SayHello=fast", nil, true},
+		{"SayHello=0", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rl, err := parseRateLimits(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRateLimits(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(rl.limiters) != len(tt.methods) {
+				t.Errorf("got %d limited methods, want %d", len(rl.limiters), len(tt.methods))
+			}
+			for _, method := range tt.methods {
+				if rl.limiters[method] == nil {
+					t.Errorf("no limiter for %s", method)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitExceeded(t *testing.T) {
+	limiter, err := parseRateLimits("SayHello=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(limiter.unaryInterceptor),
+		grpc.ChainStreamInterceptor(limiter.streamInterceptor),
+	)
+	pb.RegisterGreeterServer(grpcServer, &server{})
+	client := pb.NewGreeterClient(serveInMemory(t, grpcServer))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Twice the burst in a tight loop, far faster than 5 per second
+	var allowed, exhausted int
+	for range 10 {
+		_, err := client.SayHello(ctx, &pb.HelloRequest{Name: "flood"})
+		switch status.Code(err) {
+		case codes.OK:
+			allowed++
+		case codes.ResourceExhausted:
+			exhausted++
+		default:
+			t.Fatalf("SayHello: %v", err)
+		}
+	}
+	if exhausted == 0 || allowed < 5 {
+		t.Errorf("%d allowed, %d rejected; want the burst of 5 allowed and the rest ResourceExhausted", allowed, exhausted)
+	}
+
+	// Methods without a limit are unaffected
+	stream, err := client.StreamMessages(ctx, &pb.StreamRequest{Count: 1})
+	if err != nil {
+		t.Fatalf("StreamMessages: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Errorf("StreamMessages Recv: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"google.golang.org/grpc"
+)
+
+// createReadyFile writes the server's PID to path, marking it as serving for
+// file-based readiness probes
+func createReadyFile(path string) error {
+	if err := writeFileAtomic(path, []byte(strconv.Itoa(os.Getpid())+"\n")); err != nil {
+		return fmt.Errorf("failed to create ready file: %w", err)
+	}
+	return nil
+}
+
+// removeReadyFile deletes the readiness file, if it is still there
+func removeReadyFile(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove ready file: %v", err)
+	}
+}
+
+// serveReady serves like serveAll, with the readiness file at readyPath, if
+// set, present while the server serves. The listeners already accept
+// connections, so the file is created just before serving starts.
+func serveReady(grpcServer *grpc.Server, listeners []*serverListener, readyPath string) error {
+	if readyPath == "" {
+		return serveAll(grpcServer, listeners)
+	}
+	if err := createReadyFile(readyPath); err != nil {
+		return err
+	}
+	defer removeReadyFile(readyPath)
+	return serveAll(grpcServer, listeners)
+}
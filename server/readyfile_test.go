@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+)
+
+func TestServeReadyFile(t *testing.T) {
+	dir := t.TempDir()
+	readyPath := filepath.Join(dir, "ready")
+	listener, err := listen(filepath.Join(dir, "grpc.sock"), 0666, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	if _, err := os.Stat(readyPath); !os.IsNotExist(err) {
+		t.Fatalf("ready file exists before serving (stat: %v)", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- serveReady(grpcServer, []*serverListener{listener}, readyPath) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var data []byte
+	for {
+		if data, err = os.ReadFile(readyPath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no ready file while serving: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, want := strings.TrimSpace(string(data)), strconv.Itoa(os.Getpid()); got != want {
+		t.Errorf("ready file holds %q, want the PID %s", got, want)
+	}
+
+	grpcServer.GracefulStop()
+	if err := <-served; err != nil {
+		t.Fatalf("serveReady: %v", err)
+	}
+	if _, err := os.Stat(readyPath); !os.IsNotExist(err) {
+		t.Errorf("ready file left behind after shutdown (stat: %v)", err)
+	}
+}
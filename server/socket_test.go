@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestSetSocketPermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// The current group, which the socket may always be given to
+	gid := os.Getgid()
+	if err := setSocketPermissions(path, 0660, strconv.Itoa(gid)); err != nil {
+		t.Fatalf("setSocketPermissions: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0660 {
+		t.Errorf("socket permissions = %04o, want 0660", perm)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Gid) != gid {
+		t.Errorf("socket group = %d, want %d", stat.Gid, gid)
+	}
+}
+
+func TestSetSocketPermissionsUnknownGroup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	if err := setSocketPermissions(path, 0660, "no-such-group-here"); err == nil {
+		t.Error("setSocketPermissions succeeded for an unknown group")
+	}
+}
+
+func TestListenWhenChmodFails(t *testing.T) {
+	chmod = func(string, os.FileMode) error { return syscall.EROFS }
+	defer func() { chmod = os.Chmod }()
+
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	if _, err := listen(path, 0660, "", true); err == nil || !errors.Is(err, syscall.EROFS) {
+		t.Errorf("strict listen() = %v, want the chmod error", err)
+	}
+
+	listener, err := listen(path, 0660, "", false)
+	if err != nil {
+		t.Fatalf("listen() = %v, want the chmod failure to be a warning", err)
+	}
+	defer listener.Close()
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := pb.NewGreeterClient(conn).SayHello(ctx, &pb.HelloRequest{Name: "test"}); err != nil {
+		t.Fatalf("SayHello: %v", err)
+	}
+}
+
+func TestListenSocketCreatesDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run", "grpc")
+	path := filepath.Join(dir, "grpc.sock")
+
+	listener, info, err := listenSocket(path)
+	if err != nil {
+		t.Fatalf("listenSocket: %v", err)
+	}
+	defer listener.Close()
+
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("socket directory not created: %v", err)
+	}
+	if perm := dirInfo.Mode().Perm(); perm != socketDirMode {
+		t.Errorf("socket directory permissions = %04o, want %04o", perm, socketDirMode)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		t.Errorf("%s is %v, want a socket", path, info.Mode())
+	}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, &server{})
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := pb.NewGreeterClient(conn).SayHello(ctx, &pb.HelloRequest{Name: "test"}); err != nil {
+		t.Fatalf("SayHello: %v", err)
+	}
+}
+
+func TestListenSocketDirectoryError(t *testing.T) {
+	// A regular file where the socket directory should be
+	parent := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(parent, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := listenSocket(filepath.Join(parent, "grpc.sock"))
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to create socket directory") {
+		t.Errorf("listenSocket() = %v, want a socket directory error", err)
+	}
+}
+
+func TestListenSocketInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	live, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer live.Close()
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := listenSocket(path); !errors.Is(err, errSocketInUse) {
+		t.Fatalf("listenSocket() = %v, want errSocketInUse", err)
+	}
+	if after, err := os.Stat(path); err != nil || !os.SameFile(before, after) {
+		t.Errorf("live socket was replaced (stat: %v)", err)
+	}
+}
+
+func TestListenSocketReclaimsStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	// A socket file left behind by a server that exited without removing it
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, info, err := listenSocket(path)
+	if err != nil {
+		t.Fatalf("listenSocket() = %v, want the stale socket replaced", err)
+	}
+	defer listener.Close()
+	if info.Mode()&os.ModeSocket == 0 {
+		t.Errorf("%s is %v, want a socket", path, info.Mode())
+	}
+}
+
+// listenUnix opens a socket at path the way the server does, leaving its removal to removeSocket
+func listenUnix(t *testing.T, path string) (*net.UnixListener, os.FileInfo) {
+	t.Helper()
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unixListener := listener.(*net.UnixListener)
+	unixListener.SetUnlinkOnClose(false)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return unixListener, info
+}
+
+func TestRemoveSocketAfterShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	listener, info := listenUnix(t, path)
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, &server{})
+	served := make(chan error, 1)
+	go func() { served <- grpcServer.Serve(listener) }()
+
+	conn, err := grpc.NewClient("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := pb.NewGreeterClient(conn).SayHello(ctx, &pb.HelloRequest{Name: "test"}); err != nil {
+		t.Fatalf("SayHello: %v", err)
+	}
+
+	grpcServer.GracefulStop()
+	if err := <-served; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	removeSocket(path, info)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket still exists after a clean shutdown (stat error: %v)", err)
+	}
+}
+
+func TestRemoveSocketLeavesReplacement(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	old, oldInfo := listenUnix(t, path)
+	defer old.Close()
+
+	// A restarted server removes the file on startup and creates its own
+	// socket while the old server is still shutting down
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	replacement, _ := listenUnix(t, path)
+	defer replacement.Close()
+
+	removeSocket(path, oldInfo)
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("the replacement's socket was removed: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestGetStats(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	client := pb.NewGreeterClient(serveInMemory(t, grpcServer))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const calls = 3
+	for i := 0; i < calls; i++ {
+		if _, err := client.SayHello(ctx, &pb.HelloRequest{Name: "test"}); err != nil {
+			t.Fatalf("SayHello: %v", err)
+		}
+	}
+
+	stats, err := client.GetStats(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.RequestCount != calls {
+		t.Errorf("RequestCount = %d, want %d", stats.RequestCount, calls)
+	}
+	if uptime := stats.Uptime.AsDuration(); uptime <= 0 {
+		t.Errorf("Uptime = %v, want a positive duration", uptime)
+	}
+
+	// Reading the stats does not count as a request
+	stats, err = client.GetStats(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.RequestCount != calls {
+		t.Errorf("RequestCount after a second GetStats = %d, want %d", stats.RequestCount, calls)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"multi-process-docker/internal/buildinfo"
+	"multi-process-docker/internal/config"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// clientVersionCheck rejects RPCs from clients whose build version is outside
+// the range this server is compatible with. Clients that send no version,
+// such as older builds and tools like grpcurl, are let through, as is the
+// health service so the process manager can probe readiness.
+type clientVersionCheck struct {
+	// nil disables the check
+	accepted *buildinfo.VersionRange
+}
+
+// check checks the client version in the incoming metadata of a call to fullMethod
+func (c *clientVersionCheck) check(ctx context.Context, fullMethod string) error {
+	if c.accepted == nil || strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	versions := md.Get(config.ClientVersionHeader)
+	if len(versions) == 0 {
+		logf(levelDebug, "RPC %s sent no client version, allowing it", fullMethod)
+		return nil
+	}
+	if !c.accepted.Contains(versions[0]) {
+		return status.Errorf(codes.FailedPrecondition, "client version %s is not compatible with server %s, which accepts clients %v; upgrade or downgrade the client to match",
+			versions[0], buildinfo.Get().Version, c.accepted)
+	}
+	return nil
+}
+
+func (c *clientVersionCheck) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := c.check(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (c *clientVersionCheck) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := c.check(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"multi-process-docker/internal/buildinfo"
+	"multi-process-docker/internal/config"
+	pb "multi-process-docker/proto/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientVersionCheck(t *testing.T) {
+	accepted, err := buildinfo.ParseVersionRange("v1.2.0..v2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := &clientVersionCheck{accepted: &accepted}
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(check.unaryInterceptor),
+		grpc.ChainStreamInterceptor(check.streamInterceptor),
+	)
+	pb.RegisterGreeterServer(grpcServer, newServer("test"))
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	conn := serveInMemory(t, grpcServer)
+	client := pb.NewGreeterClient(conn)
+
+	tests := []struct {
+		name     string
+		version  string
+		wantCode codes.Code
+	}{
+		{"compatible", "v1.4.0", codes.OK},
+		{"too old", "v1.1.0", codes.FailedPrecondition},
+		{"too new", "v2.0.0", codes.FailedPrecondition},
+		{"no version", "", codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if tt.version != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, config.ClientVersionHeader, tt.version)
+			}
+
+			_, err := client.SayHello(ctx, &pb.HelloRequest{Name: "test"})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("SayHello code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+			if tt.wantCode == codes.FailedPrecondition && !strings.Contains(status.Convert(err).Message(), "accepts clients v1.2.0 up to but not including v2.0.0") {
+				t.Errorf("SayHello error %q does not name the accepted range", status.Convert(err).Message())
+			}
+
+			stream, err := client.StreamMessages(ctx, &pb.StreamRequest{Count: 1})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("StreamMessages code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+
+			// Health checks are never turned away
+			if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+				t.Errorf("health check: %v", err)
+			}
+		})
+	}
+}